@@ -1,25 +1,39 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/weaveworks/pctl/pkg/batch"
 	"github.com/weaveworks/pctl/pkg/bootstrap"
 	"github.com/weaveworks/pctl/pkg/catalog"
-	"github.com/weaveworks/pctl/pkg/client"
 	"github.com/weaveworks/pctl/pkg/git"
 	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/install/artifact"
 	"github.com/weaveworks/pctl/pkg/log"
-	"github.com/weaveworks/pctl/pkg/runner"
+	"github.com/weaveworks/pctl/pkg/regenerate"
+	"github.com/weaveworks/pctl/pkg/upgrade/repo"
 )
 
-const defaultOut = "."
+const (
+	defaultOut = "."
+	dryRunTree = "tree"
+	dryRunYAML = "yaml"
+)
 
 var createPRFlags = []cli.Flag{
 	&cli.BoolFlag{
@@ -63,12 +77,33 @@ func addCmd() *cli.Command {
 		Aliases: []string{"apply"},
 		Usage:   "generate a profile installation",
 		UsageText: "To add from a profile catalog entry: pctl --catalog-url <URL> add --name pctl-profile --namespace default --profile-branch main --config-map configmap-name <CATALOG>/<PROFILE>[/<VERSION>]\n   " +
-			"To add directly from a profile repository: pctl add --name pctl-profile --namespace default --profile-branch development --profile-repo-url https://github.com/weaveworks/profiles-examples --profile-path bitnami-nginx",
+			"To add directly from a profile repository: pctl add --name pctl-profile --namespace default --profile-branch development --profile-repo-url https://github.com/weaveworks/profiles-examples --profile-path bitnami-nginx\n   " +
+			"To add a profile from the local filesystem without a clone: pctl add --name pctl-profile --profile-dir ./bitnami-nginx, or --profile-repo-url file:///path/to/profiles-examples --profile-path bitnami-nginx\n   " +
+			"<VERSION> can be an exact version, latest or a semver constraint such as ~0.2, ^1.0 or \">=1.2 <2.0\"\n   " +
+			"To customise the chart artifacts: pctl add --name pctl-profile --values nginx-server=values.yaml --set nginx-server.replicaCount=2 <CATALOG>/<PROFILE>\n   " +
+			"To add many installations at once: pctl add -f installations.yaml, where installations.yaml is in the format:\n\n" +
+			"   installations:\n" +
+			"   - name: nginx\n" +
+			"     catalog: <CATALOG>\n" +
+			"     profile: <PROFILE>\n" +
+			"     version: ~0.1\n" +
+			"     namespace: web\n" +
+			"     configMap: nginx-values\n" +
+			"     out: clusters/prod\n" +
+			"   - name: bitnami-nginx\n" +
+			"     url: https://github.com/weaveworks/profiles-examples\n" +
+			"     branch: main\n" +
+			"     path: bitnami-nginx\n" +
+			"     gitRepository: flux-system/flux-system",
 		Flags: append(createPRFlags,
 			&cli.StringFlag{
-				Name:     "name",
-				Usage:    "The name of the installation.",
-				Required: true,
+				Name:  "name",
+				Usage: "The name of the installation. Required unless --file is provided.",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "A manifest listing the installations to add. Every installation is validated before any is generated.",
 			},
 			&cli.StringFlag{
 				Name:        "namespace",
@@ -87,6 +122,14 @@ func addCmd() *cli.Command {
 				Value: "",
 				Usage: "The name of the ConfigMap which contains values for this profile.",
 			},
+			&cli.StringSliceFlag{
+				Name:  "values",
+				Usage: "A values file for a chart artifact in the format <ARTIFACT>=<PATH>, written to the generated ConfigMap of the installation. Can be repeated.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "A value for a chart artifact in the format <ARTIFACT>.<KEY>=<VALUE>, written to the generated ConfigMap of the installation. Takes precedence over --values. Can be repeated.",
+			},
 			&cli.StringFlag{
 				Name:        "out",
 				DefaultText: "current",
@@ -98,6 +141,11 @@ func addCmd() *cli.Command {
 				Value: "",
 				Usage: "Optional value defining the URL of the repository that contains the profile to be added.",
 			},
+			&cli.StringFlag{
+				Name:  "profile-dir",
+				Value: "",
+				Usage: "Optional directory of a profile on the local filesystem to add, read in place without a clone. Equivalent to --profile-repo-url file://<DIR> --profile-path .",
+			},
 			&cli.StringFlag{
 				Name:        "profile-path",
 				Value:       ".",
@@ -108,16 +156,86 @@ func addCmd() *cli.Command {
 				Name:  "git-repository",
 				Value: "",
 				Usage: "The namespace and name of the GitRepository object governing the flux repo.",
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-prerequisites",
+				Value: false,
+				Usage: "Instead of stopping the process, output warnings when the prerequisites of the profile aren't met by the cluster.",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Value: false,
+				Usage: "Print what would be generated instead of writing it to disk.",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Value: false,
+				Usage: "If the installation directory already contains a profile installation, remove it and generate the installation again.",
+			},
+			&cli.BoolFlag{
+				Name:  "merge",
+				Value: false,
+				Usage: "If the installation directory already contains a profile installation, merge the generated installation with the local changes made to it.",
+			},
+			&cli.BoolFlag{
+				Name:  "locked",
+				Value: false,
+				Usage: "Generate the installation from the commits recorded in the " + install.LockFile + " of the installation directory, rather than the latest commits of the branches and tags. Use with --force or --merge to reproduce an existing installation.",
+			},
+			&cli.StringFlag{
+				Name:        "dry-run-output",
+				Value:       dryRunTree,
+				DefaultText: dryRunTree,
+				Usage:       "The output of --dry-run: tree lists the files which would be created or overwritten, yaml prints every generated object as a multi-document stream.",
 			}),
 		Action: func(c *cli.Context) error {
+			if c.Bool("force") && c.Bool("merge") {
+				return errors.New("only one of --force and --merge can be used")
+			}
+			if err := checkLocked(c); err != nil {
+				return err
+			}
+			var sink *artifact.MemorySink
+			if c.Bool("dry-run") {
+				if c.Bool("create-pr") {
+					return errors.New("--create-pr can't be used with --dry-run")
+				}
+				if c.Bool("merge") {
+					return errors.New("--merge can't be used with --dry-run")
+				}
+				output := c.String("dry-run-output")
+				if output != dryRunTree && output != dryRunYAML {
+					return fmt.Errorf("unsupported dry run output %q, must be one of %s or %s", output, dryRunTree, dryRunYAML)
+				}
+				if output == dryRunYAML {
+					// keep stdout a valid yaml stream.
+					log.SetOutput(os.Stderr)
+					defer log.SetOutput(nil)
+				}
+				sink = &artifact.MemorySink{}
+			}
 			// Run installation main
-			installationDirectory, err := addProfile(c)
+			var (
+				installationDirectories []string
+				err                     error
+				auth                    = readGitAuth()
+			)
+			if c.IsSet("file") {
+				installationDirectories, err = addProfilesFromManifest(c, sink, auth)
+			} else {
+				var installationDirectory string
+				installationDirectory, err = addProfile(c, sink, auth)
+				installationDirectories = []string{installationDirectory}
+			}
 			if err != nil {
 				return err
 			}
+			if sink != nil {
+				return printDryRun(os.Stdout, sink, c.String("dry-run-output"))
+			}
 			// Create a pull request if desired
 			if c.Bool("create-pr") {
-				if err := createPullRequest(c, installationDirectory); err != nil {
+				if err := createPullRequest(c, auth, installationDirectories...); err != nil {
 					return err
 				}
 			}
@@ -126,11 +244,11 @@ func addCmd() *cli.Command {
 	}
 }
 
-// add runs the add part of the `add` command.
-func addProfile(c *cli.Context) (string, error) {
+// add runs the add part of the `add` command. Files are written to sink, or to disk if it's nil.
+func addProfile(c *cli.Context, sink *artifact.MemorySink, auth []git.Auth) (string, error) {
 	var (
 		err           error
-		catalogClient *client.Client
+		catalogClient catalog.CatalogClient
 		profilePath   string
 		catalogName   string
 		profileName   string
@@ -145,6 +263,17 @@ func addProfile(c *cli.Context) (string, error) {
 
 	// only set up the catalog if a url is not provided
 	url := c.String("profile-repo-url")
+	path := c.String("profile-path")
+	if dir := c.String("profile-dir"); dir != "" {
+		if url != "" {
+			return "", errors.New("only one of --profile-dir and --profile-repo-url can be provided")
+		}
+		url, err = install.LocalURL(dir)
+		if err != nil {
+			return "", err
+		}
+		path = "."
+	}
 	if url != "" && c.Args().Len() > 0 {
 		return "", errors.New("it looks like you provided a url with a catalog entry; please choose either format: url/branch/path or <CATALOG>/<PROFILE>[/<VERSION>]")
 	}
@@ -168,17 +297,29 @@ func addProfile(c *cli.Context) (string, error) {
 
 	branch := c.String("profile-branch")
 	subName := c.String("name")
+	if subName == "" {
+		_ = cli.ShowCommandHelp(c, "add")
+		return "", errors.New("the name of the installation must be provided with --name")
+	}
 	namespace := c.String("namespace")
 	configMap := c.String("config-map")
+	values, err := install.BuildValues(c.StringSlice("values"), c.StringSlice("set"))
+	if err != nil {
+		return "", err
+	}
+	if len(values) > 0 && configMap == "" {
+		configMap = subName + "-values"
+	}
 	dir, err := getProfileOutputDirectory(c, config)
 	if err != nil {
 		return "", err
 	}
-	path := c.String("profile-path")
 	message := c.String("pr-message")
 
 	var source string
-	if url != "" && path != "" {
+	if install.IsLocalURL(url) {
+		source = fmt.Sprintf("local repository %s, path: %s", url, path)
+	} else if url != "" && path != "" {
 		source = fmt.Sprintf("repository %s, path: %s and branch %s", url, path, branch)
 	} else if url != "" && path == "" {
 		source = fmt.Sprintf("repository %s and branch %s", url, branch)
@@ -187,10 +328,13 @@ func addProfile(c *cli.Context) (string, error) {
 	}
 
 	log.Actionf("generating profile installation from source: %s", source)
-	r := &runner.CLIRunner{}
-	g := git.NewCLIGit(git.CLIGitConfig{
+	g := newGit(c, auth, git.CLIGitConfig{
 		Message: message,
-	}, r)
+	})
+	installerConfig, err := newInstallConfig(c, g)
+	if err != nil {
+		return "", err
+	}
 
 	gitRepoNamespace, gitRepoName, err := getGitRepositoryNamespaceAndName(c, config)
 	if err != nil {
@@ -198,16 +342,17 @@ func addProfile(c *cli.Context) (string, error) {
 	}
 
 	installationDirectory := filepath.Join(dir, subName)
-	installer := install.NewInstaller(install.Config{
-		GitClient:        g,
-		RootDir:          installationDirectory,
-		GitRepoNamespace: gitRepoNamespace,
-		GitRepoName:      gitRepoName,
-	})
+	installerConfig.RootDir = installationDirectory
+	installerConfig.GitRepoNamespace = gitRepoNamespace
+	installerConfig.GitRepoName = gitRepoName
+	installerConfig.Values = values
+	installerConfig.Sink = installSink(sink)
+	installer := install.NewInstaller(installerConfig)
 	cfg := catalog.InstallConfig{
 		Clients: catalog.Clients{
-			CatalogClient: catalogClient,
-			Installer:     installer,
+			CatalogClient:       catalogClient,
+			Installer:           installer,
+			PrerequisiteChecker: &clusterPrerequisiteChecker{kubeconfig: c.String("kubeconfig")},
 		},
 		Profile: catalog.Profile{
 			ProfileConfig: catalog.ProfileConfig{
@@ -226,15 +371,186 @@ func addProfile(c *cli.Context) (string, error) {
 				Name:      gitRepoName,
 			},
 		},
+		IgnorePrerequisites: c.Bool("ignore-prerequisites"),
 	}
-	manager := &catalog.Manager{}
-	err = manager.Install(cfg)
-	if err == nil {
+	_, err = regenerate.Install(c.Context, regenerate.Config{
+		Mode:           regenerateMode(c),
+		CatalogManager: &catalog.Manager{},
+		Installer:      installer,
+		NewRepoManager: newMergeRepoManager(c, auth, message),
+		Locked:         c.Bool("locked"),
+	}, cfg)
+	if err == nil && sink == nil {
 		log.Successf("installation completed successfully")
 	}
 	return installationDirectory, err
 }
 
+// addProfilesFromManifest runs the add part of the `add` command for every installation of the --file manifest.
+func addProfilesFromManifest(c *cli.Context, sink *artifact.MemorySink, auth []git.Auth) ([]string, error) {
+	if c.Args().Len() > 0 || c.IsSet("name") || c.IsSet("profile-repo-url") || c.IsSet("profile-dir") ||
+		c.IsSet("values") || c.IsSet("set") {
+		return nil, errors.New("installations are defined by the manifest when --file is provided; remove the other installation arguments")
+	}
+	manifest, err := batch.Load(c.String("file"))
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current working directory: %w", err)
+	}
+	config := bootstrap.GetConfig(wd)
+	out, err := getProfileOutputDirectory(c, config)
+	if err != nil {
+		return nil, err
+	}
+	var gitRepository string
+	if c.IsSet("git-repository") {
+		gitRepository = c.String("git-repository")
+	} else if config != nil && config.GitRepository.Name != "" {
+		gitRepository = config.GitRepository.Namespace + "/" + config.GitRepository.Name
+	}
+	catalogClient, err := getCatalogClient(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog client: %w", err)
+	}
+
+	g := newGit(c, auth, git.CLIGitConfig{
+		Message: c.String("pr-message"),
+	})
+	installerConfig, err := newInstallConfig(c, g)
+	if err != nil {
+		return nil, err
+	}
+	installerConfig.Sink = installSink(sink)
+
+	log.Actionf("generating %d profile installations from manifest %s", len(manifest.Installations), c.String("file"))
+	dirs, err := batch.Add(c.Context, batch.Config{
+		CatalogClient:       catalogClient,
+		CatalogManager:      &catalog.Manager{},
+		Installer:           install.NewInstaller(installerConfig),
+		PrerequisiteChecker: &clusterPrerequisiteChecker{kubeconfig: c.String("kubeconfig")},
+		IgnorePrerequisites: c.Bool("ignore-prerequisites"),
+		Defaults: batch.Defaults{
+			Namespace:     c.String("namespace"),
+			Branch:        c.String("profile-branch"),
+			Path:          c.String("profile-path"),
+			Out:           out,
+			GitRepository: gitRepository,
+		},
+		Mode:           regenerateMode(c),
+		NewRepoManager: newMergeRepoManager(c, auth, c.String("pr-message")),
+		Locked:         c.Bool("locked"),
+	}, manifest)
+	if err == nil && sink == nil {
+		log.Successf("installations completed successfully")
+	}
+	return dirs, err
+}
+
+// checkLocked returns an error if --locked is used without --force, --merge or --dry-run. The lock file is
+// only found in the directory of an existing installation, which is refused without one of them.
+func checkLocked(c *cli.Context) error {
+	if c.Bool("locked") && !c.Bool("force") && !c.Bool("merge") && !c.Bool("dry-run") {
+		return errors.New("--locked generates an existing installation again from its " + install.LockFile + ", use it with --force or --merge")
+	}
+	return nil
+}
+
+// regenerateMode returns how an existing installation in the installation directory is handled.
+func regenerateMode(c *cli.Context) regenerate.Mode {
+	switch {
+	case c.Bool("force"):
+		return regenerate.Force
+	case c.Bool("merge"):
+		return regenerate.Merge
+	}
+	return regenerate.Refuse
+}
+
+// newMergeRepoManager returns the function creating the repository manager used to merge existing installations.
+func newMergeRepoManager(c *cli.Context, auth []git.Auth, message string) func(dir string) repo.RepoManager {
+	return func(dir string) repo.RepoManager {
+		return repo.NewManager(newGit(c, auth, git.CLIGitConfig{
+			Directory: dir,
+			Quiet:     true,
+			Message:   message,
+		}))
+	}
+}
+
+// installSink returns the sink of the installer, leaving it unset to write to disk when sink is nil.
+func installSink(sink *artifact.MemorySink) artifact.Sink {
+	if sink == nil {
+		return nil
+	}
+	return sink
+}
+
+// printDryRun prints the files received by sink in the given dry run output.
+func printDryRun(w io.Writer, sink *artifact.MemorySink, output string) error {
+	if output == dryRunYAML {
+		for _, f := range sink.Files {
+			// the lock file isn't a kubernetes object.
+			if f.Copied || filepath.Base(f.Path) == install.LockFile {
+				continue
+			}
+			data := f.Data
+			if !bytes.HasSuffix(data, []byte("\n")) {
+				data = append(data, '\n')
+			}
+			if _, err := fmt.Fprintf(w, "---\n# Source: %s\n%s", f.Path, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	paths := make([]string, 0, len(sink.Files))
+	for _, f := range sink.Files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		action := "create"
+		if _, err := os.Stat(p); err == nil {
+			action = "overwrite"
+		}
+		if _, err := fmt.Fprintf(w, "%-9s %s\n", action, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterPrerequisiteChecker connects to the cluster only once a profile has prerequisites to verify,
+// so profiles without any can be added without access to a cluster.
+type clusterPrerequisiteChecker struct {
+	kubeconfig string
+}
+
+// Check implements catalog.PrerequisiteChecker.
+func (p *clusterPrerequisiteChecker) Check(ctx context.Context, prerequisites []string) error {
+	cl, err := buildK8sClient(p.kubeconfig)
+	if err != nil {
+		return err
+	}
+	utilruntime.Must(apiextensionsv1.AddToScheme(cl.Scheme()))
+	config, err := clientcmd.BuildConfigFromFlags("", p.kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to create config from kubeconfig path %q: %w", p.kubeconfig, err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	checker := &catalog.ClusterPrerequisiteChecker{
+		Client:    cl,
+		Discovery: discoveryClient,
+	}
+	return checker.Check(ctx, prerequisites)
+}
+
 func getGitRepositoryNamespaceAndName(c *cli.Context, config *bootstrap.Config) (string, string, error) {
 	gitRepository := c.String("git-repository")
 	if gitRepository != "" {
@@ -265,9 +581,31 @@ func getProfileOutputDirectory(c *cli.Context, config *bootstrap.Config) (string
 	return defaultOut, nil
 }
 
+// pullRequestBranch returns --pr-branch, or a branch named after the installation, the manifest of the
+// installations or the upgraded installation.
+func pullRequestBranch(c *cli.Context, installationDirectories []string) string {
+	if branch := c.String("pr-branch"); branch != "" {
+		return branch
+	}
+	prefix := c.String("name")
+	switch {
+	case c.IsSet("file"):
+		file := filepath.Base(c.String("file"))
+		prefix = strings.TrimSuffix(file, filepath.Ext(file))
+	case prefix == "" && len(installationDirectories) == 1:
+		prefix = filepath.Base(installationDirectories[0])
+	}
+	// a leading dash would be parsed as an option by git.
+	prefix = strings.TrimLeft(prefix, "-.")
+	if prefix == "" {
+		prefix = "pctl"
+	}
+	return prefix + "-" + uuid.NewString()[:6]
+}
+
 // createPullRequest runs the pull request creation part of the `add` command.
-func createPullRequest(c *cli.Context, installationDirectory string) error {
-	branch := c.String("pr-branch")
+func createPullRequest(c *cli.Context, auth []git.Auth, installationDirectories ...string) error {
+	branch := pullRequestBranch(c, installationDirectories)
 	repo := c.String("pr-repo")
 	base := c.String("pr-base")
 	remote := c.String("pr-remote")
@@ -276,18 +614,14 @@ func createPullRequest(c *cli.Context, installationDirectory string) error {
 	if repo == "" {
 		return errors.New("repo must be defined if create-pr is true")
 	}
-	if branch == "" {
-		branch = c.String("name") + "-" + uuid.NewString()[:6]
-	}
 	log.Actionf("creating a PR to repo %s with base %s and branch %s", repo, base, branch)
-	r := &runner.CLIRunner{}
-	g := git.NewCLIGit(git.CLIGitConfig{
+	g := newGit(c, auth, git.CLIGitConfig{
 		Directory: directory,
 		Branch:    branch,
 		Remote:    remote,
 		Base:      base,
 		Message:   message,
-	}, r)
+	})
 	scmClient, err := git.NewClient(git.SCMConfig{
 		Branch: branch,
 		Base:   base,
@@ -296,5 +630,5 @@ func createPullRequest(c *cli.Context, installationDirectory string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create scm client: %w", err)
 	}
-	return catalog.CreatePullRequest(scmClient, g, branch, installationDirectory)
+	return catalog.CreatePullRequest(scmClient, g, branch, installationDirectories...)
 }
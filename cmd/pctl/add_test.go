@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/urfave/cli/v2"
 
 	"github.com/weaveworks/pctl/pkg/bootstrap"
+	"github.com/weaveworks/pctl/pkg/install/artifact"
 )
 
 var _ = Describe("add", func() {
@@ -69,4 +75,126 @@ var _ = Describe("add", func() {
 			})
 		})
 	})
+
+	Context("pullRequestBranch", func() {
+		newContext := func(flags map[string]string) *cli.Context {
+			f := &flag.FlagSet{}
+			for _, name := range []string{"pr-branch", "name", "file"} {
+				f.String(name, "", "")
+			}
+			for name, value := range flags {
+				_ = f.Set(name, value)
+			}
+			return cli.NewContext(&cli.App{Commands: []*cli.Command{addCmd()}}, f, nil)
+		}
+
+		It("returns the branch the user has set", func() {
+			c := newContext(map[string]string{"pr-branch": "my-branch", "file": "installations.yaml"})
+			Expect(pullRequestBranch(c, []string{"a", "b"})).To(Equal("my-branch"))
+		})
+
+		It("names the branch after the installation", func() {
+			c := newContext(map[string]string{"name": "nginx"})
+			Expect(pullRequestBranch(c, []string{"out/nginx"})).To(MatchRegexp(`^nginx-[0-9a-f]{6}$`))
+		})
+
+		It("names the branch after the manifest of the installations", func() {
+			c := newContext(map[string]string{"file": "clusters/prod/installations.yaml"})
+			Expect(pullRequestBranch(c, []string{"out/nginx", "out/redis"})).To(MatchRegexp(`^installations-[0-9a-f]{6}$`))
+		})
+
+		It("names the branch after the upgraded installation", func() {
+			c := newContext(nil)
+			Expect(pullRequestBranch(c, []string{"clusters/prod/nginx"})).To(MatchRegexp(`^nginx-[0-9a-f]{6}$`))
+		})
+
+		It("never starts the branch with a dash", func() {
+			c := newContext(map[string]string{"file": "-.yaml"})
+			Expect(pullRequestBranch(c, []string{"out/nginx", "out/redis"})).To(MatchRegexp(`^pctl-[0-9a-f]{6}$`))
+		})
+	})
+
+	Context("checkLocked", func() {
+		newContext := func(flags ...string) *cli.Context {
+			f := &flag.FlagSet{}
+			for _, name := range []string{"locked", "force", "merge", "dry-run"} {
+				f.Bool(name, false, "")
+			}
+			for _, name := range flags {
+				_ = f.Set(name, "true")
+			}
+			return cli.NewContext(&cli.App{Commands: []*cli.Command{addCmd()}}, f, nil)
+		}
+
+		It("rejects --locked on its own", func() {
+			Expect(checkLocked(newContext("locked"))).To(MatchError("--locked generates an existing installation again from its profile-lock.yaml, use it with --force or --merge"))
+		})
+
+		It("accepts --locked with --force, --merge or --dry-run", func() {
+			Expect(checkLocked(newContext("locked", "force"))).To(Succeed())
+			Expect(checkLocked(newContext("locked", "merge"))).To(Succeed())
+			Expect(checkLocked(newContext("locked", "dry-run"))).To(Succeed())
+			Expect(checkLocked(newContext())).To(Succeed())
+		})
+	})
+
+	Context("addProfilesFromManifest", func() {
+		It("rejects the installation arguments with --file", func() {
+			for _, name := range []string{"name", "profile-repo-url", "profile-dir", "values", "set"} {
+				f := &flag.FlagSet{}
+				f.String("file", "installations.yaml", "")
+				f.String(name, "", "")
+				_ = f.Set(name, "value")
+				c := cli.NewContext(&cli.App{Commands: []*cli.Command{addCmd()}}, f, nil)
+				_, err := addProfilesFromManifest(c, &artifact.MemorySink{}, nil)
+				Expect(err).To(MatchError("installations are defined by the manifest when --file is provided; remove the other installation arguments"), name)
+			}
+		})
+	})
+
+	Context("printDryRun", func() {
+		var (
+			dir  string
+			sink *artifact.MemorySink
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "dry-run")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "profile-installation.yaml"), []byte("old"), 0644)).To(Succeed())
+			sink = &artifact.MemorySink{}
+			Expect(sink.WriteFile(filepath.Join(dir, "profile-installation.yaml"), []byte("kind: ProfileInstallation"))).To(Succeed())
+			Expect(sink.WriteFile(filepath.Join(dir, "artifacts/nginx/kustomization.yaml"), []byte("resources:\n- kustomize-flux.yaml\n"))).To(Succeed())
+			sink.Files = append(sink.Files, artifact.File{Path: filepath.Join(dir, "artifacts/nginx/files/deployment.yaml"), Data: []byte("kind: Deployment\n"), Copied: true})
+			Expect(sink.WriteFile(filepath.Join(dir, "profile-lock.yaml"), []byte("profiles: []\n"))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		It("lists the files which would be created or overwritten", func() {
+			var buf bytes.Buffer
+			Expect(printDryRun(&buf, sink, dryRunTree)).To(Succeed())
+			Expect(buf.String()).To(Equal(fmt.Sprintf(`create    %[1]s/artifacts/nginx/files/deployment.yaml
+create    %[1]s/artifacts/nginx/kustomization.yaml
+overwrite %[1]s/profile-installation.yaml
+create    %[1]s/profile-lock.yaml
+`, dir)))
+		})
+
+		It("prints the generated objects as a yaml stream", func() {
+			var buf bytes.Buffer
+			Expect(printDryRun(&buf, sink, dryRunYAML)).To(Succeed())
+			Expect(buf.String()).To(Equal(fmt.Sprintf(`---
+# Source: %[1]s/profile-installation.yaml
+kind: ProfileInstallation
+---
+# Source: %[1]s/artifacts/nginx/kustomization.yaml
+resources:
+- kustomize-flux.yaml
+`, dir)))
+		})
+	})
 })
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/weaveworks/pctl/pkg/formatter"
+	"github.com/weaveworks/pctl/pkg/git"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/repocache"
+)
+
+func cacheCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "work with the cache of profile repository clones",
+		Subcommands: []*cli.Command{
+			cacheListCmd(),
+			cachePruneCmd(),
+		},
+	}
+}
+
+func cacheListCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "list",
+		Usage:     "list the cached profile repository clones",
+		UsageText: "pctl cache list [--output table|json]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				DefaultText: "table",
+				Value:       "table",
+				Usage:       "Output format. json|table",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			// listing and pruning the cache doesn't contact any repository.
+			cache, err := repocache.New(newGit(c, nil, git.CLIGitConfig{}), repocache.Options{})
+			if err != nil {
+				return err
+			}
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			return formatCacheOutput(entries, c.String("output"))
+		},
+	}
+}
+
+func cachePruneCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "remove cached profile repository clones",
+		UsageText: "pctl cache prune [--unused-for <DURATION>]\n\n" +
+			"   example: pctl cache prune --unused-for 720h",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "unused-for",
+				Usage: "Only remove the clones which weren't used for this long, for example 720h. All clones are removed if 0",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cache, err := repocache.New(newGit(c, nil, git.CLIGitConfig{}), repocache.Options{})
+			if err != nil {
+				return err
+			}
+			removed, err := cache.Prune(c.Duration("unused-for"))
+			var freed int64
+			for _, entry := range removed {
+				log.Actionf("removed %s %s", entry.URL, entry.Ref)
+				freed += entry.Size
+			}
+			if err != nil {
+				return err
+			}
+			log.Successf("removed %d cached repositories, freeing %s", len(removed), formatSize(freed))
+			return nil
+		},
+	}
+}
+
+// newRepositoryCache returns the persistent cache profile repositories are cloned through, or nil with --no-cache.
+func newRepositoryCache(c *cli.Context, g git.Git) (install.Cloner, error) {
+	if c.Bool("no-cache") {
+		return nil, nil
+	}
+	cache, err := repocache.New(g, repocache.Options{
+		Offline: c.Bool("offline"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository cache: %w", err)
+	}
+	return cache, nil
+}
+
+func cacheDataFunc(entries []repocache.Entry) func() interface{} {
+	return func() interface{} {
+		tc := formatter.TableContents{
+			Headers: []string{"Repository", "Ref", "Size", "Fetched", "Used"},
+		}
+		for _, entry := range entries {
+			tc.Data = append(tc.Data, []string{
+				entry.URL,
+				entry.Ref,
+				formatSize(entry.Size),
+				entry.FetchedAt.Format(time.RFC3339),
+				entry.UsedAt.Format(time.RFC3339),
+			})
+		}
+		return tc
+	}
+}
+
+func formatCacheOutput(entries []repocache.Entry, outFormat string) error {
+	if len(entries) == 0 && outFormat != "json" {
+		log.Successf("no cached repositories")
+		return nil
+	}
+
+	var f formatter.Formatter
+	f = formatter.NewTableFormatter()
+	getter := cacheDataFunc(entries)
+
+	if outFormat == "json" {
+		f = formatter.NewJSONFormatter()
+		getter = func() interface{} { return entries }
+	}
+
+	out, err := f.Format(getter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+// formatSize formats a number of bytes with a binary unit, such as 1.5 MiB.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
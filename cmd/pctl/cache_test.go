@@ -0,0 +1,16 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cache", func() {
+	Context("formatSize", func() {
+		It("formats sizes with binary units", func() {
+			Expect(formatSize(512)).To(Equal("512 B"))
+			Expect(formatSize(1536)).To(Equal("1.5 KiB"))
+			Expect(formatSize(3 * 1024 * 1024)).To(Equal("3.0 MiB"))
+		})
+	})
+})
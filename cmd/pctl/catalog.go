@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/weaveworks/pctl/pkg/client"
+	"github.com/weaveworks/pctl/pkg/runner"
+	"github.com/weaveworks/pctl/pkg/server"
+)
+
+func catalogCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "catalog",
+		Usage: "work with profile catalogs",
+		Subcommands: []*cli.Command{
+			catalogServeCmd(),
+		},
+	}
+}
+
+func catalogServeCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "serve a catalog of the profiles found in local git repositories and directories",
+		UsageText: "pctl catalog serve [--listen <ADDRESS> --name <CATALOG>] <PATH>...\n\n" +
+			"   Every tag of a git repository in the format <PROFILE DIRECTORY>/<SEMVER> or <SEMVER> is served as a profile version.\n" +
+			"   Profiles in directories which aren't git repositories are served with version " + server.DirectoryVersion + " and installed from the directory in place.\n\n" +
+			"   example: pctl catalog serve --name dev ~/workspace/profiles && pctl --catalog-url http://localhost:8000 get --catalog",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "localhost:8000",
+				Usage: "The address to serve the catalog on",
+			},
+			&cli.StringFlag{
+				Name:  "name",
+				Value: "local",
+				Usage: "The name of the served catalog",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() < 1 {
+				_ = cli.ShowCommandHelp(c, "serve")
+				return errors.New("at least one path must be provided")
+			}
+			scanner := &server.Scanner{
+				CatalogName: c.String("name"),
+				Runner:      &runner.CLIRunner{},
+			}
+			entries, err := scanner.Scan(c.Args().Slice())
+			if err != nil {
+				return err
+			}
+			fileClient, err := client.NewFileClientFromEntries(entries)
+			if err != nil {
+				return err
+			}
+			return server.Serve(c.Context, c.String("listen"), server.NewHandler(fileClient))
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+const (
+	serviceEndpointPrefix = "service:"
+	fileEndpointPrefix    = "file:"
+)
+
+func buildCatalogClient(c *cli.Context) (catalog.CatalogClient, error) {
+	if endpoints := c.StringSlice("catalog-endpoint"); len(endpoints) > 0 {
+		if c.String("catalog-path") != "" || c.String("catalog-url") != "" {
+			return nil, errors.New("--catalog-endpoint can't be combined with --catalog-path or --catalog-url")
+		}
+		return buildFederatedClient(c, endpoints)
+	}
+	if c.String("catalog-path") != "" {
+		if c.String("catalog-url") != "" {
+			return nil, errors.New("only one of --catalog-path and --catalog-url can be provided")
+		}
+		return client.NewFileClient(c.String("catalog-path"))
+	}
+	if c.String("catalog-url") != "" {
+		return buildHTTPCatalogClient(c, client.HTTPOptions{
+			URL:                c.String("catalog-url"),
+			CAFile:             c.String("catalog-ca-file"),
+			Token:              c.String("catalog-token"),
+			InsecureSkipVerify: c.Bool("catalog-insecure-skip-tls-verify"),
+		})
+	}
+	return buildServiceCatalogClient(c, c.String("catalog-service-namespace"), c.String("catalog-service-name"), c.String("catalog-service-port"))
+}
+
+// buildFederatedClient creates a catalog client for every endpoint and queries them together. The
+// --catalog-token, --catalog-ca-file and --catalog-insecure-skip-tls-verify flags only apply to --catalog-url,
+// http(s) endpoints are given their own with options, so credentials are never sent to another catalog.
+func buildFederatedClient(c *cli.Context, specs []string) (catalog.CatalogClient, error) {
+	var endpoints []catalog.Endpoint
+	names := make(map[string]struct{})
+	for _, spec := range specs {
+		options := strings.Split(spec, ";")
+		spec = options[0]
+		name, target := spec, spec
+		if i := strings.Index(spec, "="); i > 0 {
+			name, target = spec[:i], spec[i+1:]
+		}
+		isHTTP := strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+		if len(options) > 1 && !isHTTP {
+			return nil, fmt.Errorf("catalog endpoint %q doesn't support options, only http(s) endpoints do", name)
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("duplicate catalog endpoint %q", name)
+		}
+		names[name] = struct{}{}
+
+		var (
+			endpointClient catalog.CatalogClient
+			err            error
+		)
+		switch {
+		case isHTTP:
+			httpOptions, perr := parseHTTPEndpointOptions(target, options[1:])
+			if perr != nil {
+				return nil, fmt.Errorf("invalid catalog endpoint %q: %w", name, perr)
+			}
+			endpointClient, err = buildHTTPCatalogClient(c, httpOptions)
+		case strings.HasPrefix(target, serviceEndpointPrefix):
+			namespace, service, port, perr := parseServiceEndpoint(strings.TrimPrefix(target, serviceEndpointPrefix))
+			if perr != nil {
+				return nil, perr
+			}
+			endpointClient, err = buildServiceCatalogClient(c, namespace, service, port)
+		case strings.HasPrefix(target, fileEndpointPrefix):
+			endpointClient, err = client.NewFileClient(strings.TrimPrefix(target, fileEndpointPrefix))
+		default:
+			return nil, fmt.Errorf("unsupported catalog endpoint %q, must be a http(s) url, service:<namespace>/<name>:<port> or file:<path>", target)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for catalog endpoint %q: %w", name, err)
+		}
+		endpoints = append(endpoints, catalog.Endpoint{Name: name, Client: endpointClient})
+	}
+	return catalog.NewFederatedClient(endpoints), nil
+}
+
+// parseServiceEndpoint parses <namespace>/<name>:<port>.
+func parseServiceEndpoint(s string) (string, string, string, error) {
+	split := strings.Split(s, "/")
+	if len(split) != 2 {
+		return "", "", "", fmt.Errorf("service endpoint must be in format service:<namespace>/<name>:<port>; was: %s", s)
+	}
+	namePort := strings.Split(split[1], ":")
+	if len(namePort) != 2 || split[0] == "" || namePort[0] == "" || namePort[1] == "" {
+		return "", "", "", fmt.Errorf("service endpoint must be in format service:<namespace>/<name>:<port>; was: %s", s)
+	}
+	return split[0], namePort[0], namePort[1], nil
+}
+
+// parseHTTPEndpointOptions parses the options of a http(s) endpoint: ca-file=<path>, token-file=<path> and
+// insecure-skip-tls-verify.
+func parseHTTPEndpointOptions(url string, options []string) (client.HTTPOptions, error) {
+	httpOptions := client.HTTPOptions{URL: url}
+	for _, option := range options {
+		key, value := option, ""
+		if i := strings.Index(option, "="); i >= 0 {
+			key, value = option[:i], option[i+1:]
+		}
+		switch key {
+		case "ca-file":
+			httpOptions.CAFile = value
+		case "token-file":
+			token, err := ioutil.ReadFile(value)
+			if err != nil {
+				return client.HTTPOptions{}, fmt.Errorf("failed to read token file: %w", err)
+			}
+			httpOptions.Token = strings.TrimSpace(string(token))
+		case "insecure-skip-tls-verify":
+			httpOptions.InsecureSkipVerify = true
+		default:
+			return client.HTTPOptions{}, fmt.Errorf("unsupported option %q, must be ca-file=<path>, token-file=<path> or insecure-skip-tls-verify", key)
+		}
+	}
+	return httpOptions, nil
+}
+
+func buildHTTPCatalogClient(c *cli.Context, options client.HTTPOptions) (catalog.CatalogClient, error) {
+	httpClient, err := client.NewHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return withCache(c, withRetries(c, httpClient), options.URL)
+}
+
+func buildServiceCatalogClient(c *cli.Context, namespace, name, port string) (catalog.CatalogClient, error) {
+	options := client.ServiceOptions{
+		KubeconfigPath: c.String("kubeconfig"),
+		Namespace:      namespace,
+		ServiceName:    name,
+		ServicePort:    port,
+	}
+	serviceClient, err := client.NewFromOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return withCache(c, withRetries(c, serviceClient), fmt.Sprintf("%s:%s/%s:%s", options.KubeconfigPath, namespace, name, port))
+}
+
+func withRetries(c *cli.Context, catalogClient client.Requester) client.Requester {
+	return client.NewRetryingClient(catalogClient, client.RetryOptions{
+		Attempts: c.Int("catalog-retry-attempts"),
+		MaxWait:  c.Duration("catalog-retry-max-wait"),
+	})
+}
+
+func withCache(c *cli.Context, catalogClient client.Requester, namespace string) (catalog.CatalogClient, error) {
+	return client.NewCachingClient(catalogClient, client.CacheOptions{
+		Namespace: namespace,
+		TTL:       c.Duration("cache-ttl"),
+		NoCache:   c.Bool("no-cache"),
+		Offline:   c.Bool("offline"),
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/cli/v2"
+
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var _ = Describe("catalog client", func() {
+	Context("parseServiceEndpoint", func() {
+		It("returns the namespace, name and port", func() {
+			namespace, name, port, err := parseServiceEndpoint("profiles-system/profiles-catalog-service:8000")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namespace).To(Equal("profiles-system"))
+			Expect(name).To(Equal("profiles-catalog-service"))
+			Expect(port).To(Equal("8000"))
+		})
+
+		When("the format is invalid", func() {
+			It("returns an error", func() {
+				for _, s := range []string{"profiles-catalog-service:8000", "profiles-system/profiles-catalog-service", "/:"} {
+					_, _, _, err := parseServiceEndpoint(s)
+					Expect(err).To(MatchError(ContainSubstring("service endpoint must be in format")))
+				}
+			})
+		})
+	})
+
+	Context("parseHTTPEndpointOptions", func() {
+		It("returns the credentials of the endpoint", func() {
+			dir, err := ioutil.TempDir("", "catalog-client")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			tokenFile := filepath.Join(dir, "token")
+			Expect(ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)).To(Succeed())
+
+			options, err := parseHTTPEndpointOptions("https://catalog", []string{"token-file=" + tokenFile, "ca-file=ca.pem", "insecure-skip-tls-verify"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options).To(Equal(client.HTTPOptions{
+				URL:                "https://catalog",
+				CAFile:             "ca.pem",
+				Token:              "secret",
+				InsecureSkipVerify: true,
+			}))
+		})
+
+		It("returns an error for an unsupported option", func() {
+			_, err := parseHTTPEndpointOptions("https://catalog", []string{"token=secret"})
+			Expect(err).To(MatchError(`unsupported option "token", must be ca-file=<path>, token-file=<path> or insecure-skip-tls-verify`))
+		})
+	})
+
+	Context("buildFederatedClient", func() {
+		var (
+			server        *httptest.Server
+			authorization chan string
+			c             *cli.Context
+		)
+
+		BeforeEach(func() {
+			authorization = make(chan string, 1)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization <- r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(`{"item":{}}`))
+			}))
+			f := &flag.FlagSet{}
+			f.String("catalog-token", "", "")
+			f.Bool("no-cache", false, "")
+			_ = f.Set("catalog-token", "global-secret")
+			_ = f.Set("no-cache", "true")
+			c = cli.NewContext(&cli.App{}, f, nil)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("doesn't send the --catalog-token to the endpoints", func() {
+			federated, err := buildFederatedClient(c, []string{"public=" + server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = federated.DoRequest(context.Background(), "/profiles/catalog/nginx", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(<-authorization).To(BeEmpty())
+		})
+
+		It("rejects options for endpoints which aren't http(s)", func() {
+			_, err := buildFederatedClient(c, []string{"local=file:catalog;insecure-skip-tls-verify"})
+			Expect(err).To(MatchError(`catalog endpoint "local" doesn't support options, only http(s) endpoints do`))
+		})
+	})
+})
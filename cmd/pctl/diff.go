@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/diff"
+	"github.com/weaveworks/pctl/pkg/formatter"
+	"github.com/weaveworks/pctl/pkg/git"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+func diffCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "show the artifact changes between two versions of a catalog profile",
+		UsageText: "pctl diff [--output table|json] <CATALOG>/<PROFILE> <FROM> <TO>\n\n" +
+			"   example: pctl diff catalog/weaveworks-nginx v0.1.0 v0.1.1",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				DefaultText: "table",
+				Value:       "table",
+				Usage:       "Output format. json|table",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 3 {
+				_ = cli.ShowCommandHelp(c, "diff")
+				return errors.New("a profile and the two versions to compare must be provided")
+			}
+			parts := strings.Split(c.Args().Get(0), "/")
+			if len(parts) != 2 {
+				_ = cli.ShowCommandHelp(c, "diff")
+				return errors.New("both catalog name and profile name must be provided")
+			}
+			catalogClient, err := getCatalogClient(c)
+			if err != nil {
+				return fmt.Errorf("failed to create catalog client: %w", err)
+			}
+			g := newGit(c, readGitAuth(), git.CLIGitConfig{})
+			installerConfig, err := newInstallConfig(c, g)
+			if err != nil {
+				return err
+			}
+			result, err := diff.Diff(c.Context, diff.Config{
+				CatalogClient:  catalogClient,
+				CatalogManager: &catalog.Manager{},
+				Fetcher:        install.NewInstaller(installerConfig),
+				CatalogName:    parts[0],
+				ProfileName:    parts[1],
+				From:           c.Args().Get(1),
+				To:             c.Args().Get(2),
+			})
+			if err != nil {
+				return err
+			}
+			return formatDiffOutput(result, c.String("output"))
+		},
+	}
+}
+
+func diffDataFunc(result diff.Result) func() interface{} {
+	return func() interface{} {
+		tc := formatter.TableContents{
+			Headers: []string{"Artifact", "Change", "Field", "From", "To"},
+		}
+		for _, change := range result.Changes {
+			tc.Data = append(tc.Data, []string{
+				change.Artifact,
+				string(change.Type),
+				change.Field,
+				change.From,
+				change.To,
+			})
+		}
+		return tc
+	}
+}
+
+func formatDiffOutput(result diff.Result, outFormat string) error {
+	if len(result.Changes) == 0 && outFormat != "json" {
+		log.Successf("no changes between %s/%s versions %s and %s", result.Catalog, result.Profile, result.From, result.To)
+		return nil
+	}
+
+	var f formatter.Formatter
+	f = formatter.NewTableFormatter()
+	getter := diffDataFunc(result)
+
+	if outFormat == "json" {
+		f = formatter.NewJSONFormatter()
+		getter = func() interface{} { return result }
+	}
+
+	out, err := f.Format(getter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(out)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+)
+
+// Exit codes returned by pctl so scripts can react to catalog failures.
+const (
+	exitCodeError              = 1
+	exitCodeProfileNotFound    = 3
+	exitCodeCatalogNotFound    = 4
+	exitCodeCatalogUnavailable = 5
+	exitCodeInvalidResponse    = 6
+	exitCodeCanceled           = 7
+)
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, catalog.ErrProfileNotFound):
+		return exitCodeProfileNotFound
+	case errors.Is(err, catalog.ErrCatalogNotFound):
+		return exitCodeCatalogNotFound
+	case errors.Is(err, catalog.ErrCatalogUnavailable):
+		return exitCodeCatalogUnavailable
+	case errors.Is(err, catalog.ErrInvalidResponse):
+		return exitCodeInvalidResponse
+	case errors.Is(err, catalog.ErrCanceled):
+		return exitCodeCanceled
+	}
+	return exitCodeError
+}
+
+// hint returns a suggestion on how to resolve an error, if there is one.
+func hint(err error) string {
+	switch {
+	case errors.Is(err, catalog.ErrProfileNotFound):
+		return "run 'pctl get --catalog <PROFILE>' to list the profiles and versions available in the catalog"
+	case errors.Is(err, catalog.ErrCatalogNotFound):
+		return "check the catalog name, 'pctl get --catalog' lists the profiles of all available catalogs"
+	case errors.Is(err, catalog.ErrCatalogUnavailable):
+		return "check that the catalog service is running and reachable, or use --offline to use cached responses"
+	case errors.Is(err, catalog.ErrInvalidResponse):
+		return "check that the catalog flags point to a profiles catalog API"
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+)
+
+var _ = Describe("errors", func() {
+	Context("exitCode", func() {
+		It("maps catalog errors to their exit codes", func() {
+			Expect(exitCode(fmt.Errorf("failed to install: %w", catalog.ErrProfileNotFound))).To(Equal(exitCodeProfileNotFound))
+			Expect(exitCode(fmt.Errorf("failed to install: %w", catalog.ErrCatalogNotFound))).To(Equal(exitCodeCatalogNotFound))
+			Expect(exitCode(catalog.ErrCatalogUnavailable)).To(Equal(exitCodeCatalogUnavailable))
+			Expect(exitCode(catalog.ErrInvalidResponse)).To(Equal(exitCodeInvalidResponse))
+			Expect(exitCode(catalog.ErrCanceled)).To(Equal(exitCodeCanceled))
+			Expect(hint(catalog.ErrCatalogUnavailable)).To(ContainSubstring("--offline"))
+		})
+
+		It("returns 1 for any other error", func() {
+			Expect(exitCode(errors.New("boom"))).To(Equal(exitCodeError))
+			Expect(hint(errors.New("boom"))).To(BeEmpty())
+		})
+	})
+})
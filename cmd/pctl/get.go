@@ -1,16 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/urfave/cli/v2"
-	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 
 	"github.com/weaveworks/pctl/pkg/catalog"
-	"github.com/weaveworks/pctl/pkg/client"
 	"github.com/weaveworks/pctl/pkg/formatter"
 	"github.com/weaveworks/pctl/pkg/log"
 )
@@ -19,7 +19,7 @@ func getCmd() *cli.Command {
 	return &cli.Command{
 		Name:  "get",
 		Usage: "get a profile",
-		UsageText: "pctl get [--output table|json <QUERY> --installed --catalog --version] \n\n" +
+		UsageText: "pctl get [--output table|json <QUERY> --installed --catalog --version --versions] \n\n" +
 			"   example: pctl get nginx",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -46,14 +46,21 @@ func getCmd() *cli.Command {
 					"   pctl [--kubeconfig=<kubeconfig-path>] get <CATALOG>/<PROFILE>\n\n" +
 					"   example: pctl get catalog/weaveworks-nginx --profile-version v0.1.0",
 			},
+			&cli.BoolFlag{
+				Name: "versions",
+				Usage: "List every published version of a profile \n\n" +
+					"   example: pctl get catalog/weaveworks-nginx --versions",
+			},
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Value: catalog.DefaultListConcurrency,
+				Usage: "The number of installed profiles checked for updates at the same time",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			outFormat := c.String("output")
+			kubeconfig := c.String("kubeconfig")
 
-			cl, err := buildK8sClient(c.String("kubeconfig"))
-			if err != nil {
-				return err
-			}
 			catalogClient, err := getCatalogClient(c)
 			if err != nil {
 				_ = cli.ShowCommandHelp(c, "get")
@@ -63,17 +70,17 @@ func getCmd() *cli.Command {
 			// get all installed and catalog profiles
 			if c.Args().Len() < 1 {
 				if c.Bool("installed") {
-					return getInstalledProfiles(cl, catalogClient, "", outFormat)
+					return getInstalledProfiles(c, kubeconfig, catalogClient, "", outFormat)
 				}
 				if c.Bool("catalog") {
-					return getCatalogProfiles(catalogClient, "", outFormat)
+					return getCatalogProfiles(c.Context, catalogClient, "", outFormat)
 				}
 
-				err := getInstalledProfiles(cl, catalogClient, "", outFormat)
+				err := getInstalledProfiles(c, kubeconfig, catalogClient, "", outFormat)
 				if err != nil {
 					return err
 				}
-				return getCatalogProfiles(catalogClient, "", outFormat)
+				return getCatalogProfiles(c.Context, catalogClient, "", outFormat)
 			} else {
 				name, catalogClient, err := parseArgs(c)
 				if err != nil {
@@ -82,25 +89,29 @@ func getCmd() *cli.Command {
 				}
 
 				// check if other flags are passed along with the name
+				if c.Bool("versions") {
+					return getProfileVersions(c, kubeconfig, catalogClient, name, outFormat)
+				}
+
 				if c.String("profile-version") != "" {
 					return getCatalogProfilesWithVersion(c, catalogClient, name, c.String("profile-version"), outFormat)
 				}
 
 				if c.Bool("catalog") {
-					return getCatalogProfiles(catalogClient, name, outFormat)
+					return getCatalogProfiles(c.Context, catalogClient, name, outFormat)
 				}
 
 				if c.Bool("installed") {
-					return getInstalledProfiles(cl, catalogClient, name, outFormat)
+					return getInstalledProfiles(c, kubeconfig, catalogClient, name, outFormat)
 				}
 
 				// default return all installed and catalog profiles with name
-				err = getInstalledProfiles(cl, catalogClient, name, outFormat)
+				err = getInstalledProfiles(c, kubeconfig, catalogClient, name, outFormat)
 				if err != nil {
 					return err
 				}
 
-				err = getCatalogProfiles(catalogClient, name, outFormat)
+				err = getCatalogProfiles(c.Context, catalogClient, name, outFormat)
 				if err != nil {
 					return err
 				}
@@ -110,26 +121,50 @@ func getCmd() *cli.Command {
 	}
 }
 
-func getCatalogProfiles(catalogClient *client.Client, name string, outFormat string) error {
+func getCatalogProfiles(ctx context.Context, catalogClient catalog.CatalogClient, name string, outFormat string) error {
 	manager := &catalog.Manager{}
-	profiles, err := manager.Search(catalogClient, name)
+	profiles, err := manager.Search(ctx, catalogClient, name)
 	if err != nil {
 		return err
 	}
-	return formatOutput(profiles, outFormat)
+	return formatOutput(profiles, outFormat, originFunc(catalogClient))
 }
 
-func getInstalledProfiles(cl runtimeclient.Client, catalogClient *client.Client, name string, outFormat string) error {
-	manager := &catalog.Manager{}
-	data, err := manager.List(cl, catalogClient, name)
+// originFunc returns a func which looks up the endpoint an entry was served from
+// when multiple catalog endpoints are queried.
+func originFunc(catalogClient catalog.CatalogClient) func(profilesv1.ProfileCatalogEntry) string {
+	if fc, ok := catalogClient.(*catalog.FederatedClient); ok {
+		return fc.Origin
+	}
+	return nil
+}
+
+func getInstalledProfiles(c *cli.Context, kubeconfig string, catalogClient catalog.CatalogClient, name string, outFormat string) error {
+	if outFormat == "json" {
+		// keep stdout valid json.
+		log.SetOutput(os.Stderr)
+		defer log.SetOutput(nil)
+	}
+	// the kubernetes client is only built when needed so catalog queries work without cluster access.
+	cl, err := buildK8sClient(kubeconfig)
 	if err != nil {
 		return err
 	}
+	manager := &catalog.Manager{ListConcurrency: c.Int("concurrency")}
+	data, err := manager.List(c.Context, cl, catalogClient, name)
+	if err != nil {
+		return err
+	}
+	for _, d := range data {
+		if d.Err != nil {
+			log.Warningf("unable to check for updates of %s/%s: %v", d.Profile.Namespace, d.Profile.Name, d.Err)
+		}
+	}
 
 	return formatInstalledProfilesOutput(data, outFormat)
 }
 
-func getCatalogProfilesWithVersion(c *cli.Context, catalogClient *client.Client, name string, version string, outFormat string) error {
+func getCatalogProfilesWithVersion(c *cli.Context, catalogClient catalog.CatalogClient, name string, version string, outFormat string) error {
 	parts := strings.Split(name, "/")
 	if len(parts) < 2 {
 		_ = cli.ShowCommandHelp(c, "get")
@@ -137,7 +172,7 @@ func getCatalogProfilesWithVersion(c *cli.Context, catalogClient *client.Client,
 	}
 	catalogName, profileName := parts[0], parts[1]
 	manager := &catalog.Manager{}
-	profile, err := manager.Show(catalogClient, catalogName, profileName, version)
+	profile, err := manager.Show(c.Context, catalogClient, catalogName, profileName, version)
 	if err != nil {
 		return err
 	}
@@ -145,17 +180,50 @@ func getCatalogProfilesWithVersion(c *cli.Context, catalogClient *client.Client,
 	return formatCatlogProfilesOutput(profile, outFormat)
 }
 
-func profilesDataFunc(profiles []profilesv1.ProfileCatalogEntry) func() interface{} {
+func getProfileVersions(c *cli.Context, kubeconfig string, catalogClient catalog.CatalogClient, name string, outFormat string) error {
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 {
+		_ = cli.ShowCommandHelp(c, "get")
+		return fmt.Errorf("both catalog name and profile name must be provided example: pctl get catalog/weaveworks-nginx --versions")
+	}
+	catalogName, profileName := parts[0], parts[1]
+
+	if outFormat == "json" {
+		// keep stdout valid json.
+		log.SetOutput(os.Stderr)
+		defer log.SetOutput(nil)
+	}
+	// installed versions are only shown when the cluster can be reached.
+	cl, err := buildK8sClient(kubeconfig)
+	if err != nil {
+		log.Warningf("unable to determine installed versions: %v", err)
+	}
+	manager := &catalog.Manager{}
+	versions, err := manager.Versions(c.Context, cl, catalogClient, catalogName, profileName)
+	if err != nil {
+		return err
+	}
+	return formatVersionsOutput(versions, outFormat)
+}
+
+func profilesDataFunc(profiles []profilesv1.ProfileCatalogEntry, origin func(profilesv1.ProfileCatalogEntry) string) func() interface{} {
 	return func() interface{} {
 		tc := formatter.TableContents{
 			Headers: []string{"Catalog/Profile", "Version", "Description"},
 		}
+		if origin != nil {
+			tc.Headers = append(tc.Headers, "Endpoint")
+		}
 		for _, profile := range profiles {
-			tc.Data = append(tc.Data, []string{
+			row := []string{
 				fmt.Sprintf("%s/%s", profile.CatalogSource, profile.Name),
 				profilesv1.GetVersionFromTag(profile.Tag),
 				profile.Description,
-			})
+			}
+			if origin != nil {
+				row = append(row, origin(profile))
+			}
+			tc.Data = append(tc.Data, row)
 		}
 		return tc
 	}
@@ -182,6 +250,24 @@ func installedProfilesDataFunc(data []catalog.ProfileData) func() interface{} {
 	}
 }
 
+// installedProfileJSON is the json output of an installed profile, with the error ProfileData can't marshal.
+type installedProfileJSON struct {
+	catalog.ProfileData
+	Error string `json:"error,omitempty"`
+}
+
+func installedProfilesJSON(data []catalog.ProfileData) []installedProfileJSON {
+	profiles := make([]installedProfileJSON, 0, len(data))
+	for _, d := range data {
+		p := installedProfileJSON{ProfileData: d}
+		if d.Err != nil {
+			p.Error = d.Err.Error()
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
 func profileWithVersionDataFunc(profile profilesv1.ProfileCatalogEntry) func() interface{} {
 	return func() interface{} {
 		return formatter.TableContents{
@@ -198,7 +284,30 @@ func profileWithVersionDataFunc(profile profilesv1.ProfileCatalogEntry) func() i
 	}
 }
 
-func formatOutput(profiles []profilesv1.ProfileCatalogEntry, outFormat string) error {
+func profileVersionsDataFunc(versions []catalog.ProfileVersion) func() interface{} {
+	return func() interface{} {
+		tc := formatter.TableContents{
+			Headers: []string{"Version", "Prerelease", "Installed"},
+		}
+		for _, v := range versions {
+			tc.Data = append(tc.Data, []string{
+				v.Version,
+				marker(v.Prerelease),
+				strings.Join(v.Installed, ","),
+			})
+		}
+		return tc
+	}
+}
+
+func marker(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "-"
+}
+
+func formatOutput(profiles []profilesv1.ProfileCatalogEntry, outFormat string, origin func(profilesv1.ProfileCatalogEntry) string) error {
 	if len(profiles) == 0 {
 		log.Failuref("No available catalog profiles found.")
 		return nil
@@ -206,7 +315,7 @@ func formatOutput(profiles []profilesv1.ProfileCatalogEntry, outFormat string) e
 
 	var f formatter.Formatter
 	f = formatter.NewTableFormatter()
-	getter := profilesDataFunc(profiles)
+	getter := profilesDataFunc(profiles, origin)
 
 	if outFormat == "json" {
 		f = formatter.NewJSONFormatter()
@@ -238,7 +347,7 @@ func formatInstalledProfilesOutput(data []catalog.ProfileData, outFormat string)
 
 	if outFormat == "json" {
 		f = formatter.NewJSONFormatter()
-		getter = func() interface{} { return data }
+		getter = func() interface{} { return installedProfilesJSON(data) }
 	}
 
 	out, err := f.Format(getter)
@@ -277,3 +386,22 @@ func formatCatlogProfilesOutput(profile profilesv1.ProfileCatalogEntry, outForma
 	fmt.Println(out)
 	return nil
 }
+
+func formatVersionsOutput(versions []catalog.ProfileVersion, outFormat string) error {
+	var f formatter.Formatter
+	f = formatter.NewTableFormatter()
+	getter := profileVersionsDataFunc(versions)
+
+	if outFormat == "json" {
+		f = formatter.NewJSONFormatter()
+		getter = func() interface{} { return versions }
+	}
+
+	out, err := f.Format(getter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(out)
+	return nil
+}
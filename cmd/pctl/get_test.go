@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/installation"
+)
+
+var _ = Describe("get", func() {
+	Context("installedProfilesJSON", func() {
+		It("includes the error of the installations whose updates couldn't be checked", func() {
+			out, err := json.Marshal(installedProfilesJSON([]catalog.ProfileData{
+				{
+					Profile:                 installation.Summary{Name: "nginx", Namespace: "default"},
+					AvailableVersionUpdates: []string{"v0.2.0"},
+				},
+				{
+					Profile: installation.Summary{Name: "redis", Namespace: "default"},
+					Err:     errors.New("catalog unavailable"),
+				},
+			}))
+			Expect(err).NotTo(HaveOccurred())
+			var profiles []map[string]interface{}
+			Expect(json.Unmarshal(out, &profiles)).To(Succeed())
+			Expect(profiles).To(HaveLen(2))
+			Expect(profiles[0]).NotTo(HaveKey("error"))
+			Expect(profiles[0]["AvailableVersionUpdates"]).To(Equal([]interface{}{"v0.2.0"}))
+			Expect(profiles[1]["error"]).To(Equal("catalog unavailable"))
+		})
+	})
+})
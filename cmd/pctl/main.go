@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/urfave/cli/v2"
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
@@ -13,35 +16,67 @@ import (
 	"k8s.io/client-go/util/homedir"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/weaveworks/pctl/pkg/bootstrap"
+	"github.com/weaveworks/pctl/pkg/catalog"
 	"github.com/weaveworks/pctl/pkg/client"
+	"github.com/weaveworks/pctl/pkg/git"
+	"github.com/weaveworks/pctl/pkg/install"
 	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/runner"
 	"github.com/weaveworks/pctl/pkg/version"
 )
 
 const (
 	releaseUrl = "https://github.com/weaveworks/profiles/releases"
+
+	gitCLI   = "cli"
+	gitGoGit = "go-git"
 )
 
 func main() {
+	// cancelling the context on interrupt aborts in-flight catalog and Kubernetes requests.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	cancelTimeout := func() {}
 	app := &cli.App{
 		Version: version.GetVersion(),
 		Usage:   "A cli tool for interacting with profiles",
 		Flags:   globalFlags(),
+		Before: func(c *cli.Context) error {
+			if g := c.String("git"); g != gitCLI && g != gitGoGit {
+				return fmt.Errorf("invalid --git %q, must be %q or %q", g, gitCLI, gitGoGit)
+			}
+			if timeout := c.Duration("timeout"); timeout > 0 {
+				c.Context, cancelTimeout = context.WithTimeout(c.Context, timeout)
+			}
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			cancelTimeout()
+			return nil
+		},
 		Commands: []*cli.Command{
 			getCmd(),
 			addCmd(),
 			installCmd(),
 			docgenCmd(),
 			upgradeCmd(),
+			diffCmd(),
+			valuesCmd(),
 			bootstrapCmd(),
+			catalogCmd(),
+			cacheCmd(),
 		},
 	}
 
-	err := app.Run(os.Args)
+	err := app.RunContext(ctx, os.Args)
+	stop()
 	if err != nil {
 		// to prevent the timestamp in the output from log.Fatal.
 		log.Failuref("%v", err)
-		os.Exit(1)
+		if h := hint(err); h != "" {
+			log.Actionf("%s", h)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -79,11 +114,79 @@ func globalFlags() []cli.Flag {
 			Value: "profiles-system",
 			Usage: "Catalog Kubernetes Service namespace",
 		},
+		&cli.StringFlag{
+			Name:    "catalog-url",
+			Usage:   "Base URL of the catalog API. If set, the catalog is contacted directly instead of through the Kubernetes service proxy",
+			EnvVars: []string{"PCTL_CATALOG_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "catalog-path",
+			Usage:   "Path to a local directory or index file of catalog entries. If set, the catalog is read from disk and no catalog service is contacted",
+			EnvVars: []string{"PCTL_CATALOG_PATH"},
+		},
+		&cli.StringSliceFlag{
+			Name: "catalog-endpoint",
+			Usage: "A catalog endpoint to query, can be repeated to federate several catalogs. Earlier endpoints take precedence " +
+				"for duplicate entries. Format: [<name>=]<https://url|service:<namespace>/<name>:<port>|file:<path>>. A http(s) " +
+				"endpoint takes its credentials as options, e.g. https://url;token-file=<path>;ca-file=<path>;insecure-skip-tls-verify",
+			EnvVars: []string{"PCTL_CATALOG_ENDPOINTS"},
+		},
+		&cli.StringFlag{
+			Name:  "catalog-ca-file",
+			Usage: "Path to a PEM encoded CA bundle used to verify the catalog's certificate when --catalog-url is set",
+		},
+		&cli.StringFlag{
+			Name:    "catalog-token",
+			Usage:   "Bearer token to authenticate with the catalog when --catalog-url is set",
+			EnvVars: []string{"PCTL_CATALOG_TOKEN"},
+		},
+		&cli.BoolFlag{
+			Name:  "catalog-insecure-skip-tls-verify",
+			Usage: "Skip verification of the catalog's certificate when --catalog-url is set",
+		},
+		&cli.IntFlag{
+			Name:  "catalog-retry-attempts",
+			Value: client.DefaultRetryAttempts,
+			Usage: "The number of times a catalog request failing with a server error, 429 or refused connection is attempted",
+		},
+		&cli.DurationFlag{
+			Name:  "catalog-retry-max-wait",
+			Value: client.DefaultRetryMaxWait,
+			Usage: "The maximum time to wait between two attempts of a catalog request",
+		},
+		&cli.DurationFlag{
+			Name:  "cache-ttl",
+			Value: client.DefaultCacheTTL,
+			Usage: "The time a cached catalog response is used without contacting the catalog",
+		},
+		&cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Don't read or write cached catalog responses and profile repository clones",
+		},
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "Serve catalog responses and profile repositories only from the cache, regardless of their age",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "The maximum time a command may take, for example 30s or 5m. No limit if 0",
+		},
+		&cli.IntFlag{
+			Name:  "max-nesting-depth",
+			Value: install.DefaultMaxDepth,
+			Usage: "The maximum depth of nested profiles resolved when generating an installation",
+		},
+		&cli.StringFlag{
+			Name:    "git",
+			Value:   gitCLI,
+			Usage:   "The git implementation to use: cli runs the git binary, go-git runs in-process and needs no git binary",
+			EnvVars: []string{"PCTL_GIT"},
+		},
 		kubeconfigFlag,
 	}
 }
 
-func parseArgs(c *cli.Context) (string, *client.Client, error) {
+func parseArgs(c *cli.Context) (string, catalog.CatalogClient, error) {
 	if c.Args().Len() < 1 {
 		return "", nil, errors.New("<CATALOG>/<PROFILE>[/<VERSION>] must be provided")
 	}
@@ -94,20 +197,10 @@ func parseArgs(c *cli.Context) (string, *client.Client, error) {
 	return c.Args().First(), client, nil
 }
 
-func getCatalogClient(c *cli.Context) (*client.Client, error) {
+func getCatalogClient(c *cli.Context) (catalog.CatalogClient, error) {
 	return buildCatalogClient(c)
 }
 
-func buildCatalogClient(c *cli.Context) (*client.Client, error) {
-	options := client.ServiceOptions{
-		KubeconfigPath: c.String("kubeconfig"),
-		Namespace:      c.String("catalog-service-namespace"),
-		ServiceName:    c.String("catalog-service-name"),
-		ServicePort:    c.String("catalog-service-port"),
-	}
-	return client.NewFromOptions(options)
-}
-
 func buildK8sClient(kubeconfig string) (runtimeclient.Client, error) {
 	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
 	if err != nil {
@@ -120,3 +213,40 @@ func buildK8sClient(kubeconfig string) (runtimeclient.Client, error) {
 	utilruntime.Must(profilesv1.AddToScheme(cl.Scheme()))
 	return cl, nil
 }
+
+// newGit creates the git implementation selected with --git, authenticating with auth.
+func newGit(c *cli.Context, auth []git.Auth, cfg git.CLIGitConfig) git.Git {
+	cfg.Auth = auth
+	if c.String("git") == gitGoGit {
+		return git.NewGoGit(cfg)
+	}
+	return git.NewCLIGit(cfg, &runner.CLIRunner{})
+}
+
+// newInstallConfig returns the installer configuration shared by the commands resolving profiles, cloning them with g.
+func newInstallConfig(c *cli.Context, g git.Git) (install.Config, error) {
+	cache, err := newRepositoryCache(c, g)
+	if err != nil {
+		return install.Config{}, err
+	}
+	return install.Config{
+		GitClient: g,
+		Cache:     cache,
+		MaxDepth:  c.Int("max-nesting-depth"),
+	}, nil
+}
+
+// readGitAuth reads the credentials in the pctl config of the git repository of the working directory, if any.
+// Commands read it when they run and pass it to the git clients they create.
+func readGitAuth() []git.Auth {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	// commands which don't run in a repository with a pctl config use the ambient credentials of git.
+	config, err := bootstrap.ReadConfig(wd)
+	if err != nil || config == nil {
+		return nil
+	}
+	return config.Auth
+}
@@ -10,28 +10,32 @@ import (
 	"github.com/weaveworks/pctl/pkg/catalog"
 	"github.com/weaveworks/pctl/pkg/git"
 	"github.com/weaveworks/pctl/pkg/log"
-	"github.com/weaveworks/pctl/pkg/runner"
 	upgr "github.com/weaveworks/pctl/pkg/upgrade"
 	"github.com/weaveworks/pctl/pkg/upgrade/repo"
 )
 
 func upgradeCmd() *cli.Command {
 	return &cli.Command{
-		Name:      "upgrade",
-		Usage:     "upgrade profile installation",
-		UsageText: "To upgrade an installation: pctl upgrade pctl-profile-installation-path/ v0.1.1",
+		Name:  "upgrade",
+		Usage: "upgrade profile installation",
+		UsageText: "To upgrade an installation: pctl upgrade pctl-profile-installation-path/ v0.1.1\n   " +
+			"To upgrade an installation and record a new version constraint: pctl upgrade pctl-profile-installation-path/ ~0.2",
 		Flags: append(createPRFlags, &cli.BoolFlag{
 			Name:        "latest",
 			Usage:       "--latest",
 			DefaultText: "*WARNING*: Upgrade to the latest version. Please ensure this is a valid upgrade path before proceeding",
+		}, &cli.BoolFlag{
+			Name:  "ignore-version-constraint",
+			Usage: "Allow upgrading to a version outside of the version constraint the installation was added with. The constraint stays recorded on the installation.",
 		}),
 		Action: func(c *cli.Context) error {
-			if err := upgrade(c); err != nil {
+			auth := readGitAuth()
+			if err := upgrade(c, auth); err != nil {
 				return err
 			}
 			if c.Bool("create-pr") {
 				profilePath := c.Args().Slice()[0]
-				if err := createPullRequest(c, profilePath); err != nil {
+				if err := createPullRequest(c, auth, profilePath); err != nil {
 					return err
 				}
 			}
@@ -40,7 +44,7 @@ func upgradeCmd() *cli.Command {
 	}
 }
 
-func upgrade(c *cli.Context) error {
+func upgrade(c *cli.Context, auth []git.Auth) error {
 	var (
 		profilePath, profileVersion string
 	)
@@ -74,19 +78,28 @@ func upgrade(c *cli.Context) error {
 		}
 	}()
 	message := c.String("pr-message")
+	g := newGit(c, auth, git.CLIGitConfig{
+		Message: message,
+	})
+	installerConfig, err := newInstallConfig(c, g)
+	if err != nil {
+		return err
+	}
 	cfg := upgr.Config{
 		ProfileDir:     profilePath,
 		Version:        profileVersion,
 		CatalogClient:  catalogClient,
 		CatalogManager: &catalog.Manager{},
-		RepoManager: repo.NewManager(git.NewCLIGit(git.CLIGitConfig{
+		RepoManager: repo.NewManager(newGit(c, auth, git.CLIGitConfig{
 			Directory: tmpDir,
 			Quiet:     true,
 			Message:   message,
-		}, &runner.CLIRunner{})),
-		WorkingDir: tmpDir,
-		Message:    message,
-		Latest:     latest,
+		})),
+		Installer:               installerConfig,
+		WorkingDir:              tmpDir,
+		Message:                 message,
+		Latest:                  latest,
+		IgnoreVersionConstraint: c.Bool("ignore-version-constraint"),
 	}
-	return upgr.Upgrade(cfg)
+	return upgr.Upgrade(c.Context, cfg)
 }
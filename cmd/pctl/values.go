@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/git"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+func valuesCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "values",
+		Usage: "work with the values of profile installations",
+		Subcommands: []*cli.Command{
+			valuesScaffoldCmd(),
+		},
+	}
+}
+
+func valuesScaffoldCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "scaffold",
+		Usage: "generate a values ConfigMap with the default values of every chart in a profile installation",
+		UsageText: "pctl values scaffold [--out <FILE>] <INSTALLATION DIRECTORY>\n\n" +
+			"   example: pctl values scaffold --out values.yaml pctl-profile/",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "out",
+				DefaultText: "stdout",
+				Usage:       "Optional file to write the ConfigMap to.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				_ = cli.ShowCommandHelp(c, "scaffold")
+				return errors.New("the installation directory must be provided")
+			}
+			g := newGit(c, readGitAuth(), git.CLIGitConfig{})
+			installerConfig, err := newInstallConfig(c, g)
+			if err != nil {
+				return err
+			}
+			return scaffoldValues(install.NewInstaller(installerConfig), c.Args().First(), c.String("out"))
+		},
+	}
+}
+
+func scaffoldValues(installer *install.Installer, installationDir, out string) error {
+	if out == "" {
+		// keep stdout a valid yaml stream.
+		log.SetOutput(os.Stderr)
+		defer log.SetOutput(nil)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(installationDir, "profile-installation.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to read profile installation: %w", err)
+	}
+	var installation profilesv1.ProfileInstallation
+	if err := yaml.Unmarshal(content, &installation); err != nil {
+		return fmt.Errorf("failed to parse profile installation: %w", err)
+	}
+	if installation.Spec.Source == nil {
+		return fmt.Errorf("profile installation has no source")
+	}
+
+	artifacts, err := installer.Artifacts(installation)
+	if err != nil {
+		return fmt.Errorf("failed to collect artifacts: %w", err)
+	}
+
+	configMap := installation.Spec.ConfigMap
+	if configMap == "" {
+		configMap = installation.Name + "-values"
+		log.Warningf("installation %q doesn't reference a values ConfigMap, set spec.configMap to %q to use the scaffolded values", installation.Name, configMap)
+	}
+	values, err := install.ScaffoldValues(installation, artifacts, configMap)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		fmt.Print(string(values))
+		return nil
+	}
+	if err := ioutil.WriteFile(out, values, 0644); err != nil {
+		return fmt.Errorf("failed to write values to %q: %w", out, err)
+	}
+	log.Successf("values ConfigMap written to %s", out)
+	return nil
+}
@@ -0,0 +1,256 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/regenerate"
+	"github.com/weaveworks/pctl/pkg/upgrade/repo"
+)
+
+// Manifest lists profile installations which are added together.
+type Manifest struct {
+	Installations []Installation `json:"installations"`
+}
+
+// Installation is a profile installation of a manifest. It either comes from a catalog with
+// Catalog, Profile and an optional Version, or from a profile repository with URL, Branch and Path.
+type Installation struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Catalog   string `json:"catalog,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	// Version is an exact version, latest or a semver constraint.
+	Version   string `json:"version,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	Path      string `json:"path,omitempty"`
+	ConfigMap string `json:"configMap,omitempty"`
+	// Out is the directory the installation directory is created in.
+	Out string `json:"out,omitempty"`
+	// GitRepository is the <namespace>/<name> of the GitRepository governing the flux repo.
+	GitRepository string `json:"gitRepository,omitempty"`
+}
+
+// Defaults are used for the fields an installation of the manifest doesn't set.
+type Defaults struct {
+	Namespace     string
+	Branch        string
+	Path          string
+	Out           string
+	GitRepository string
+}
+
+// Config holds the fields used to add the installations of a manifest.
+type Config struct {
+	CatalogClient  catalog.CatalogClient
+	CatalogManager catalog.CatalogManager
+	// Installer is the installer whose configuration and clone cache are shared by all installations,
+	// so repositories used by several of them are only cloned once.
+	Installer           *install.Installer
+	PrerequisiteChecker catalog.PrerequisiteChecker
+	IgnorePrerequisites bool
+	Defaults            Defaults
+	// Mode is how installations whose directory already contains one are handled.
+	Mode regenerate.Mode
+	// NewRepoManager creates the repository manager used to merge existing installations.
+	NewRepoManager func(dir string) repo.RepoManager
+	// Locked generates every installation from the commits recorded in the lock file of its directory.
+	Locked bool
+}
+
+// Load reads a manifest from path.
+func Load(path string) (Manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m Manifest
+	if err := yaml.UnmarshalStrict(content, &m); err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse manifest %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// Add validates every installation of the manifest before generating any of them, and returns
+// the directories of the generated installations.
+func Add(ctx context.Context, cfg Config, m Manifest) ([]string, error) {
+	installations := make([]Installation, 0, len(m.Installations))
+	for _, i := range m.Installations {
+		installations = append(installations, cfg.Defaults.apply(i))
+	}
+	if err := Validate(installations); err != nil {
+		return nil, err
+	}
+	if err := checkExisting(cfg.Mode, installations); err != nil {
+		return nil, err
+	}
+	for _, i := range installations {
+		if err := checkCatalogProfile(ctx, cfg, i); err != nil {
+			return nil, err
+		}
+	}
+
+	var dirs []string
+	for _, i := range installations {
+		dir := filepath.Join(i.Out, i.Name)
+		log.Actionf("generating profile installation %q in %s", i.Name, dir)
+		installer, installCfg := cfg.installConfig(i, dir)
+		regenerateCfg := regenerate.Config{
+			Mode:           cfg.Mode,
+			CatalogManager: cfg.CatalogManager,
+			Installer:      installer,
+			NewRepoManager: cfg.NewRepoManager,
+			Locked:         cfg.Locked,
+		}
+		if _, err := regenerate.Install(ctx, regenerateCfg, installCfg); err != nil {
+			return dirs, fmt.Errorf("failed to add installation %q: %w", i.Name, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// Validate checks that every installation of the manifest is complete and that no two installations
+// are generated into the same directory. It reports the problems of all installations at once.
+func Validate(installations []Installation) error {
+	if len(installations) == 0 {
+		return fmt.Errorf("manifest contains no installations")
+	}
+	var problems []string
+	dirs := make(map[string]int)
+	for n, i := range installations {
+		invalid := func(format string, a ...interface{}) {
+			problems = append(problems, fmt.Sprintf("installation %d (%s): %s", n+1, i.Name, fmt.Sprintf(format, a...)))
+		}
+		if i.Name == "" {
+			invalid("name must be provided")
+		}
+		if i.URL != "" && (i.Catalog != "" || i.Profile != "") {
+			invalid("either a catalog profile or a url must be provided, not both")
+		}
+		if i.URL == "" && (i.Catalog == "" || i.Profile == "") {
+			invalid("both catalog and profile must be provided when no url is")
+		}
+		if i.URL != "" && i.Version != "" {
+			invalid("version can only be used with a catalog profile, use branch for a url")
+		}
+		if i.GitRepository == "" {
+			invalid("flux git repository not provided, please set gitRepository or use the pctl bootstrap functionality")
+		} else if len(strings.Split(i.GitRepository, "/")) != 2 {
+			invalid("gitRepository must be in the format <namespace>/<name>; was: %s", i.GitRepository)
+		}
+		if i.Name != "" {
+			dir := filepath.Clean(filepath.Join(i.Out, i.Name))
+			if previous, ok := dirs[dir]; ok {
+				invalid("directory %s is already used by installation %d", dir, previous)
+			}
+			dirs[dir] = n + 1
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid manifest:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// checkExisting makes sure no installation is generated into a directory which already contains one,
+// unless the mode says how to handle them.
+func checkExisting(mode regenerate.Mode, installations []Installation) error {
+	if mode == regenerate.Force || mode == regenerate.Merge {
+		return nil
+	}
+	var existing []string
+	for _, i := range installations {
+		if dir := filepath.Join(i.Out, i.Name); regenerate.Exists(dir) {
+			existing = append(existing, dir)
+		}
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("directories already contain a profile installation: %s; use --force to generate them again or --merge to keep the local changes made to them", strings.Join(existing, ", "))
+	}
+	return nil
+}
+
+// checkCatalogProfile makes sure the catalog has the version of the profile an installation refers to.
+func checkCatalogProfile(ctx context.Context, cfg Config, i Installation) error {
+	if i.URL != "" {
+		return nil
+	}
+	if catalog.IsVersionConstraint(i.Version) {
+		if _, err := cfg.CatalogManager.ResolveVersionConstraint(ctx, cfg.CatalogClient, i.Catalog, i.Profile, i.Version); err != nil {
+			return fmt.Errorf("installation %q: %w", i.Name, err)
+		}
+		return nil
+	}
+	if _, err := cfg.CatalogManager.Show(ctx, cfg.CatalogClient, i.Catalog, i.Profile, i.Version); err != nil {
+		return fmt.Errorf("installation %q: failed to get profile %q in catalog %q: %w", i.Name, i.Profile, i.Catalog, err)
+	}
+	return nil
+}
+
+func (cfg Config) installConfig(i Installation, dir string) (*install.Installer, catalog.InstallConfig) {
+	parts := strings.Split(i.GitRepository, "/")
+	gitRepoNamespace, gitRepoName := parts[0], parts[1]
+	installerConfig := cfg.Installer.Config
+	installerConfig.RootDir = dir
+	installerConfig.GitRepoNamespace = gitRepoNamespace
+	installerConfig.GitRepoName = gitRepoName
+	installer := cfg.Installer.WithConfig(installerConfig)
+	return installer, catalog.InstallConfig{
+		Clients: catalog.Clients{
+			CatalogClient:       cfg.CatalogClient,
+			Installer:           installer,
+			PrerequisiteChecker: cfg.PrerequisiteChecker,
+		},
+		Profile: catalog.Profile{
+			ProfileConfig: catalog.ProfileConfig{
+				CatalogName:           i.Catalog,
+				ProfileName:           i.Profile,
+				Version:               i.Version,
+				URL:                   i.URL,
+				ProfileBranch:         i.Branch,
+				Path:                  i.Path,
+				ConfigMap:             i.ConfigMap,
+				InstallationName:      i.Name,
+				InstallationNamespace: i.Namespace,
+			},
+			GitRepoConfig: catalog.GitRepoConfig{
+				Namespace: gitRepoNamespace,
+				Name:      gitRepoName,
+			},
+		},
+		IgnorePrerequisites: cfg.IgnorePrerequisites,
+	}
+}
+
+func (d Defaults) apply(i Installation) Installation {
+	if i.Namespace == "" {
+		i.Namespace = d.Namespace
+	}
+	if i.URL != "" {
+		if i.Branch == "" {
+			i.Branch = d.Branch
+		}
+		if i.Path == "" {
+			i.Path = d.Path
+		}
+	} else if i.Version == "" {
+		i.Version = "latest"
+	}
+	if i.Out == "" {
+		i.Out = d.Out
+	}
+	if i.GitRepository == "" {
+		i.GitRepository = d.GitRepository
+	}
+	return i
+}
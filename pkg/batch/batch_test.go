@@ -0,0 +1,212 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/batch"
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	gitfakes "github.com/weaveworks/pctl/pkg/git/fakes"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/regenerate"
+)
+
+var _ = Describe("Batch", func() {
+	var (
+		fakeCatalogManager *fakes.FakeCatalogManager
+		cfg                batch.Config
+		manifest           batch.Manifest
+	)
+
+	BeforeEach(func() {
+		fakeCatalogManager = new(fakes.FakeCatalogManager)
+		cfg = batch.Config{
+			CatalogManager: fakeCatalogManager,
+			Installer:      install.NewInstaller(install.Config{GitClient: new(gitfakes.FakeGit)}),
+			Defaults: batch.Defaults{
+				Namespace:     "default",
+				Branch:        "main",
+				Path:          ".",
+				Out:           "clusters",
+				GitRepository: "flux-system/flux-system",
+			},
+		}
+		manifest = batch.Manifest{
+			Installations: []batch.Installation{
+				{
+					Name:      "nginx",
+					Catalog:   "weaveworks",
+					Profile:   "nginx",
+					Version:   "~0.1",
+					ConfigMap: "nginx-values",
+				},
+				{
+					Name:          "bitnami",
+					Namespace:     "web",
+					URL:           "https://github.com/weaveworks/profiles-examples",
+					Path:          "bitnami-nginx",
+					Out:           "clusters/prod",
+					GitRepository: "flux/repo",
+				},
+				{
+					Name:    "redis",
+					Catalog: "weaveworks",
+					Profile: "redis",
+				},
+			},
+		}
+	})
+
+	It("generates every installation of the manifest", func() {
+		dirs, err := batch.Add(context.Background(), cfg, manifest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirs).To(Equal([]string{"clusters/nginx", "clusters/prod/bitnami", "clusters/redis"}))
+
+		By("checking the catalog profiles before generating any installation")
+		Expect(fakeCatalogManager.ResolveVersionConstraintCallCount()).To(Equal(1))
+		_, _, catalogName, profileName, constraint := fakeCatalogManager.ResolveVersionConstraintArgsForCall(0)
+		Expect([]string{catalogName, profileName, constraint}).To(Equal([]string{"weaveworks", "nginx", "~0.1"}))
+		Expect(fakeCatalogManager.ShowCallCount()).To(Equal(1))
+		_, _, catalogName, profileName, version := fakeCatalogManager.ShowArgsForCall(0)
+		Expect([]string{catalogName, profileName, version}).To(Equal([]string{"weaveworks", "redis", "latest"}))
+
+		Expect(fakeCatalogManager.InstallCallCount()).To(Equal(3))
+		_, nginx := fakeCatalogManager.InstallArgsForCall(0)
+		Expect(nginx.Profile).To(Equal(catalog.Profile{
+			ProfileConfig: catalog.ProfileConfig{
+				CatalogName:           "weaveworks",
+				ProfileName:           "nginx",
+				Version:               "~0.1",
+				ConfigMap:             "nginx-values",
+				InstallationName:      "nginx",
+				InstallationNamespace: "default",
+			},
+			GitRepoConfig: catalog.GitRepoConfig{Namespace: "flux-system", Name: "flux-system"},
+		}))
+		installer, ok := nginx.Installer.(*install.Installer)
+		Expect(ok).To(BeTrue())
+		Expect(installer.RootDir).To(Equal("clusters/nginx"))
+
+		_, bitnami := fakeCatalogManager.InstallArgsForCall(1)
+		Expect(bitnami.Profile).To(Equal(catalog.Profile{
+			ProfileConfig: catalog.ProfileConfig{
+				URL:                   "https://github.com/weaveworks/profiles-examples",
+				ProfileBranch:         "main",
+				Path:                  "bitnami-nginx",
+				InstallationName:      "bitnami",
+				InstallationNamespace: "web",
+			},
+			GitRepoConfig: catalog.GitRepoConfig{Namespace: "flux", Name: "repo"},
+		}))
+	})
+
+	When("installations are invalid", func() {
+		It("reports all of them without generating any installation", func() {
+			manifest.Installations = append(manifest.Installations,
+				batch.Installation{Catalog: "weaveworks", Profile: "nginx"},
+				batch.Installation{Name: "nginx", URL: "https://github.com/weaveworks/profiles-examples", Profile: "nginx"},
+				batch.Installation{Name: "other", URL: "https://github.com/weaveworks/profiles-examples", Version: "v0.1.0", GitRepository: "repo"},
+			)
+			_, err := batch.Add(context.Background(), cfg, manifest)
+			Expect(err).To(MatchError(`invalid manifest:
+installation 4 (): name must be provided
+installation 5 (nginx): either a catalog profile or a url must be provided, not both
+installation 5 (nginx): directory clusters/nginx is already used by installation 1
+installation 6 (other): version can only be used with a catalog profile, use branch for a url
+installation 6 (other): gitRepository must be in the format <namespace>/<name>; was: repo`))
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(0))
+		})
+	})
+
+	When("a catalog profile doesn't exist", func() {
+		It("errors without generating any installation", func() {
+			fakeCatalogManager.ShowReturns(profilesv1.ProfileCatalogEntry{}, errors.New("not found"))
+			_, err := batch.Add(context.Background(), cfg, manifest)
+			Expect(err).To(MatchError(`installation "redis": failed to get profile "redis" in catalog "weaveworks": not found`))
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(0))
+		})
+	})
+
+	When("installation directories already contain an installation", func() {
+		var out string
+
+		BeforeEach(func() {
+			var err error
+			out, err = ioutil.TempDir("", "batch")
+			Expect(err).NotTo(HaveOccurred())
+			cfg.Defaults.Out = out
+			Expect(os.MkdirAll(filepath.Join(out, "redis"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(out, "redis", "profile-installation.yaml"), []byte("kind: ProfileInstallation"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(out)
+		})
+
+		It("errors without generating any installation", func() {
+			_, err := batch.Add(context.Background(), cfg, manifest)
+			Expect(err).To(MatchError(fmt.Sprintf("directories already contain a profile installation: %s; use --force to generate them again or --merge to keep the local changes made to them", filepath.Join(out, "redis"))))
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(0))
+		})
+
+		It("generates them again when forced", func() {
+			cfg.Mode = regenerate.Force
+			_, err := batch.Add(context.Background(), cfg, manifest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(3))
+		})
+	})
+
+	When("generating an installation fails", func() {
+		It("returns the directories generated so far", func() {
+			fakeCatalogManager.InstallReturnsOnCall(1, errors.New("clone failed"))
+			dirs, err := batch.Add(context.Background(), cfg, manifest)
+			Expect(err).To(MatchError(`failed to add installation "bitnami": clone failed`))
+			Expect(dirs).To(Equal([]string{"clusters/nginx"}))
+		})
+	})
+
+	Context("Load", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "batch")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		It("reads the manifest", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(`installations:
+- name: nginx
+  catalog: weaveworks
+  profile: nginx
+  version: v0.1.0
+  out: clusters/prod
+`), 0644)).To(Succeed())
+			m, err := batch.Load(filepath.Join(dir, "manifest.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(Equal(batch.Manifest{Installations: []batch.Installation{
+				{Name: "nginx", Catalog: "weaveworks", Profile: "nginx", Version: "v0.1.0", Out: "clusters/prod"},
+			}}))
+		})
+
+		It("rejects unknown fields", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("installations:\n- name: nginx\n  profileName: nginx\n"), 0644)).To(Succeed())
+			_, err := batch.Load(filepath.Join(dir, "manifest.yaml"))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "profileName"`)))
+		})
+	})
+})
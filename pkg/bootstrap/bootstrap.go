@@ -9,6 +9,7 @@ import (
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	"gopkg.in/yaml.v2"
 
+	"github.com/weaveworks/pctl/pkg/git"
 	"github.com/weaveworks/pctl/pkg/log"
 	"github.com/weaveworks/pctl/pkg/runner"
 )
@@ -19,6 +20,8 @@ type Config struct {
 	GitRepository profilesv1.GitRepository `yaml:"gitRepository,omitempty"`
 	// DefaultDir defines the location to use with pctl add
 	DefaultDir string `yaml:"defaultDir,omitempty"`
+	// Auth holds the credentials for the repositories of profiles and pull requests, by repository URL prefix
+	Auth []git.Auth `yaml:"auth,omitempty"`
 }
 
 var r runner.Runner = &runner.CLIRunner{}
@@ -45,30 +48,37 @@ func CreateConfig(cfg Config, directory string) error {
 
 //GetConfig gets the bootstrap config
 func GetConfig(directory string) *Config {
-	gitDir, err := getGitRepoPath(directory)
+	config, err := ReadConfig(directory)
 	if err != nil {
-		log.Warningf("failed to get git repo path: %v", err)
+		log.Warningf("%v", err)
 		return nil
 	}
-	configPath := filepath.Join(gitDir, ".pctl", "config.yaml")
-
-	out, err := os.ReadFile(configPath)
-	if os.IsNotExist(err) {
+	if config == nil {
 		// don't use Warningf in case the file doesn't exist. Warning is a bit intrusive
 		// the config file not existing is a perfectly fine scenario.
 		fmt.Println("config file cannot be found... using default values")
-		return nil
-	} else if err != nil {
-		log.Warningf("failed to read config file: %v", err)
-		return nil
 	}
+	return config
+}
 
+// ReadConfig reads the bootstrap config of the git repository of directory. It returns nil if there is none.
+func ReadConfig(directory string) (*Config, error) {
+	gitDir, err := getGitRepoPath(directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git repo path: %w", err)
+	}
+	configPath := filepath.Join(gitDir, ".pctl", "config.yaml")
+	out, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
 	config := &Config{}
 	if err = yaml.Unmarshal(out, config); err != nil {
-		log.Warningf("failed to unmarshal config file: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to unmarshal config file: %w", err)
 	}
-	return config
+	return config, nil
 }
 
 func getGitRepoPath(directory string) (string, error) {
@@ -14,6 +14,7 @@ import (
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 
 	"github.com/weaveworks/pctl/pkg/bootstrap"
+	"github.com/weaveworks/pctl/pkg/git"
 	"github.com/weaveworks/pctl/pkg/runner"
 	"github.com/weaveworks/pctl/pkg/runner/fakes"
 )
@@ -35,6 +36,17 @@ var _ = Describe("Bootstrap", func() {
 				Namespace: "foo",
 			},
 			DefaultDir: "default-dir",
+			Auth: []git.Auth{
+				{
+					URLPrefix: "https://github.com/weaveworks/",
+					TokenEnv:  "GITHUB_TOKEN",
+				},
+				{
+					URLPrefix:      "git@github.com:weaveworks/",
+					SSHKeyFile:     "~/.ssh/id_profiles",
+					KnownHostsFile: "~/.ssh/known_hosts",
+				},
+			},
 		}
 	})
 
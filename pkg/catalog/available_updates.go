@@ -1,8 +1,8 @@
 package catalog
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -13,29 +13,52 @@ import (
 
 // GetAvailableUpdates queries the catalog at catalogURL for profiles which have greater versions than the current
 // given one.
-func GetAvailableUpdates(catalogClient CatalogClient, catalogName, profileName, profileVersion string) ([]profilesv1.ProfileCatalogEntry, error) {
+func GetAvailableUpdates(ctx context.Context, catalogClient CatalogClient, catalogName, profileName, profileVersion string) ([]profilesv1.ProfileCatalogEntry, error) {
 	u, err := url.Parse("/profiles")
 	if err != nil {
 		return nil, err
 	}
 	u.Path = path.Join(u.Path, catalogName, profileName, profileVersion, "available_updates")
-	data, statusCode, err := catalogClient.DoRequest(u.String(), nil)
+	data, statusCode, err := catalogClient.DoRequest(ctx, u.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch catalog: %w", err)
+		return nil, requestError(err, "failed to fetch catalog")
 	}
 
 	if statusCode != http.StatusOK {
 		if statusCode == http.StatusNotFound {
-			// 404 right now is valid in case there are no updated versions
-			// this must be re-visited after https://github.com/weaveworks/profiles/issues/143
-			return nil, nil
+			// the catalog answers with 404 both when there are no updated versions and when the
+			// profile doesn't exist, see https://github.com/weaveworks/profiles/issues/143
+			return nil, installedVersionError(ctx, catalogClient, catalogName, profileName, profileVersion)
 		}
-		return nil, fmt.Errorf("failed to fetch available updates for profile, status code %d", statusCode)
+		return nil, statusError(statusCode, "failed to fetch available updates for profile")
 	}
 	var profiles protos.GRPCProfileCatalogEntryList
 	if err := json.Unmarshal(data, &profiles); err != nil {
-		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+		return nil, parseError(err, "failed to parse catalog")
 	}
 
 	return profiles.Items, nil
 }
+
+// installedVersionError checks whether the catalog has the installed version of the profile, in which case there
+// are no updates and it returns nil. It only requests the installed version, a missing version, profile or catalog
+// are all reported as ErrProfileNotFound.
+func installedVersionError(ctx context.Context, catalogClient CatalogClient, catalogName, profileName, profileVersion string) error {
+	u, err := url.Parse("/profiles")
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, catalogName, profileName, profileVersion)
+	_, statusCode, err := catalogClient.DoRequest(ctx, u.String(), nil)
+	if err != nil {
+		return requestError(err, "failed to fetch profile from catalog")
+	}
+	switch statusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return profileNotFoundError("unable to find profile %q in catalog %q (with version if provided: %s)", profileName, catalogName, profileVersion)
+	default:
+		return statusError(statusCode, "failed to fetch profile from catalog")
+	}
+}
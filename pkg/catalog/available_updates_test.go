@@ -1,6 +1,8 @@
 package catalog_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	. "github.com/onsi/ginkgo"
@@ -40,10 +42,10 @@ var _ = Describe("GetAvailableUpdates", func() {
 		  `)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			resp, err := catalog.GetAvailableUpdates(fakeCatalogClient, "catalog", "weaveworks-nginx", "v0.0.1")
+			resp, err := catalog.GetAvailableUpdates(context.Background(), fakeCatalogClient, "catalog", "weaveworks-nginx", "v0.0.1")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles/catalog/weaveworks-nginx/v0.0.1/available_updates"))
 			Expect(query).To(BeNil())
 			Expect(resp).To(ConsistOf(
@@ -65,10 +67,33 @@ var _ = Describe("GetAvailableUpdates", func() {
 		})
 	})
 
+	When("the catalog returns 404", func() {
+		It("returns no updates if the installed version exists", func() {
+			fakeCatalogClient.DoRequestReturnsOnCall(0, nil, 404, nil)
+			fakeCatalogClient.DoRequestReturnsOnCall(1, []byte(`{"item": {"name": "weaveworks-nginx", "catalogSource": "catalog", "tag": "v0.0.1"}}`), 200, nil)
+
+			resp, err := catalog.GetAvailableUpdates(context.Background(), fakeCatalogClient, "catalog", "weaveworks-nginx", "v0.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp).To(BeEmpty())
+			_, path, _ := fakeCatalogClient.DoRequestArgsForCall(1)
+			Expect(path).To(Equal("/profiles/catalog/weaveworks-nginx/v0.0.1"))
+		})
+
+		It("returns an error without searching the catalog if the installed version doesn't exist", func() {
+			fakeCatalogClient.DoRequestReturnsOnCall(0, nil, 404, nil)
+			fakeCatalogClient.DoRequestReturnsOnCall(1, nil, 404, nil)
+
+			_, err := catalog.GetAvailableUpdates(context.Background(), fakeCatalogClient, "catalgo", "weaveworks-nginx", "v0.0.1")
+			Expect(err).To(MatchError(`unable to find profile "weaveworks-nginx" in catalog "catalgo" (with version if provided: v0.0.1)`))
+			Expect(errors.Is(err, catalog.ErrProfileNotFound)).To(BeTrue())
+			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(2))
+		})
+	})
+
 	When("catalog client fails to make the request", func() {
 		It("returns an error", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 502, fmt.Errorf("foo"))
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(MatchError("failed to fetch catalog: foo"))
 		})
 	})
@@ -78,9 +103,9 @@ var _ = Describe("GetAvailableUpdates", func() {
 			httpBody := []byte(`[]`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 500, nil)
 
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(MatchError("failed to fetch profile from catalog, status code 500"))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles"))
 			Expect(query).To(Equal(map[string]string{
 				"name": "dontexist",
@@ -93,7 +118,7 @@ var _ = Describe("GetAvailableUpdates", func() {
 			httpBody := []byte(`!20342 totally n:ot json "`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError(ContainSubstring("failed to parse catalog")))
 		})
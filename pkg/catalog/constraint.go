@@ -0,0 +1,65 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+)
+
+// VersionConstraintAnnotation records the semver constraint a profile installation was added with.
+const VersionConstraintAnnotation = "pctl.weave.works/version-constraint"
+
+// IsVersionConstraint returns whether version is a semver range such as ~0.2 or >=1.2 <2.0
+// instead of an exact version or "latest".
+func IsVersionConstraint(version string) bool {
+	if version == "" || version == "latest" {
+		return false
+	}
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(version)
+	return err == nil
+}
+
+// ResolveVersionConstraint returns the greatest version of the profile known to the catalog
+// which satisfies the constraint.
+func (m *Manager) ResolveVersionConstraint(ctx context.Context, catalogClient CatalogClient, catalogName, profileName, constraint string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+	candidates, err := m.profileEntries(ctx, catalogClient, catalogName, profileName)
+	if err != nil {
+		return "", err
+	}
+
+	version, ok := LatestMatchingVersion(candidates, c)
+	if !ok {
+		return "", newError(ErrProfileNotFound, nil, "no version of profile %q in catalog %q satisfies constraint %q", profileName, catalogName, constraint)
+	}
+	return version, nil
+}
+
+// LatestMatchingVersion returns the greatest version of entries which satisfies the constraint.
+func LatestMatchingVersion(entries []profilesv1.ProfileCatalogEntry, c *semver.Constraints) (string, bool) {
+	var (
+		latest     *semver.Version
+		latestName string
+	)
+	for _, e := range entries {
+		name := profilesv1.GetVersionFromTag(e.Tag)
+		v, err := semver.NewVersion(name)
+		if err != nil {
+			continue
+		}
+		if c.Check(v) && (latest == nil || v.GreaterThan(latest)) {
+			latest = v
+			latestName = name
+		}
+	}
+	return latestName, latest != nil
+}
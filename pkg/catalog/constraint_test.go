@@ -0,0 +1,80 @@
+package catalog_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+)
+
+var _ = Describe("VersionConstraint", func() {
+	var (
+		fakeCatalogClient *fakes.FakeCatalogClient
+		manager           catalog.Manager
+	)
+
+	BeforeEach(func() {
+		fakeCatalogClient = new(fakes.FakeCatalogClient)
+		fakeCatalogClient.DoRequestReturns([]byte(`{"items":[
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.1.0"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.2.0"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.2.3"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v1.0.0"},
+  {"name": "nginx", "catalogSource": "other", "tag": "nginx/v0.2.9"},
+  {"name": "nginx-2", "catalogSource": "cat", "tag": "nginx-2/v0.2.8"}
+]}`), 200, nil)
+	})
+
+	Describe("IsVersionConstraint", func() {
+		It("returns true for semver ranges", func() {
+			for _, v := range []string{"~0.2", "^1.0", ">=1.2 <2.0"} {
+				Expect(catalog.IsVersionConstraint(v)).To(BeTrue(), v)
+			}
+		})
+
+		It("returns false for exact versions and latest", func() {
+			for _, v := range []string{"", "latest", "v0.1.0", "0.1.0", "not-a-version"} {
+				Expect(catalog.IsVersionConstraint(v)).To(BeFalse(), v)
+			}
+		})
+	})
+
+	Describe("ResolveVersionConstraint", func() {
+		It("returns the greatest version of the profile which satisfies the constraint", func() {
+			version, err := manager.ResolveVersionConstraint(context.Background(), fakeCatalogClient, "cat", "nginx", "~0.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("v0.2.3"))
+
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			Expect(path).To(Equal("/profiles"))
+			Expect(query).To(Equal(map[string]string{"name": "nginx"}))
+		})
+
+		When("no version satisfies the constraint", func() {
+			It("returns an error", func() {
+				_, err := manager.ResolveVersionConstraint(context.Background(), fakeCatalogClient, "cat", "nginx", ">=2.0")
+				Expect(err).To(MatchError(`no version of profile "nginx" in catalog "cat" satisfies constraint ">=2.0"`))
+				Expect(errors.Is(err, catalog.ErrProfileNotFound)).To(BeTrue())
+			})
+		})
+
+		When("the catalog doesn't exist", func() {
+			It("returns an error", func() {
+				_, err := manager.ResolveVersionConstraint(context.Background(), fakeCatalogClient, "nope", "nginx", "~0.2")
+				Expect(err).To(MatchError(`unable to find catalog "nope"`))
+				Expect(errors.Is(err, catalog.ErrCatalogNotFound)).To(BeTrue())
+			})
+		})
+
+		When("the constraint is invalid", func() {
+			It("returns an error", func() {
+				_, err := manager.ResolveVersionConstraint(context.Background(), fakeCatalogClient, "cat", "nginx", "~~")
+				Expect(err).To(MatchError(ContainSubstring("invalid version constraint")))
+			})
+		})
+	})
+})
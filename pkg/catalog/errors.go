@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var (
+	// ErrProfileNotFound is returned when the catalog doesn't have the requested profile or version.
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrCatalogNotFound is returned when no profiles are served from the requested catalog.
+	ErrCatalogNotFound = errors.New("catalog not found")
+	// ErrCatalogUnavailable is returned when the catalog can't be reached or fails to answer.
+	ErrCatalogUnavailable = errors.New("catalog unavailable")
+	// ErrInvalidResponse is returned when the catalog answers with an unexpected status code or body.
+	ErrInvalidResponse = errors.New("invalid catalog response")
+	// ErrCanceled is returned when a catalog request is canceled or its deadline is exceeded before the
+	// catalog answers.
+	ErrCanceled = errors.New("catalog request canceled")
+)
+
+// Error is returned by catalog operations. It matches one of the Err* sentinel errors
+// with errors.Is and unwraps to its cause, such as a *client.StatusError.
+type Error struct {
+	// Kind is one of the Err* sentinel errors.
+	Kind error
+	// Err is the underlying cause, if any.
+	Err error
+	msg string
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind, cause error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Err: cause, msg: fmt.Sprintf(format, args...)}
+}
+
+// requestError wraps an error returned by a catalog client. A canceled request or an exceeded deadline
+// says nothing about the catalog, so it isn't reported as unavailable.
+func requestError(err error, format string, args ...interface{}) error {
+	kind := ErrCatalogUnavailable
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrCanceled
+	}
+	return newError(kind, err, "%s: %v", fmt.Sprintf(format, args...), err)
+}
+
+// statusError maps an unsuccessful status code to an error. Server errors and throttling
+// make the catalog unavailable, any other status is unexpected.
+func statusError(code int, format string, args ...interface{}) error {
+	kind := ErrInvalidResponse
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		kind = ErrCatalogUnavailable
+	}
+	return newError(kind, client.NewStatusError(code), "%s, status code %d", fmt.Sprintf(format, args...), code)
+}
+
+// parseError wraps an error decoding a catalog response.
+func parseError(err error, format string, args ...interface{}) error {
+	return newError(ErrInvalidResponse, err, "%s: %v", fmt.Sprintf(format, args...), err)
+}
+
+func profileNotFoundError(format string, args ...interface{}) error {
+	return newError(ErrProfileNotFound, client.NewStatusError(http.StatusNotFound), format, args...)
+}
+
+// notFoundError tells apart a missing profile from a missing catalog. The catalog answers
+// both with 404, so this checks whether any profile is served from catalogName and returns
+// profileErr if there is.
+func notFoundError(ctx context.Context, catalogClient CatalogClient, catalogName string, profileErr error) error {
+	profiles, err := (&Manager{}).Search(ctx, catalogClient, "")
+	if err != nil {
+		return profileErr
+	}
+	for _, p := range profiles {
+		if p.CatalogSource == catalogName {
+			return profileErr
+		}
+	}
+	return newError(ErrCatalogNotFound, client.NewStatusError(http.StatusNotFound), "unable to find catalog %q", catalogName)
+}
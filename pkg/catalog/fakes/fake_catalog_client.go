@@ -2,17 +2,19 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/weaveworks/pctl/pkg/catalog"
 )
 
 type FakeCatalogClient struct {
-	DoRequestStub        func(string, map[string]string) ([]byte, int, error)
+	DoRequestStub        func(context.Context, string, map[string]string) ([]byte, int, error)
 	doRequestMutex       sync.RWMutex
 	doRequestArgsForCall []struct {
-		arg1 string
-		arg2 map[string]string
+		arg1 context.Context
+		arg2 string
+		arg3 map[string]string
 	}
 	doRequestReturns struct {
 		result1 []byte
@@ -28,19 +30,20 @@ type FakeCatalogClient struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCatalogClient) DoRequest(arg1 string, arg2 map[string]string) ([]byte, int, error) {
+func (fake *FakeCatalogClient) DoRequest(arg1 context.Context, arg2 string, arg3 map[string]string) ([]byte, int, error) {
 	fake.doRequestMutex.Lock()
 	ret, specificReturn := fake.doRequestReturnsOnCall[len(fake.doRequestArgsForCall)]
 	fake.doRequestArgsForCall = append(fake.doRequestArgsForCall, struct {
-		arg1 string
-		arg2 map[string]string
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 map[string]string
+	}{arg1, arg2, arg3})
 	stub := fake.DoRequestStub
 	fakeReturns := fake.doRequestReturns
-	fake.recordInvocation("DoRequest", []interface{}{arg1, arg2})
+	fake.recordInvocation("DoRequest", []interface{}{arg1, arg2, arg3})
 	fake.doRequestMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -54,17 +57,17 @@ func (fake *FakeCatalogClient) DoRequestCallCount() int {
 	return len(fake.doRequestArgsForCall)
 }
 
-func (fake *FakeCatalogClient) DoRequestCalls(stub func(string, map[string]string) ([]byte, int, error)) {
+func (fake *FakeCatalogClient) DoRequestCalls(stub func(context.Context, string, map[string]string) ([]byte, int, error)) {
 	fake.doRequestMutex.Lock()
 	defer fake.doRequestMutex.Unlock()
 	fake.DoRequestStub = stub
 }
 
-func (fake *FakeCatalogClient) DoRequestArgsForCall(i int) (string, map[string]string) {
+func (fake *FakeCatalogClient) DoRequestArgsForCall(i int) (context.Context, string, map[string]string) {
 	fake.doRequestMutex.RLock()
 	defer fake.doRequestMutex.RUnlock()
 	argsForCall := fake.doRequestArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeCatalogClient) DoRequestReturns(result1 []byte, result2 int, result3 error) {
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/weaveworks/pctl/pkg/catalog"
@@ -10,10 +11,11 @@ import (
 )
 
 type FakeCatalogManager struct {
-	InstallStub        func(catalog.InstallConfig) error
+	InstallStub        func(context.Context, catalog.InstallConfig) error
 	installMutex       sync.RWMutex
 	installArgsForCall []struct {
-		arg1 catalog.InstallConfig
+		arg1 context.Context
+		arg2 catalog.InstallConfig
 	}
 	installReturns struct {
 		result1 error
@@ -21,12 +23,13 @@ type FakeCatalogManager struct {
 	installReturnsOnCall map[int]struct {
 		result1 error
 	}
-	ListStub        func(client.Client, catalog.CatalogClient, string) ([]catalog.ProfileData, error)
+	ListStub        func(context.Context, client.Client, catalog.CatalogClient, string) ([]catalog.ProfileData, error)
 	listMutex       sync.RWMutex
 	listArgsForCall []struct {
-		arg1 client.Client
-		arg2 catalog.CatalogClient
-		arg3 string
+		arg1 context.Context
+		arg2 client.Client
+		arg3 catalog.CatalogClient
+		arg4 string
 	}
 	listReturns struct {
 		result1 []catalog.ProfileData
@@ -36,11 +39,29 @@ type FakeCatalogManager struct {
 		result1 []catalog.ProfileData
 		result2 error
 	}
-	SearchStub        func(catalog.CatalogClient, string) ([]v1alpha1.ProfileCatalogEntry, error)
+	ResolveVersionConstraintStub        func(context.Context, catalog.CatalogClient, string, string, string) (string, error)
+	resolveVersionConstraintMutex       sync.RWMutex
+	resolveVersionConstraintArgsForCall []struct {
+		arg1 context.Context
+		arg2 catalog.CatalogClient
+		arg3 string
+		arg4 string
+		arg5 string
+	}
+	resolveVersionConstraintReturns struct {
+		result1 string
+		result2 error
+	}
+	resolveVersionConstraintReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	SearchStub        func(context.Context, catalog.CatalogClient, string) ([]v1alpha1.ProfileCatalogEntry, error)
 	searchMutex       sync.RWMutex
 	searchArgsForCall []struct {
-		arg1 catalog.CatalogClient
-		arg2 string
+		arg1 context.Context
+		arg2 catalog.CatalogClient
+		arg3 string
 	}
 	searchReturns struct {
 		result1 []v1alpha1.ProfileCatalogEntry
@@ -50,13 +71,14 @@ type FakeCatalogManager struct {
 		result1 []v1alpha1.ProfileCatalogEntry
 		result2 error
 	}
-	ShowStub        func(catalog.CatalogClient, string, string, string) (v1alpha1.ProfileCatalogEntry, error)
+	ShowStub        func(context.Context, catalog.CatalogClient, string, string, string) (v1alpha1.ProfileCatalogEntry, error)
 	showMutex       sync.RWMutex
 	showArgsForCall []struct {
-		arg1 catalog.CatalogClient
-		arg2 string
+		arg1 context.Context
+		arg2 catalog.CatalogClient
 		arg3 string
 		arg4 string
+		arg5 string
 	}
 	showReturns struct {
 		result1 v1alpha1.ProfileCatalogEntry
@@ -66,22 +88,40 @@ type FakeCatalogManager struct {
 		result1 v1alpha1.ProfileCatalogEntry
 		result2 error
 	}
+	VersionsStub        func(context.Context, client.Client, catalog.CatalogClient, string, string) ([]catalog.ProfileVersion, error)
+	versionsMutex       sync.RWMutex
+	versionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 client.Client
+		arg3 catalog.CatalogClient
+		arg4 string
+		arg5 string
+	}
+	versionsReturns struct {
+		result1 []catalog.ProfileVersion
+		result2 error
+	}
+	versionsReturnsOnCall map[int]struct {
+		result1 []catalog.ProfileVersion
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCatalogManager) Install(arg1 catalog.InstallConfig) error {
+func (fake *FakeCatalogManager) Install(arg1 context.Context, arg2 catalog.InstallConfig) error {
 	fake.installMutex.Lock()
 	ret, specificReturn := fake.installReturnsOnCall[len(fake.installArgsForCall)]
 	fake.installArgsForCall = append(fake.installArgsForCall, struct {
-		arg1 catalog.InstallConfig
-	}{arg1})
+		arg1 context.Context
+		arg2 catalog.InstallConfig
+	}{arg1, arg2})
 	stub := fake.InstallStub
 	fakeReturns := fake.installReturns
-	fake.recordInvocation("Install", []interface{}{arg1})
+	fake.recordInvocation("Install", []interface{}{arg1, arg2})
 	fake.installMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -95,17 +135,17 @@ func (fake *FakeCatalogManager) InstallCallCount() int {
 	return len(fake.installArgsForCall)
 }
 
-func (fake *FakeCatalogManager) InstallCalls(stub func(catalog.InstallConfig) error) {
+func (fake *FakeCatalogManager) InstallCalls(stub func(context.Context, catalog.InstallConfig) error) {
 	fake.installMutex.Lock()
 	defer fake.installMutex.Unlock()
 	fake.InstallStub = stub
 }
 
-func (fake *FakeCatalogManager) InstallArgsForCall(i int) catalog.InstallConfig {
+func (fake *FakeCatalogManager) InstallArgsForCall(i int) (context.Context, catalog.InstallConfig) {
 	fake.installMutex.RLock()
 	defer fake.installMutex.RUnlock()
 	argsForCall := fake.installArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCatalogManager) InstallReturns(result1 error) {
@@ -131,20 +171,21 @@ func (fake *FakeCatalogManager) InstallReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeCatalogManager) List(arg1 client.Client, arg2 catalog.CatalogClient, arg3 string) ([]catalog.ProfileData, error) {
+func (fake *FakeCatalogManager) List(arg1 context.Context, arg2 client.Client, arg3 catalog.CatalogClient, arg4 string) ([]catalog.ProfileData, error) {
 	fake.listMutex.Lock()
 	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
 	fake.listArgsForCall = append(fake.listArgsForCall, struct {
-		arg1 client.Client
-		arg2 catalog.CatalogClient
-		arg3 string
-	}{arg1, arg2, arg3})
+		arg1 context.Context
+		arg2 client.Client
+		arg3 catalog.CatalogClient
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.ListStub
 	fakeReturns := fake.listReturns
-	fake.recordInvocation("List", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("List", []interface{}{arg1, arg2, arg3, arg4})
 	fake.listMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -158,17 +199,17 @@ func (fake *FakeCatalogManager) ListCallCount() int {
 	return len(fake.listArgsForCall)
 }
 
-func (fake *FakeCatalogManager) ListCalls(stub func(client.Client, catalog.CatalogClient, string) ([]catalog.ProfileData, error)) {
+func (fake *FakeCatalogManager) ListCalls(stub func(context.Context, client.Client, catalog.CatalogClient, string) ([]catalog.ProfileData, error)) {
 	fake.listMutex.Lock()
 	defer fake.listMutex.Unlock()
 	fake.ListStub = stub
 }
 
-func (fake *FakeCatalogManager) ListArgsForCall(i int) (client.Client, catalog.CatalogClient, string) {
+func (fake *FakeCatalogManager) ListArgsForCall(i int) (context.Context, client.Client, catalog.CatalogClient, string) {
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
 	argsForCall := fake.listArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeCatalogManager) ListReturns(result1 []catalog.ProfileData, result2 error) {
@@ -197,19 +238,88 @@ func (fake *FakeCatalogManager) ListReturnsOnCall(i int, result1 []catalog.Profi
 	}{result1, result2}
 }
 
-func (fake *FakeCatalogManager) Search(arg1 catalog.CatalogClient, arg2 string) ([]v1alpha1.ProfileCatalogEntry, error) {
+func (fake *FakeCatalogManager) ResolveVersionConstraint(arg1 context.Context, arg2 catalog.CatalogClient, arg3 string, arg4 string, arg5 string) (string, error) {
+	fake.resolveVersionConstraintMutex.Lock()
+	ret, specificReturn := fake.resolveVersionConstraintReturnsOnCall[len(fake.resolveVersionConstraintArgsForCall)]
+	fake.resolveVersionConstraintArgsForCall = append(fake.resolveVersionConstraintArgsForCall, struct {
+		arg1 context.Context
+		arg2 catalog.CatalogClient
+		arg3 string
+		arg4 string
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.ResolveVersionConstraintStub
+	fakeReturns := fake.resolveVersionConstraintReturns
+	fake.recordInvocation("ResolveVersionConstraint", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.resolveVersionConstraintMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCatalogManager) ResolveVersionConstraintCallCount() int {
+	fake.resolveVersionConstraintMutex.RLock()
+	defer fake.resolveVersionConstraintMutex.RUnlock()
+	return len(fake.resolveVersionConstraintArgsForCall)
+}
+
+func (fake *FakeCatalogManager) ResolveVersionConstraintCalls(stub func(context.Context, catalog.CatalogClient, string, string, string) (string, error)) {
+	fake.resolveVersionConstraintMutex.Lock()
+	defer fake.resolveVersionConstraintMutex.Unlock()
+	fake.ResolveVersionConstraintStub = stub
+}
+
+func (fake *FakeCatalogManager) ResolveVersionConstraintArgsForCall(i int) (context.Context, catalog.CatalogClient, string, string, string) {
+	fake.resolveVersionConstraintMutex.RLock()
+	defer fake.resolveVersionConstraintMutex.RUnlock()
+	argsForCall := fake.resolveVersionConstraintArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeCatalogManager) ResolveVersionConstraintReturns(result1 string, result2 error) {
+	fake.resolveVersionConstraintMutex.Lock()
+	defer fake.resolveVersionConstraintMutex.Unlock()
+	fake.ResolveVersionConstraintStub = nil
+	fake.resolveVersionConstraintReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalogManager) ResolveVersionConstraintReturnsOnCall(i int, result1 string, result2 error) {
+	fake.resolveVersionConstraintMutex.Lock()
+	defer fake.resolveVersionConstraintMutex.Unlock()
+	fake.ResolveVersionConstraintStub = nil
+	if fake.resolveVersionConstraintReturnsOnCall == nil {
+		fake.resolveVersionConstraintReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.resolveVersionConstraintReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalogManager) Search(arg1 context.Context, arg2 catalog.CatalogClient, arg3 string) ([]v1alpha1.ProfileCatalogEntry, error) {
 	fake.searchMutex.Lock()
 	ret, specificReturn := fake.searchReturnsOnCall[len(fake.searchArgsForCall)]
 	fake.searchArgsForCall = append(fake.searchArgsForCall, struct {
-		arg1 catalog.CatalogClient
-		arg2 string
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 catalog.CatalogClient
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.SearchStub
 	fakeReturns := fake.searchReturns
-	fake.recordInvocation("Search", []interface{}{arg1, arg2})
+	fake.recordInvocation("Search", []interface{}{arg1, arg2, arg3})
 	fake.searchMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -223,17 +333,17 @@ func (fake *FakeCatalogManager) SearchCallCount() int {
 	return len(fake.searchArgsForCall)
 }
 
-func (fake *FakeCatalogManager) SearchCalls(stub func(catalog.CatalogClient, string) ([]v1alpha1.ProfileCatalogEntry, error)) {
+func (fake *FakeCatalogManager) SearchCalls(stub func(context.Context, catalog.CatalogClient, string) ([]v1alpha1.ProfileCatalogEntry, error)) {
 	fake.searchMutex.Lock()
 	defer fake.searchMutex.Unlock()
 	fake.SearchStub = stub
 }
 
-func (fake *FakeCatalogManager) SearchArgsForCall(i int) (catalog.CatalogClient, string) {
+func (fake *FakeCatalogManager) SearchArgsForCall(i int) (context.Context, catalog.CatalogClient, string) {
 	fake.searchMutex.RLock()
 	defer fake.searchMutex.RUnlock()
 	argsForCall := fake.searchArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeCatalogManager) SearchReturns(result1 []v1alpha1.ProfileCatalogEntry, result2 error) {
@@ -262,21 +372,22 @@ func (fake *FakeCatalogManager) SearchReturnsOnCall(i int, result1 []v1alpha1.Pr
 	}{result1, result2}
 }
 
-func (fake *FakeCatalogManager) Show(arg1 catalog.CatalogClient, arg2 string, arg3 string, arg4 string) (v1alpha1.ProfileCatalogEntry, error) {
+func (fake *FakeCatalogManager) Show(arg1 context.Context, arg2 catalog.CatalogClient, arg3 string, arg4 string, arg5 string) (v1alpha1.ProfileCatalogEntry, error) {
 	fake.showMutex.Lock()
 	ret, specificReturn := fake.showReturnsOnCall[len(fake.showArgsForCall)]
 	fake.showArgsForCall = append(fake.showArgsForCall, struct {
-		arg1 catalog.CatalogClient
-		arg2 string
+		arg1 context.Context
+		arg2 catalog.CatalogClient
 		arg3 string
 		arg4 string
-	}{arg1, arg2, arg3, arg4})
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.ShowStub
 	fakeReturns := fake.showReturns
-	fake.recordInvocation("Show", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Show", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.showMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -290,17 +401,17 @@ func (fake *FakeCatalogManager) ShowCallCount() int {
 	return len(fake.showArgsForCall)
 }
 
-func (fake *FakeCatalogManager) ShowCalls(stub func(catalog.CatalogClient, string, string, string) (v1alpha1.ProfileCatalogEntry, error)) {
+func (fake *FakeCatalogManager) ShowCalls(stub func(context.Context, catalog.CatalogClient, string, string, string) (v1alpha1.ProfileCatalogEntry, error)) {
 	fake.showMutex.Lock()
 	defer fake.showMutex.Unlock()
 	fake.ShowStub = stub
 }
 
-func (fake *FakeCatalogManager) ShowArgsForCall(i int) (catalog.CatalogClient, string, string, string) {
+func (fake *FakeCatalogManager) ShowArgsForCall(i int) (context.Context, catalog.CatalogClient, string, string, string) {
 	fake.showMutex.RLock()
 	defer fake.showMutex.RUnlock()
 	argsForCall := fake.showArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeCatalogManager) ShowReturns(result1 v1alpha1.ProfileCatalogEntry, result2 error) {
@@ -329,6 +440,74 @@ func (fake *FakeCatalogManager) ShowReturnsOnCall(i int, result1 v1alpha1.Profil
 	}{result1, result2}
 }
 
+func (fake *FakeCatalogManager) Versions(arg1 context.Context, arg2 client.Client, arg3 catalog.CatalogClient, arg4 string, arg5 string) ([]catalog.ProfileVersion, error) {
+	fake.versionsMutex.Lock()
+	ret, specificReturn := fake.versionsReturnsOnCall[len(fake.versionsArgsForCall)]
+	fake.versionsArgsForCall = append(fake.versionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 client.Client
+		arg3 catalog.CatalogClient
+		arg4 string
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.VersionsStub
+	fakeReturns := fake.versionsReturns
+	fake.recordInvocation("Versions", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.versionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCatalogManager) VersionsCallCount() int {
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
+	return len(fake.versionsArgsForCall)
+}
+
+func (fake *FakeCatalogManager) VersionsCalls(stub func(context.Context, client.Client, catalog.CatalogClient, string, string) ([]catalog.ProfileVersion, error)) {
+	fake.versionsMutex.Lock()
+	defer fake.versionsMutex.Unlock()
+	fake.VersionsStub = stub
+}
+
+func (fake *FakeCatalogManager) VersionsArgsForCall(i int) (context.Context, client.Client, catalog.CatalogClient, string, string) {
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
+	argsForCall := fake.versionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeCatalogManager) VersionsReturns(result1 []catalog.ProfileVersion, result2 error) {
+	fake.versionsMutex.Lock()
+	defer fake.versionsMutex.Unlock()
+	fake.VersionsStub = nil
+	fake.versionsReturns = struct {
+		result1 []catalog.ProfileVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalogManager) VersionsReturnsOnCall(i int, result1 []catalog.ProfileVersion, result2 error) {
+	fake.versionsMutex.Lock()
+	defer fake.versionsMutex.Unlock()
+	fake.VersionsStub = nil
+	if fake.versionsReturnsOnCall == nil {
+		fake.versionsReturnsOnCall = make(map[int]struct {
+			result1 []catalog.ProfileVersion
+			result2 error
+		})
+	}
+	fake.versionsReturnsOnCall[i] = struct {
+		result1 []catalog.ProfileVersion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCatalogManager) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -336,10 +515,14 @@ func (fake *FakeCatalogManager) Invocations() map[string][][]interface{} {
 	defer fake.installMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.resolveVersionConstraintMutex.RLock()
+	defer fake.resolveVersionConstraintMutex.RUnlock()
 	fake.searchMutex.RLock()
 	defer fake.searchMutex.RUnlock()
 	fake.showMutex.RLock()
 	defer fake.showMutex.RUnlock()
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,205 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	"github.com/weaveworks/profiles/pkg/protos"
+
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+// Endpoint is a named catalog client.
+type Endpoint struct {
+	Name   string
+	Client CatalogClient
+}
+
+// FederatedClient makes requests to several catalog endpoints concurrently and merges their responses.
+// Endpoints are listed in order of precedence: when more than one endpoint returns the same
+// catalog/profile/version the entry of the first endpoint is used.
+type FederatedClient struct {
+	Endpoints []Endpoint
+
+	originsMutex sync.RWMutex
+	origins      map[string]string
+}
+
+var _ CatalogClient = &FederatedClient{}
+
+type endpointResponse struct {
+	data       []byte
+	statusCode int
+	err        error
+}
+
+// NewFederatedClient creates a FederatedClient for the given endpoints.
+func NewFederatedClient(endpoints []Endpoint) *FederatedClient {
+	return &FederatedClient{
+		Endpoints: endpoints,
+		origins:   make(map[string]string),
+	}
+}
+
+// Origin returns the name of the endpoint a catalog entry was served from.
+func (f *FederatedClient) Origin(entry profilesv1.ProfileCatalogEntry) string {
+	f.originsMutex.RLock()
+	defer f.originsMutex.RUnlock()
+	return f.origins[entryKey(entry)]
+}
+
+// DoRequest sends the request to all endpoints and merges the results.
+func (f *FederatedClient) DoRequest(ctx context.Context, path string, query map[string]string) ([]byte, int, error) {
+	responses := make([]endpointResponse, len(f.Endpoints))
+	var wg sync.WaitGroup
+	for i, e := range f.Endpoints {
+		wg.Add(1)
+		go func(i int, e Endpoint) {
+			defer wg.Done()
+			data, code, err := e.Client.DoRequest(ctx, path, query)
+			responses[i] = endpointResponse{data: data, statusCode: code, err: err}
+		}(i, e)
+	}
+	wg.Wait()
+
+	if isListPath(path) {
+		return f.mergeLists(path, responses)
+	}
+	return f.firstItem(responses)
+}
+
+// mergeLists combines the items of all successful responses.
+func (f *FederatedClient) mergeLists(path string, responses []endpointResponse) ([]byte, int, error) {
+	var (
+		items []profilesv1.ProfileCatalogEntry
+		found bool
+		seen  = make(map[string]struct{})
+	)
+	for i, r := range responses {
+		if r.statusCode != http.StatusOK || r.err != nil {
+			continue
+		}
+		var list protos.GRPCProfileCatalogEntryList
+		if err := json.Unmarshal(r.data, &list); err != nil {
+			log.Warningf("ignoring invalid response from catalog endpoint %q: %v", f.Endpoints[i].Name, err)
+			continue
+		}
+		found = true
+		for _, item := range list.Items {
+			key := entryKey(item)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			f.recordOrigin(key, f.Endpoints[i].Name)
+			items = append(items, item)
+		}
+	}
+	if !found {
+		return f.failure(responses)
+	}
+	f.warnFailures(responses)
+
+	if strings.HasSuffix(strings.TrimSuffix(path, "/"), "/available_updates") {
+		sortByVersionDescending(items)
+	}
+	data, err := json.Marshal(protos.GRPCProfileCatalogEntryList{Items: items})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal merged response: %w", err)
+	}
+	return data, http.StatusOK, nil
+}
+
+// firstItem returns the response of the endpoint with the highest precedence which found the item.
+func (f *FederatedClient) firstItem(responses []endpointResponse) ([]byte, int, error) {
+	for i, r := range responses {
+		if r.statusCode != http.StatusOK || r.err != nil {
+			continue
+		}
+		var item protos.GRPCProfileCatalogEntry
+		if err := json.Unmarshal(r.data, &item); err == nil {
+			f.recordOrigin(entryKey(item.Item), f.Endpoints[i].Name)
+		}
+		f.warnFailures(responses)
+		return r.data, r.statusCode, nil
+	}
+	return f.failure(responses)
+}
+
+// failure returns the most relevant unsuccessful response. Not found is only returned
+// when all endpoints which could be reached agree on it.
+func (f *FederatedClient) failure(responses []endpointResponse) ([]byte, int, error) {
+	if len(responses) > 0 && allFailed(responses) {
+		return nil, 0, fmt.Errorf("catalog endpoint %q: %w", f.Endpoints[0].Name, responses[0].err)
+	}
+	f.warnFailures(responses)
+	for _, r := range responses {
+		if r.err == nil && r.statusCode != http.StatusNotFound {
+			return nil, r.statusCode, nil
+		}
+	}
+	return nil, http.StatusNotFound, nil
+}
+
+func (f *FederatedClient) warnFailures(responses []endpointResponse) {
+	for i, r := range responses {
+		if r.err != nil {
+			log.Warningf("catalog endpoint %q failed: %v", f.Endpoints[i].Name, r.err)
+		} else if r.statusCode != http.StatusOK && r.statusCode != http.StatusNotFound {
+			log.Warningf("catalog endpoint %q returned status code %d", f.Endpoints[i].Name, r.statusCode)
+		}
+	}
+}
+
+func (f *FederatedClient) recordOrigin(key, endpoint string) {
+	f.originsMutex.Lock()
+	defer f.originsMutex.Unlock()
+	if f.origins == nil {
+		f.origins = make(map[string]string)
+	}
+	f.origins[key] = endpoint
+}
+
+func allFailed(responses []endpointResponse) bool {
+	for _, r := range responses {
+		if r.err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func isListPath(p string) bool {
+	u, err := url.Parse(p)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return len(parts) == 1 || parts[len(parts)-1] == "available_updates"
+}
+
+// entryKey identifies a catalog entry by its catalog, profile and version.
+func entryKey(e profilesv1.ProfileCatalogEntry) string {
+	return fmt.Sprintf("%s/%s/%s", e.CatalogSource, e.Name, profilesv1.GetVersionFromTag(e.Tag))
+}
+
+// sortByVersionDescending orders entries from the newest to the oldest version. Entries
+// without a valid semver version keep their relative order at the end.
+func sortByVersionDescending(entries []profilesv1.ProfileCatalogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		vi, erri := semver.NewVersion(profilesv1.GetVersionFromTag(entries[i].Tag))
+		vj, errj := semver.NewVersion(profilesv1.GetVersionFromTag(entries[j].Tag))
+		if erri != nil || errj != nil {
+			return erri == nil && errj != nil
+		}
+		return vj.LessThan(vi)
+	})
+}
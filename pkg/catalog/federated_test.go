@@ -0,0 +1,119 @@
+package catalog_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+)
+
+var _ = Describe("FederatedClient", func() {
+	var (
+		central        *fakes.FakeCatalogClient
+		cluster        *fakes.FakeCatalogClient
+		manager        catalog.Manager
+		federated      *catalog.FederatedClient
+		centralEntries = []byte(`{"items":[
+  {"name": "nginx", "catalogSource": "cat", "tag": "v0.1.0", "description": "central"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "v0.2.0", "description": "central"}
+]}`)
+		clusterEntries = []byte(`{"items":[
+  {"name": "nginx", "catalogSource": "cat", "tag": "v0.2.0", "description": "cluster"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "v0.3.0", "description": "cluster"}
+]}`)
+	)
+
+	BeforeEach(func() {
+		central = new(fakes.FakeCatalogClient)
+		cluster = new(fakes.FakeCatalogClient)
+		federated = catalog.NewFederatedClient([]catalog.Endpoint{
+			{Name: "central", Client: central},
+			{Name: "cluster", Client: cluster},
+		})
+	})
+
+	Describe("Search", func() {
+		It("merges and deduplicates the results of all endpoints", func() {
+			central.DoRequestReturns(centralEntries, 200, nil)
+			cluster.DoRequestReturns(clusterEntries, 200, nil)
+
+			profiles, err := manager.Search(context.Background(), federated, "nginx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(central.DoRequestCallCount()).To(Equal(1))
+			Expect(cluster.DoRequestCallCount()).To(Equal(1))
+			Expect(profiles).To(HaveLen(3))
+			Expect(profiles[1].Tag).To(Equal("v0.2.0"))
+			Expect(profiles[1].Description).To(Equal("central"))
+			Expect(federated.Origin(profiles[0])).To(Equal("central"))
+			Expect(federated.Origin(profiles[1])).To(Equal("central"))
+			Expect(federated.Origin(profiles[2])).To(Equal("cluster"))
+		})
+
+		It("ignores endpoints which fail while others succeed", func() {
+			central.DoRequestReturns(nil, 0, errors.New("connection refused"))
+			cluster.DoRequestReturns(clusterEntries, 200, nil)
+
+			profiles, err := manager.Search(context.Background(), federated, "nginx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(profiles).To(HaveLen(2))
+		})
+
+		It("returns an error when all endpoints fail", func() {
+			central.DoRequestReturns(nil, 0, errors.New("connection refused"))
+			cluster.DoRequestReturns(nil, 0, errors.New("timeout"))
+
+			_, err := manager.Search(context.Background(), federated, "nginx")
+			Expect(err).To(MatchError(ContainSubstring(`catalog endpoint "central": connection refused`)))
+		})
+	})
+
+	Describe("Show", func() {
+		It("returns the entry of the endpoint with the highest precedence", func() {
+			central.DoRequestReturns([]byte(`{"item": {"name": "nginx", "catalogSource": "cat", "tag": "v0.2.0", "description": "central"}}`), 200, nil)
+			cluster.DoRequestReturns([]byte(`{"item": {"name": "nginx", "catalogSource": "cat", "tag": "v0.2.0", "description": "cluster"}}`), 200, nil)
+
+			profile, err := manager.Show(context.Background(), federated, "cat", "nginx", "v0.2.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(profile.Description).To(Equal("central"))
+			Expect(federated.Origin(profile)).To(Equal("central"))
+		})
+
+		It("falls back to the other endpoints", func() {
+			central.DoRequestReturns(nil, 404, nil)
+			cluster.DoRequestReturns([]byte(`{"item": {"name": "nginx", "catalogSource": "cat", "tag": "v0.3.0", "description": "cluster"}}`), 200, nil)
+
+			profile, err := manager.Show(context.Background(), federated, "cat", "nginx", "v0.3.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(profile.Description).To(Equal("cluster"))
+		})
+
+		It("returns not found if no endpoint has the profile", func() {
+			central.DoRequestReturns(nil, 404, nil)
+			cluster.DoRequestReturns(nil, 404, nil)
+
+			_, err := manager.Show(context.Background(), federated, "cat", "nginx", "v0.4.0")
+			Expect(err).To(MatchError(ContainSubstring("unable to find profile")))
+		})
+	})
+
+	Describe("GetAvailableUpdates", func() {
+		It("merges the updates ordered from the newest version", func() {
+			central.DoRequestReturns(centralEntries, 200, nil)
+			cluster.DoRequestReturns(clusterEntries, 200, nil)
+
+			updates, err := catalog.GetAvailableUpdates(context.Background(), federated, "cat", "nginx", "v0.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			var versions []string
+			for _, u := range updates {
+				versions = append(versions, profilesv1.GetVersionFromTag(u.Tag))
+			}
+			Expect(versions).To(Equal([]string{"v0.3.0", "v0.2.0", "v0.1.0"}))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -9,12 +10,15 @@ import (
 
 	"github.com/weaveworks/pctl/pkg/git"
 	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
 )
 
 // Clients contains a set of clients which are used by install.
 type Clients struct {
 	CatalogClient CatalogClient
 	Installer     install.ProfileInstaller
+	// PrerequisiteChecker verifies the prerequisites of catalog profiles. They aren't verified if it is nil.
+	PrerequisiteChecker PrerequisiteChecker
 }
 
 // Profile contains configuration for profiles ie. catalogName, profilesName, etc.
@@ -23,6 +27,8 @@ type Profile struct {
 	ProfileConfig
 }
 
+// ProfileConfig contains the profile details. VersionConstraint is the semver range
+// Version was resolved from, if any.
 type ProfileConfig struct {
 	ProfileName           string
 	CatalogName           string
@@ -30,6 +36,7 @@ type ProfileConfig struct {
 	InstallationNamespace string
 	InstallationName      string
 	Version               string
+	VersionConstraint     string
 	ProfileBranch         string
 	URL                   string
 	Path                  string
@@ -45,23 +52,43 @@ type GitRepoConfig struct {
 type InstallConfig struct {
 	Clients
 	Profile
+	// IgnorePrerequisites reports unmet prerequisites as warnings instead of failing the installation.
+	IgnorePrerequisites bool
 }
 
 // Install using the catalog at catalogURL and a profile matching the provided profileName generates a profile installation
 // and its artifacts
-func (m *Manager) Install(cfg InstallConfig) error {
-	pSpec, err := m.createInstallationSpec(cfg)
+func (m *Manager) Install(ctx context.Context, cfg InstallConfig) error {
+	if cfg.URL == "" && IsVersionConstraint(cfg.Version) {
+		version, err := m.ResolveVersionConstraint(ctx, cfg.CatalogClient, cfg.CatalogName, cfg.ProfileName, cfg.Version)
+		if err != nil {
+			return fmt.Errorf("failed to resolve version constraint: %w", err)
+		}
+		cfg.VersionConstraint = cfg.Version
+		cfg.Version = version
+	}
+	pSpec, prerequisites, err := m.createInstallationSpec(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	if err := checkPrerequisites(ctx, cfg, prerequisites); err != nil {
+		return err
+	}
+	var annotations map[string]string
+	if cfg.VersionConstraint != "" {
+		annotations = map[string]string{
+			VersionConstraintAnnotation: cfg.VersionConstraint,
+		}
+	}
 	installation := profilesv1.ProfileInstallation{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ProfileInstallation",
 			APIVersion: "weave.works/v1alpha1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cfg.InstallationName,
-			Namespace: cfg.InstallationNamespace,
+			Name:        cfg.InstallationName,
+			Namespace:   cfg.InstallationNamespace,
+			Annotations: annotations,
 		},
 		Spec: pSpec,
 	}
@@ -71,8 +98,30 @@ func (m *Manager) Install(cfg InstallConfig) error {
 	return nil
 }
 
-// createInstallationSpec creates a spec based on configured properties.
-func (m *Manager) createInstallationSpec(cfg InstallConfig) (profilesv1.ProfileInstallationSpec, error) {
+// checkPrerequisites verifies the prerequisites of the profile before any files are written.
+func checkPrerequisites(ctx context.Context, cfg InstallConfig, prerequisites []string) error {
+	if len(prerequisites) == 0 {
+		return nil
+	}
+	if cfg.PrerequisiteChecker == nil {
+		log.Warningf("no cluster to verify the prerequisites of profile %q against: %s", cfg.ProfileName, strings.Join(prerequisites, ", "))
+		return nil
+	}
+	log.Actionf("checking prerequisites of profile %q", cfg.ProfileName)
+	if err := cfg.PrerequisiteChecker.Check(ctx, prerequisites); err != nil {
+		if cfg.IgnorePrerequisites {
+			log.Warningf("ignoring prerequisites of profile %q: %s", cfg.ProfileName, err)
+			return nil
+		}
+		return fmt.Errorf("failed to verify prerequisites of profile %q: %w\nTo ignore this error, please see the --ignore-prerequisites flag", cfg.ProfileName, err)
+	}
+	log.Successf("prerequisites of profile %q are met", cfg.ProfileName)
+	return nil
+}
+
+// createInstallationSpec creates a spec based on configured properties. It also returns
+// the prerequisites of catalog profiles.
+func (m *Manager) createInstallationSpec(ctx context.Context, cfg InstallConfig) (profilesv1.ProfileInstallationSpec, []string, error) {
 	var gitRepo *profilesv1.GitRepository
 	if cfg.GitRepoConfig.Name != "" {
 		gitRepo = &profilesv1.GitRepository{
@@ -89,11 +138,11 @@ func (m *Manager) createInstallationSpec(cfg InstallConfig) (profilesv1.ProfileI
 			},
 			ConfigMap:     cfg.ConfigMap,
 			GitRepository: gitRepo,
-		}, nil
+		}, nil, nil
 	}
-	p, err := m.Show(cfg.CatalogClient, cfg.CatalogName, cfg.ProfileName, cfg.Version)
+	p, err := m.Show(ctx, cfg.CatalogClient, cfg.CatalogName, cfg.ProfileName, cfg.Version)
 	if err != nil {
-		return profilesv1.ProfileInstallationSpec{}, fmt.Errorf("failed to get profile %q in catalog %q: %w", cfg.ProfileName, cfg.CatalogName, err)
+		return profilesv1.ProfileInstallationSpec{}, nil, fmt.Errorf("failed to get profile %q in catalog %q: %w", cfg.ProfileName, cfg.CatalogName, err)
 	}
 
 	//tag could be <semver> or <name/semver>
@@ -116,11 +165,11 @@ func (m *Manager) createInstallationSpec(cfg InstallConfig) (profilesv1.ProfileI
 		},
 		ConfigMap:     cfg.ConfigMap,
 		GitRepository: gitRepo,
-	}, nil
+	}, p.Prerequisites, nil
 }
 
-// CreatePullRequest creates a pull request from the current changes.
-func CreatePullRequest(scm git.SCMClient, g git.Git, branch, directory string) error {
+// CreatePullRequest creates a pull request from the current changes in directories.
+func CreatePullRequest(scm git.SCMClient, g git.Git, branch string, directories ...string) error {
 	if err := g.IsRepository(); err != nil {
 		return fmt.Errorf("directory is not a git repository: %w", err)
 	}
@@ -129,8 +178,10 @@ func CreatePullRequest(scm git.SCMClient, g git.Git, branch, directory string) e
 		return fmt.Errorf("failed to create branch: %w", err)
 	}
 
-	if err := g.Add(directory); err != nil {
-		return fmt.Errorf("failed to add changes: %w", err)
+	for _, directory := range directories {
+		if err := g.Add(directory); err != nil {
+			return fmt.Errorf("failed to add changes: %w", err)
+		}
 	}
 
 	if err := g.Commit(); err != nil {
@@ -1,6 +1,7 @@
 package catalog_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -80,7 +81,7 @@ var _ = Describe("Install", func() {
 	Describe("install", func() {
 		When("installing from a catalog entry", func() {
 			It("generates the artifacts", func() {
-				err := manager.Install(cfg)
+				err := manager.Install(context.Background(), cfg)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeInstaller.InstallCallCount()).To(Equal(1))
 				arg := fakeInstaller.InstallArgsForCall(0)
@@ -115,6 +116,66 @@ var _ = Describe("Install", func() {
 			})
 		})
 
+		When("installing from a catalog entry with a version constraint", func() {
+			BeforeEach(func() {
+				fakeCatalogClient.DoRequestReturnsOnCall(0, []byte(`{"items":[
+  {"name": "nginx-1", "catalogSource": "nginx", "tag": "nginx-1/v0.0.1"},
+  {"name": "nginx-1", "catalogSource": "nginx", "tag": "nginx-1/v0.1.0"}
+]}`), 200, nil)
+				cfg.Version = "~0.0"
+			})
+
+			It("resolves the version and records the constraint", func() {
+				err := manager.Install(context.Background(), cfg)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(2))
+				_, path, _ := fakeCatalogClient.DoRequestArgsForCall(1)
+				Expect(path).To(Equal("/profiles/nginx/nginx-1/v0.0.1"))
+				arg := fakeInstaller.InstallArgsForCall(0)
+				Expect(arg.Annotations).To(Equal(map[string]string{
+					catalog.VersionConstraintAnnotation: "~0.0",
+				}))
+				Expect(arg.Spec.Catalog.Version).To(Equal("v0.0.1"))
+			})
+		})
+
+		When("a prerequisite checker is configured", func() {
+			var fakeChecker *fakes.FakePrerequisiteChecker
+
+			BeforeEach(func() {
+				fakeChecker = new(fakes.FakePrerequisiteChecker)
+				cfg.PrerequisiteChecker = fakeChecker
+			})
+
+			It("checks the prerequisites of the profile", func() {
+				err := manager.Install(context.Background(), cfg)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeChecker.CheckCallCount()).To(Equal(1))
+				_, prerequisites := fakeChecker.CheckArgsForCall(0)
+				Expect(prerequisites).To(ConsistOf("Kubernetes 1.18+"))
+				Expect(fakeInstaller.InstallCallCount()).To(Equal(1))
+			})
+
+			When("the prerequisites aren't met", func() {
+				BeforeEach(func() {
+					fakeChecker.CheckReturns(errors.New("prerequisites not met: Kubernetes 1.18+: cluster runs Kubernetes v1.17.0"))
+				})
+
+				It("errors before generating the artifacts", func() {
+					err := manager.Install(context.Background(), cfg)
+					Expect(err).To(MatchError("failed to verify prerequisites of profile \"nginx-1\": prerequisites not met: Kubernetes 1.18+: cluster runs Kubernetes v1.17.0\nTo ignore this error, please see the --ignore-prerequisites flag"))
+					Expect(fakeInstaller.InstallCallCount()).To(Equal(0))
+				})
+
+				It("generates the artifacts when prerequisites are ignored", func() {
+					cfg.IgnorePrerequisites = true
+					err := manager.Install(context.Background(), cfg)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeInstaller.InstallCallCount()).To(Equal(1))
+				})
+			})
+		})
+
 		When("installing from a url", func() {
 			BeforeEach(func() {
 				cfg = catalog.InstallConfig{
@@ -140,7 +201,7 @@ var _ = Describe("Install", func() {
 				}
 			})
 			It("generates the artifacts", func() {
-				err := manager.Install(cfg)
+				err := manager.Install(context.Background(), cfg)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeInstaller.InstallCallCount()).To(Equal(1))
 				arg := fakeInstaller.InstallArgsForCall(0)
@@ -172,7 +233,7 @@ var _ = Describe("Install", func() {
 		When("getting the artifacts fails", func() {
 			It("errors", func() {
 				fakeInstaller.InstallReturns(fmt.Errorf("foo"))
-				err := manager.Install(cfg)
+				err := manager.Install(context.Background(), cfg)
 				Expect(err).To(MatchError("failed to make artifacts: foo"))
 			})
 		})
@@ -197,7 +258,7 @@ var _ = Describe("Install", func() {
 						},
 					},
 				}
-				err := manager.Install(cfg)
+				err := manager.Install(context.Background(), cfg)
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
@@ -216,6 +277,17 @@ var _ = Describe("Install", func() {
 				Expect(fakeScm.CreatePullRequestCallCount()).To(Equal(1))
 			})
 		})
+		When("several directories are given", func() {
+			It("creates a single PR with the changes of all of them", func() {
+				err := catalog.CreatePullRequest(fakeScm, fakeGit, "branch", "foo/bar", "foo/baz")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeGit.AddCallCount()).To(Equal(2))
+				Expect(fakeGit.AddArgsForCall(0)).To(Equal("foo/bar"))
+				Expect(fakeGit.AddArgsForCall(1)).To(Equal("foo/baz"))
+				Expect(fakeGit.CommitCallCount()).To(Equal(1))
+				Expect(fakeScm.CreatePullRequestCallCount()).To(Equal(1))
+			})
+		})
 		When("create-pr is set to true but something goes wrong", func() {
 			It("handles create branch errors", func() {
 				fakeGit.CreateBranchReturns(errors.New("nope"))
@@ -1,8 +1,10 @@
 package catalog
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -10,53 +12,113 @@ import (
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 )
 
+// DefaultListConcurrency is the number of available update lookups List makes at the same time.
+const DefaultListConcurrency = 10
+
 // ProfileData data containing profile and available version update for format printing.
 type ProfileData struct {
 	Profile                 installation.Summary
 	AvailableVersionUpdates []string
+	// Err is set if the available updates of the profile couldn't be determined.
+	Err error `json:"-"`
+}
+
+// updateLookup identifies the available updates query of an installation.
+type updateLookup struct {
+	catalog, profile, version string
+}
+
+type updateResult struct {
+	versions []string
+	err      error
 }
 
 // List will fetch all installed profiles on the cluster and check if there are updated versions available.
-func (m *Manager) List(k8sClient runtimeclient.Client, catalogClient CatalogClient, name string) ([]ProfileData, error) {
-	profiles, err := installation.NewManager(k8sClient).List()
+// The updates are looked up concurrently, at most ListConcurrency at a time, and installations of the same
+// profile version share a single lookup. Failed lookups are reported in ProfileData.Err.
+func (m *Manager) List(ctx context.Context, k8sClient runtimeclient.Client, catalogClient CatalogClient, name string) ([]ProfileData, error) {
+	profiles, err := installation.NewManager(k8sClient).List(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if len(profiles) == 0 {
 		return nil, nil
 	}
-	profileData := make([]ProfileData, 0)
+
+	var (
+		matching []installation.Summary
+		lookups  []updateLookup
+		indexes  = make(map[updateLookup]int)
+	)
 	for _, p := range profiles {
-		var versions []string
+		// filter results if name is provided
+		if name != "" && !strings.Contains(p.Name, name) {
+			continue
+		}
+		matching = append(matching, p)
 		// skip for profiles which don't have a catalog entry. i.e.: profiles installed via branch, url, path.
-		if p.Catalog != "-" {
-			related, err := GetAvailableUpdates(catalogClient, p.Catalog, p.Profile, p.Version)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get available updates: %w", err)
-			}
-			for _, r := range related {
-				versions = append(versions, profilesv1.GetVersionFromTag(r.Tag))
-			}
+		if p.Catalog == "-" {
+			continue
 		}
-		if len(versions) == 0 {
-			versions = append(versions, "-")
+		l := updateLookup{catalog: p.Catalog, profile: p.Profile, version: p.Version}
+		if _, ok := indexes[l]; !ok {
+			indexes[l] = len(lookups)
+			lookups = append(lookups, l)
 		}
+	}
 
-		// filter results if name is provided
-		if name != "" {
-			if strings.Contains(p.Name, name) {
-				profileData = append(profileData, ProfileData{
-					Profile:                 p,
-					AvailableVersionUpdates: versions,
-				})
-			}
-		} else {
-			profileData = append(profileData, ProfileData{
-				Profile:                 p,
-				AvailableVersionUpdates: versions,
-			})
-		}
+	results := m.lookupUpdates(ctx, catalogClient, lookups)
 
+	profileData := make([]ProfileData, 0, len(matching))
+	for _, p := range matching {
+		data := ProfileData{Profile: p}
+		if p.Catalog != "-" {
+			r := results[indexes[updateLookup{catalog: p.Catalog, profile: p.Profile, version: p.Version}]]
+			data.AvailableVersionUpdates = r.versions
+			data.Err = r.err
+		}
+		if len(data.AvailableVersionUpdates) == 0 {
+			data.AvailableVersionUpdates = []string{"-"}
+		}
+		profileData = append(profileData, data)
 	}
 	return profileData, nil
 }
+
+// lookupUpdates queries the available updates with a bounded pool of workers. The results are in the order of lookups.
+func (m *Manager) lookupUpdates(ctx context.Context, catalogClient CatalogClient, lookups []updateLookup) []updateResult {
+	results := make([]updateResult, len(lookups))
+	workers := m.ListConcurrency
+	if workers <= 0 {
+		workers = DefaultListConcurrency
+	}
+	if workers > len(lookups) {
+		workers = len(lookups)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				l := lookups[i]
+				related, err := GetAvailableUpdates(ctx, catalogClient, l.catalog, l.profile, l.version)
+				if err != nil {
+					results[i].err = fmt.Errorf("failed to get available updates: %w", err)
+					continue
+				}
+				for _, r := range related {
+					results[i].versions = append(results[i].versions, profilesv1.GetVersionFromTag(r.Tag))
+				}
+			}
+		}()
+	}
+	for i := range lookups {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
@@ -2,6 +2,8 @@ package catalog_test
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -83,7 +85,7 @@ var _ = Describe("List", func() {
 ]}
 `)
 		fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
-		out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
+		out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
 		Expect(err).NotTo(HaveOccurred())
 		expected := []catalog.ProfileData{
 			{
@@ -119,7 +121,7 @@ var _ = Describe("List", func() {
 ]}
 `)
 		fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
-		out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, sub1)
+		out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, sub1)
 		Expect(err).NotTo(HaveOccurred())
 		expected := []catalog.ProfileData{
 			{
@@ -144,7 +146,7 @@ var _ = Describe("List", func() {
 			scheme := runtime.NewScheme()
 			Expect(profilesv1.AddToScheme(scheme)).To(Succeed())
 			fakeRuntimeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(out).To(BeEmpty())
 		})
@@ -152,7 +154,7 @@ var _ = Describe("List", func() {
 			scheme := runtime.NewScheme()
 			Expect(profilesv1.AddToScheme(scheme)).To(Succeed())
 			fakeRuntimeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "testProfileName")
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "testProfileName")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(out).To(BeEmpty())
 		})
@@ -162,7 +164,7 @@ var _ = Describe("List", func() {
 		It("returns the profile without any version updates", func() {
 			httpBody := []byte(`{"items":[]}`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
 			Expect(err).NotTo(HaveOccurred())
 			expected := []catalog.ProfileData{
 				{
@@ -184,7 +186,7 @@ var _ = Describe("List", func() {
 		It("returns the profile without any version updates with name", func() {
 			httpBody := []byte(`{"items":[]}`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, sub1)
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, sub1)
 			Expect(err).NotTo(HaveOccurred())
 			expected := []catalog.ProfileData{
 				{
@@ -206,19 +208,63 @@ var _ = Describe("List", func() {
 	})
 
 	When("get greater than fails to query the catalog", func() {
-		It("returns a sane error", func() {
+		It("returns a sane error for the installation", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 400, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("failed to get available updates: failed to fetch available updates for profile, status code 400"))
-			Expect(out).To(BeNil())
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Err).To(MatchError("failed to get available updates: failed to fetch available updates for profile, status code 400"))
+			Expect(out[0].AvailableVersionUpdates).To(Equal([]string{"-"}))
 		})
-		It("returns a sane error when name is provided", func() {
+		It("returns a sane error for the installation when name is provided", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 400, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, sub1)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("failed to get available updates: failed to fetch available updates for profile, status code 400"))
-			Expect(out).To(BeNil())
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, sub1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Err).To(MatchError("failed to get available updates: failed to fetch available updates for profile, status code 400"))
+		})
+	})
+
+	When("there are many installations", func() {
+		It("looks up each profile version once and keeps the order of the installations", func() {
+			for i := 0; i < 20; i++ {
+				p := &profilesv1.ProfileInstallation{
+					TypeMeta: profileTypeMeta,
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("many-%02d", i),
+						Namespace: namespace2,
+					},
+					Spec: profilesv1.ProfileInstallationSpec{
+						Catalog: &profilesv1.Catalog{
+							Profile: profile,
+							Catalog: cat,
+							Version: fmt.Sprintf("v0.1.%d", i%4),
+						},
+					},
+				}
+				Expect(fakeRuntimeClient.Create(context.TODO(), p)).To(Succeed())
+			}
+			fakeCatalogClient.DoRequestStub = func(_ context.Context, path string, _ map[string]string) ([]byte, int, error) {
+				if strings.Contains(path, "/v0.1.3/") {
+					return nil, 500, nil
+				}
+				return []byte(`{"items":[{"name":"weaveworks-nginx","tag":"weaveworks-nginx/v0.2.0"}]}`), 200, nil
+			}
+
+			m := catalog.Manager{ListConcurrency: 3}
+			out, err := m.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "many")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(HaveLen(20))
+			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(4))
+			for i, d := range out {
+				Expect(d.Profile.Name).To(Equal(fmt.Sprintf("many-%02d", i)))
+				if i%4 == 3 {
+					Expect(d.Err).To(HaveOccurred())
+				} else {
+					Expect(d.Err).NotTo(HaveOccurred())
+					Expect(d.AvailableVersionUpdates).To(Equal([]string{"v0.2.0"}))
+				}
+			}
 		})
 	})
 
@@ -226,13 +272,13 @@ var _ = Describe("List", func() {
 		It("returns a sane error", func() {
 			fakeRuntimeClient = fake.NewClientBuilder().Build()
 			fakeCatalogClient.DoRequestReturns(nil, 200, nil)
-			_, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
+			_, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
 			Expect(err).To(MatchError("failed to list profile installations: no kind is registered for the type v1alpha1.ProfileInstallationList in scheme \"pkg/runtime/scheme.go:100\""))
 		})
 		It("returns a sane error when name is provided", func() {
 			fakeRuntimeClient = fake.NewClientBuilder().Build()
 			fakeCatalogClient.DoRequestReturns(nil, 200, nil)
-			_, err := manager.List(fakeRuntimeClient, fakeCatalogClient, sub1)
+			_, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, sub1)
 			Expect(err).To(MatchError("failed to list profile installations: no kind is registered for the type v1alpha1.ProfileInstallationList in scheme \"pkg/runtime/scheme.go:100\""))
 		})
 	})
@@ -307,10 +353,12 @@ var _ = Describe("List", func() {
   }
 ]}
 `)
-			fakeCatalogClient.DoRequestReturnsOnCall(0, return1, 200, nil)
-			fakeCatalogClient.DoRequestReturnsOnCall(1, return2, 200, nil)
-			fakeCatalogClient.DoRequestReturnsOnCall(2, return3, 200, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, "")
+			responses := map[string][]byte{profile: return1, profile2: return2, profile3: return3}
+			fakeCatalogClient.DoRequestStub = func(_ context.Context, path string, _ map[string]string) ([]byte, int, error) {
+				// path is /profiles/<catalog>/<profile>/<version>/available_updates
+				return responses[strings.Split(path, "/")[3]], 200, nil
+			}
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, "")
 			Expect(err).NotTo(HaveOccurred())
 			expected := []catalog.ProfileData{
 				{
@@ -425,10 +473,12 @@ var _ = Describe("List", func() {
   }
 ]}
 `)
-			fakeCatalogClient.DoRequestReturnsOnCall(0, return1, 200, nil)
-			fakeCatalogClient.DoRequestReturnsOnCall(1, return2, 200, nil)
-			fakeCatalogClient.DoRequestReturnsOnCall(2, return3, 200, nil)
-			out, err := manager.List(fakeRuntimeClient, fakeCatalogClient, sub3)
+			responses := map[string][]byte{profile: return1, profile2: return2, profile3: return3}
+			fakeCatalogClient.DoRequestStub = func(_ context.Context, path string, _ map[string]string) ([]byte, int, error) {
+				// path is /profiles/<catalog>/<profile>/<version>/available_updates
+				return responses[strings.Split(path, "/")[3]], 200, nil
+			}
+			out, err := manager.List(context.Background(), fakeRuntimeClient, fakeCatalogClient, sub3)
 			Expect(err).NotTo(HaveOccurred())
 			expected := []catalog.ProfileData{
 				{
@@ -1,6 +1,8 @@
 package catalog
 
 import (
+	"context"
+
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -8,11 +10,16 @@ import (
 //go:generate counterfeiter -o fakes/fake_catalog_manager.go . CatalogManager
 // CatalogManager inteface for interacting with catalog API
 type CatalogManager interface {
-	Show(CatalogClient, string, string, string) (profilesv1.ProfileCatalogEntry, error)
-	Search(CatalogClient, string) ([]profilesv1.ProfileCatalogEntry, error)
-	Install(InstallConfig) error
-	List(runtimeclient.Client, CatalogClient, string) ([]ProfileData, error)
+	Show(context.Context, CatalogClient, string, string, string) (profilesv1.ProfileCatalogEntry, error)
+	Search(context.Context, CatalogClient, string) ([]profilesv1.ProfileCatalogEntry, error)
+	Install(context.Context, InstallConfig) error
+	List(context.Context, runtimeclient.Client, CatalogClient, string) ([]ProfileData, error)
+	Versions(context.Context, runtimeclient.Client, CatalogClient, string, string) ([]ProfileVersion, error)
+	ResolveVersionConstraint(context.Context, CatalogClient, string, string, string) (string, error)
 }
 
 // Manager is responsible for manager interactions with the catalog API
-type Manager struct{}
+type Manager struct {
+	// ListConcurrency limits the number of concurrent catalog requests made by List. Defaults to DefaultListConcurrency.
+	ListConcurrency int
+}
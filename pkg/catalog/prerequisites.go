@@ -0,0 +1,180 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+// PrerequisiteChecker verifies the prerequisites of a profile against the target cluster.
+//go:generate counterfeiter -o fakes/fake_prerequisite_checker.go . PrerequisiteChecker
+type PrerequisiteChecker interface {
+	// Check returns an error describing every prerequisite which isn't met.
+	Check(ctx context.Context, prerequisites []string) error
+}
+
+// PrerequisiteKind is the kind of requirement a prerequisite describes.
+type PrerequisiteKind string
+
+const (
+	// KubernetesVersionPrerequisite requires a minimum Kubernetes version, e.g. `Kubernetes 1.18+`.
+	KubernetesVersionPrerequisite PrerequisiteKind = "kubernetes"
+	// CRDPrerequisite requires a CustomResourceDefinition, e.g. `crd:helmreleases.helm.toolkit.fluxcd.io`.
+	CRDPrerequisite PrerequisiteKind = "crd"
+	// APIPrerequisite requires an API group and optionally a version of it, e.g. `api:monitoring.coreos.com/v1`.
+	APIPrerequisite PrerequisiteKind = "api"
+)
+
+var kubernetesVersionRegexp = regexp.MustCompile(`(?i)^kubernetes\s*(?:>=\s*)?(v?\d+\.\d+(?:\.\d+)?)\+?$`)
+
+// Prerequisite is a parsed profile prerequisite.
+type Prerequisite struct {
+	Kind PrerequisiteKind
+	// Name is the minimum version, the name of the CRD or the API group, depending on Kind.
+	Name string
+	// Version is the API version required by an APIPrerequisite, if any.
+	Version string
+}
+
+// ParsePrerequisite parses the free form prerequisite of a profile. It returns false if
+// the prerequisite isn't in a format which can be verified against a cluster.
+func ParsePrerequisite(prerequisite string) (Prerequisite, bool) {
+	prerequisite = strings.TrimSpace(prerequisite)
+	if m := kubernetesVersionRegexp.FindStringSubmatch(prerequisite); m != nil {
+		return Prerequisite{Kind: KubernetesVersionPrerequisite, Name: m[1]}, true
+	}
+	kind, value, ok := cut(prerequisite, ":")
+	if !ok {
+		return Prerequisite{}, false
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Prerequisite{}, false
+	}
+	switch PrerequisiteKind(strings.ToLower(strings.TrimSpace(kind))) {
+	case CRDPrerequisite:
+		return Prerequisite{Kind: CRDPrerequisite, Name: value}, true
+	case APIPrerequisite:
+		group, version, _ := cut(value, "/")
+		return Prerequisite{Kind: APIPrerequisite, Name: group, Version: version}, true
+	}
+	return Prerequisite{}, false
+}
+
+// ClusterPrerequisiteChecker checks prerequisites using the controller-runtime client for CRDs
+// and discovery for the Kubernetes version and served API groups.
+type ClusterPrerequisiteChecker struct {
+	// Client must have apiextensions/v1 registered in its scheme.
+	Client    runtimeclient.Client
+	Discovery discovery.DiscoveryInterface
+}
+
+var _ PrerequisiteChecker = &ClusterPrerequisiteChecker{}
+
+// Check verifies prerequisites against the cluster. Prerequisites which can't be parsed are skipped with a warning.
+func (c *ClusterPrerequisiteChecker) Check(ctx context.Context, prerequisites []string) error {
+	var unmet []string
+	for _, p := range prerequisites {
+		prerequisite, ok := ParsePrerequisite(p)
+		if !ok {
+			log.Warningf("unable to verify prerequisite %q, skipping", p)
+			continue
+		}
+		var (
+			reason string
+			err    error
+		)
+		switch prerequisite.Kind {
+		case KubernetesVersionPrerequisite:
+			reason, err = c.checkKubernetesVersion(prerequisite.Name)
+		case CRDPrerequisite:
+			reason, err = c.checkCRD(ctx, prerequisite.Name)
+		case APIPrerequisite:
+			reason, err = c.checkAPI(prerequisite.Name, prerequisite.Version)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to verify prerequisite %q: %w", p, err)
+		}
+		if reason != "" {
+			unmet = append(unmet, fmt.Sprintf("%s: %s", p, reason))
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("prerequisites not met: %s", strings.Join(unmet, "; "))
+	}
+	return nil
+}
+
+func (c *ClusterPrerequisiteChecker) checkKubernetesVersion(minimum string) (string, error) {
+	min, err := semver.NewVersion(minimum)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version %q: %w", minimum, err)
+	}
+	info, err := c.Discovery.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+	server, err := semver.NewVersion(info.GitVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse server version %q: %w", info.GitVersion, err)
+	}
+	// providers publish versions such as v1.20.7-gke.1800 which semver would order before v1.20.7
+	release, err := server.SetPrerelease("")
+	if err != nil {
+		return "", err
+	}
+	if release.LessThan(min) {
+		return fmt.Sprintf("cluster runs Kubernetes %s", info.GitVersion), nil
+	}
+	return "", nil
+}
+
+func (c *ClusterPrerequisiteChecker) checkCRD(ctx context.Context, name string) (string, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Client.Get(ctx, runtimeclient.ObjectKey{Name: name}, crd); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "CustomResourceDefinition not found", nil
+		}
+		return "", fmt.Errorf("failed to get CustomResourceDefinition %q: %w", name, err)
+	}
+	return "", nil
+}
+
+func (c *ClusterPrerequisiteChecker) checkAPI(group, version string) (string, error) {
+	groups, err := c.Discovery.ServerGroups()
+	if err != nil {
+		return "", fmt.Errorf("failed to list API groups: %w", err)
+	}
+	for _, g := range groups.Groups {
+		if g.Name != group {
+			continue
+		}
+		if version == "" {
+			return "", nil
+		}
+		for _, v := range g.Versions {
+			if v.Version == version {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("API version %s/%s not served", group, version), nil
+	}
+	return fmt.Sprintf("API group %s not served", group), nil
+}
+
+// cut slices s around the first instance of sep, like strings.Cut which isn't available in go 1.17.
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
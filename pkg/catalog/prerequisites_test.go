@@ -0,0 +1,93 @@
+package catalog_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+)
+
+var _ = Describe("Prerequisites", func() {
+	Context("ParsePrerequisite", func() {
+		It("parses the supported formats", func() {
+			for prerequisite, expected := range map[string]catalog.Prerequisite{
+				"Kubernetes 1.18+":                        {Kind: catalog.KubernetesVersionPrerequisite, Name: "1.18"},
+				"kubernetes 1.19":                         {Kind: catalog.KubernetesVersionPrerequisite, Name: "1.19"},
+				"Kubernetes >= v1.20.1":                   {Kind: catalog.KubernetesVersionPrerequisite, Name: "v1.20.1"},
+				"crd:helmreleases.helm.toolkit.fluxcd.io": {Kind: catalog.CRDPrerequisite, Name: "helmreleases.helm.toolkit.fluxcd.io"},
+				"api:monitoring.coreos.com/v1":            {Kind: catalog.APIPrerequisite, Name: "monitoring.coreos.com", Version: "v1"},
+				"API: networking.k8s.io":                  {Kind: catalog.APIPrerequisite, Name: "networking.k8s.io"},
+			} {
+				p, ok := catalog.ParsePrerequisite(prerequisite)
+				Expect(ok).To(BeTrue(), prerequisite)
+				Expect(p).To(Equal(expected), prerequisite)
+			}
+		})
+
+		It("doesn't parse free text", func() {
+			for _, prerequisite := range []string{"a running flux", "crd:", "helm 3+"} {
+				_, ok := catalog.ParsePrerequisite(prerequisite)
+				Expect(ok).To(BeFalse(), prerequisite)
+			}
+		})
+	})
+
+	Context("ClusterPrerequisiteChecker", func() {
+		var checker *catalog.ClusterPrerequisiteChecker
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+			crd := &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "helmreleases.helm.toolkit.fluxcd.io"},
+			}
+			discovery := &fakediscovery.FakeDiscovery{
+				Fake: &k8stesting.Fake{
+					Resources: []*metav1.APIResourceList{
+						{GroupVersion: "apps/v1"},
+						{GroupVersion: "monitoring.coreos.com/v1"},
+					},
+				},
+				FakedServerVersion: &version.Info{GitVersion: "v1.20.7-gke.1800"},
+			}
+			checker = &catalog.ClusterPrerequisiteChecker{
+				Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(crd).Build(),
+				Discovery: discovery,
+			}
+		})
+
+		It("succeeds when all prerequisites are met", func() {
+			err := checker.Check(context.Background(), []string{
+				"Kubernetes 1.20+",
+				"crd:helmreleases.helm.toolkit.fluxcd.io",
+				"api:monitoring.coreos.com/v1",
+				"api:apps",
+				"a running flux",
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports every prerequisite which isn't met", func() {
+			err := checker.Check(context.Background(), []string{
+				"Kubernetes 1.21+",
+				"crd:kustomizations.kustomize.toolkit.fluxcd.io",
+				"api:monitoring.coreos.com/v2",
+				"api:networking.istio.io",
+			})
+			Expect(err).To(MatchError("prerequisites not met: " +
+				"Kubernetes 1.21+: cluster runs Kubernetes v1.20.7-gke.1800; " +
+				"crd:kustomizations.kustomize.toolkit.fluxcd.io: CustomResourceDefinition not found; " +
+				"api:monitoring.coreos.com/v2: API version monitoring.coreos.com/v2 not served; " +
+				"api:networking.istio.io: API group networking.istio.io not served"))
+		})
+	})
+})
@@ -1,8 +1,8 @@
 package catalog
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
@@ -11,30 +11,30 @@ import (
 
 // Search queries the catalog at catalogURL for profiles matching the provided searchName.
 // If no searchName is provided it returns all profiles
-func (m *Manager) Search(catalogClient CatalogClient, searchName string) ([]profilesv1.ProfileCatalogEntry, error) {
+func (m *Manager) Search(ctx context.Context, catalogClient CatalogClient, searchName string) ([]profilesv1.ProfileCatalogEntry, error) {
 	var data []byte
 	var statusCode int
 	var err error
 
 	if searchName == "" {
-		data, statusCode, err = catalogClient.DoRequest("/profiles", nil)
+		data, statusCode, err = catalogClient.DoRequest(ctx, "/profiles", nil)
 	} else {
 		q := map[string]string{
 			"name": searchName,
 		}
-		data, statusCode, err = catalogClient.DoRequest("/profiles", q)
+		data, statusCode, err = catalogClient.DoRequest(ctx, "/profiles", q)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch catalog: %w", err)
+		return nil, requestError(err, "failed to fetch catalog")
 	}
 
 	if statusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch profile from catalog, status code %d", statusCode)
+		return nil, statusError(statusCode, "failed to fetch profile from catalog")
 	}
 	var profiles protos.GRPCProfileCatalogEntryList
 	if err := json.Unmarshal(data, &profiles); err != nil {
-		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+		return nil, parseError(err, "failed to parse catalog")
 	}
 
 	return profiles.Items, nil
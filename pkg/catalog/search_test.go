@@ -1,6 +1,7 @@
 package catalog_test
 
 import (
+	"context"
 	"fmt"
 
 	. "github.com/onsi/ginkgo"
@@ -38,10 +39,10 @@ var _ = Describe("Search", func() {
 		  `)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			resp, err := manager.Search(fakeCatalogClient, "nginx")
+			resp, err := manager.Search(context.Background(), fakeCatalogClient, "nginx")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles"))
 			Expect(query).To(Equal(map[string]string{
 				"name": "nginx",
@@ -83,10 +84,10 @@ var _ = Describe("Search", func() {
 		  `)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			resp, err := manager.Search(fakeCatalogClient, "")
+			resp, err := manager.Search(context.Background(), fakeCatalogClient, "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
-			path, _ := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, _ := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles"))
 			Expect(resp).To(ConsistOf(
 				profilesv1.ProfileCatalogEntry{
@@ -114,7 +115,7 @@ var _ = Describe("Search", func() {
 	When("catalog client fails to make the request", func() {
 		It("returns an error", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 502, fmt.Errorf("foo"))
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(MatchError("failed to fetch catalog: foo"))
 		})
 	})
@@ -124,9 +125,9 @@ var _ = Describe("Search", func() {
 			httpBody := []byte(`[]`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 500, nil)
 
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(MatchError("failed to fetch profile from catalog, status code 500"))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles"))
 			Expect(query).To(Equal(map[string]string{
 				"name": "dontexist",
@@ -139,7 +140,7 @@ var _ = Describe("Search", func() {
 			httpBody := []byte(`!20342 totally n:ot json "`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			_, err := manager.Search(fakeCatalogClient, "dontexist")
+			_, err := manager.Search(context.Background(), fakeCatalogClient, "dontexist")
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError(ContainSubstring("failed to parse catalog")))
 		})
@@ -1,8 +1,8 @@
 package catalog
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -12,29 +12,28 @@ import (
 )
 
 // Show queries the catalog at catalogURL for a profile matching the provided profileName
-func (m *Manager) Show(catalogClient CatalogClient, catalogName, profileName, profileVersion string) (profilesv1.ProfileCatalogEntry, error) {
+func (m *Manager) Show(ctx context.Context, catalogClient CatalogClient, catalogName, profileName, profileVersion string) (profilesv1.ProfileCatalogEntry, error) {
 	u, err := url.Parse("/profiles")
 	if err != nil {
 		return profilesv1.ProfileCatalogEntry{}, err
 	}
 	u.Path = path.Join(u.Path, catalogName, profileName, profileVersion)
-	data, code, err := catalogClient.DoRequest(u.String(), nil)
+	data, code, err := catalogClient.DoRequest(ctx, u.String(), nil)
 	if err != nil {
-		return profilesv1.ProfileCatalogEntry{}, fmt.Errorf("failed to do request: %w", err)
+		return profilesv1.ProfileCatalogEntry{}, requestError(err, "failed to do request")
 	}
 
 	if code != http.StatusOK {
 		if code == http.StatusNotFound {
-			return profilesv1.ProfileCatalogEntry{},
-				fmt.Errorf("unable to find profile %q in catalog %q (with version if provided: %s)",
-					profileName, catalogName, profileVersion)
+			return profilesv1.ProfileCatalogEntry{}, notFoundError(ctx, catalogClient, catalogName,
+				profileNotFoundError("unable to find profile %q in catalog %q (with version if provided: %s)", profileName, catalogName, profileVersion))
 		}
-		return profilesv1.ProfileCatalogEntry{}, fmt.Errorf("failed to fetch profile from catalog, status code %d", code)
+		return profilesv1.ProfileCatalogEntry{}, statusError(code, "failed to fetch profile from catalog")
 	}
 
 	var profile protos.GRPCProfileCatalogEntry
 	if err := json.Unmarshal(data, &profile); err != nil {
-		return profilesv1.ProfileCatalogEntry{}, fmt.Errorf("failed to parse profile: %w", err)
+		return profilesv1.ProfileCatalogEntry{}, parseError(err, "failed to parse profile")
 	}
 
 	return profile.Item, nil
@@ -1,7 +1,9 @@
 package catalog_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/weaveworks/pctl/pkg/catalog"
 	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	"github.com/weaveworks/pctl/pkg/client"
 )
 
 var _ = Describe("Show", func() {
@@ -37,10 +40,10 @@ var _ = Describe("Show", func() {
 		  `)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			resp, err := manager.Show(fakeCatalogClient, "foo", "weaveworks-nginx", "")
+			resp, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "weaveworks-nginx", "")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles/foo/weaveworks-nginx"))
 			Expect(query).To(BeEmpty())
 			Expect(resp).To(Equal(
@@ -74,10 +77,10 @@ var _ = Describe("Show", func() {
 		  `)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			resp, err := manager.Show(fakeCatalogClient, "foo", "weaveworks-nginx", "v0.1.0")
+			resp, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "weaveworks-nginx", "v0.1.0")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles/foo/weaveworks-nginx/v0.1.0"))
 			Expect(query).To(BeEmpty())
 			Expect(resp).To(Equal(
@@ -99,8 +102,26 @@ var _ = Describe("Show", func() {
 	When("http request fails", func() {
 		It("returns an error", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 0, errors.New("epic fail"))
-			_, err := manager.Show(fakeCatalogClient, "foo", "weaveworks-nginx", "")
+			_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "weaveworks-nginx", "")
 			Expect(err).To(MatchError(ContainSubstring("failed to do request: epic fail")))
+			Expect(errors.Is(err, catalog.ErrCatalogUnavailable)).To(BeTrue())
+		})
+
+		It("doesn't report the catalog as unavailable when the request is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			fakeCatalogClient.DoRequestReturns(nil, 0, fmt.Errorf("Get \"http://catalog/profiles/foo/weaveworks-nginx\": %w", ctx.Err()))
+			_, err := manager.Show(ctx, fakeCatalogClient, "foo", "weaveworks-nginx", "")
+			Expect(errors.Is(err, catalog.ErrCanceled)).To(BeTrue())
+			Expect(errors.Is(err, catalog.ErrCatalogUnavailable)).To(BeFalse())
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+
+		It("doesn't report the catalog as unavailable when the deadline is exceeded", func() {
+			fakeCatalogClient.DoRequestReturns(nil, 0, context.DeadlineExceeded)
+			_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "weaveworks-nginx", "")
+			Expect(errors.Is(err, catalog.ErrCanceled)).To(BeTrue())
+			Expect(errors.Is(err, catalog.ErrCatalogUnavailable)).To(BeFalse())
 		})
 	})
 
@@ -108,21 +129,40 @@ var _ = Describe("Show", func() {
 		It("returns an error", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 404, nil)
 
-			_, err := manager.Show(fakeCatalogClient, "foo", "dontexist", "")
+			_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "dontexist", "")
 			Expect(err).To(MatchError("unable to find profile \"dontexist\" in catalog \"foo\" (with version if provided: )"))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			Expect(errors.Is(err, catalog.ErrProfileNotFound)).To(BeTrue())
+			var statusErr *client.StatusError
+			Expect(errors.As(err, &statusErr)).To(BeTrue())
+			Expect(statusErr.Code()).To(BeEquivalentTo(404))
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles/foo/dontexist"))
 			Expect(query).To(BeEmpty())
 		})
+
+		When("the catalog doesn't serve any profiles", func() {
+			It("returns a catalog not found error", func() {
+				fakeCatalogClient.DoRequestReturnsOnCall(0, nil, 404, nil)
+				fakeCatalogClient.DoRequestReturnsOnCall(1, []byte(`{"items":[{"name": "nginx", "catalogSource": "other", "tag": "v0.1.0"}]}`), 200, nil)
+
+				_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "nginx", "v0.1.0")
+				Expect(err).To(MatchError(`unable to find catalog "foo"`))
+				Expect(errors.Is(err, catalog.ErrCatalogNotFound)).To(BeTrue())
+				_, path, query := fakeCatalogClient.DoRequestArgsForCall(1)
+				Expect(path).To(Equal("/profiles"))
+				Expect(query).To(BeNil())
+			})
+		})
 	})
 
 	When("the catalog returns a non 200 status code", func() {
 		It("returns an error", func() {
 			fakeCatalogClient.DoRequestReturns(nil, 500, nil)
 
-			_, err := manager.Show(fakeCatalogClient, "foo", "dontexist", "")
+			_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "dontexist", "")
 			Expect(err).To(MatchError("failed to fetch profile from catalog, status code 500"))
-			path, query := fakeCatalogClient.DoRequestArgsForCall(0)
+			Expect(errors.Is(err, catalog.ErrCatalogUnavailable)).To(BeTrue())
+			_, path, query := fakeCatalogClient.DoRequestArgsForCall(0)
 			Expect(path).To(Equal("/profiles/foo/dontexist"))
 			Expect(query).To(BeEmpty())
 		})
@@ -133,8 +173,9 @@ var _ = Describe("Show", func() {
 			httpBody := []byte(`!20342 totally n:ot json "`)
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 
-			_, err := manager.Show(fakeCatalogClient, "foo", "weaveworks-nginx", "")
+			_, err := manager.Show(context.Background(), fakeCatalogClient, "foo", "weaveworks-nginx", "")
 			Expect(err).To(MatchError(ContainSubstring("failed to parse profile")))
+			Expect(errors.Is(err, catalog.ErrInvalidResponse)).To(BeTrue())
 		})
 	})
 })
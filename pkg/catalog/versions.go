@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/weaveworks/pctl/pkg/installation"
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+// ProfileVersion describes a published version of a profile.
+type ProfileVersion struct {
+	Version     string   `json:"version"`
+	Tag         string   `json:"tag"`
+	Description string   `json:"description,omitempty"`
+	Prerelease  bool     `json:"prerelease"`
+	Installed   []string `json:"installed,omitempty"`
+}
+
+// Versions returns every published version of a profile sorted by semver, oldest first.
+// If k8sClient is provided, the installations of each version in the cluster are listed as namespace/name.
+// Failing to list the installations only leaves them out with a warning.
+func (m *Manager) Versions(ctx context.Context, k8sClient runtimeclient.Client, catalogClient CatalogClient, catalogName, profileName string) ([]ProfileVersion, error) {
+	entries, err := m.profileEntries(ctx, catalogClient, catalogName, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string][]string)
+	if k8sClient != nil {
+		installations, err := installation.NewManager(k8sClient).List(ctx)
+		if err != nil {
+			log.Warningf("unable to determine installed versions: %v", err)
+		}
+		for _, i := range installations {
+			if i.Catalog == catalogName && i.Profile == profileName {
+				installed[i.Version] = append(installed[i.Version], fmt.Sprintf("%s/%s", i.Namespace, i.Name))
+			}
+		}
+	}
+
+	versions := make([]ProfileVersion, 0, len(entries))
+	for _, e := range entries {
+		version := profilesv1.GetVersionFromTag(e.Tag)
+		pv := ProfileVersion{
+			Version:     version,
+			Tag:         e.Tag,
+			Description: e.Description,
+			Installed:   installed[version],
+		}
+		if v, err := semver.NewVersion(version); err == nil {
+			pv.Prerelease = v.Prerelease() != ""
+		}
+		versions = append(versions, pv)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, erri := semver.NewVersion(versions[i].Version)
+		vj, errj := semver.NewVersion(versions[j].Version)
+		if erri != nil || errj != nil {
+			return erri == nil && errj != nil
+		}
+		return vi.LessThan(vj)
+	})
+	return versions, nil
+}
+
+// profileEntries returns all catalog entries of the given profile.
+func (m *Manager) profileEntries(ctx context.Context, catalogClient CatalogClient, catalogName, profileName string) ([]profilesv1.ProfileCatalogEntry, error) {
+	profiles, err := m.Search(ctx, catalogClient, profileName)
+	if err != nil {
+		return nil, err
+	}
+	var entries []profilesv1.ProfileCatalogEntry
+	for _, p := range profiles {
+		if p.CatalogSource == catalogName && p.Name == profileName {
+			entries = append(entries, p)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, notFoundError(ctx, catalogClient, catalogName, profileNotFoundError("unable to find profile %q in catalog %q", profileName, catalogName))
+	}
+	return entries, nil
+}
@@ -0,0 +1,111 @@
+package catalog_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+)
+
+var _ = Describe("Versions", func() {
+	var (
+		fakeCatalogClient *fakes.FakeCatalogClient
+		fakeRuntimeClient runtimeclient.Client
+		manager           catalog.Manager
+	)
+
+	BeforeEach(func() {
+		fakeCatalogClient = new(fakes.FakeCatalogClient)
+		fakeCatalogClient.DoRequestReturns([]byte(`{"items":[
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.2.0"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.10.0-rc.1"},
+  {"name": "nginx", "catalogSource": "cat", "tag": "nginx/v0.1.0"},
+  {"name": "nginx", "catalogSource": "other", "tag": "nginx/v0.3.0"},
+  {"name": "nginx-2", "catalogSource": "cat", "tag": "nginx-2/v0.1.0"}
+]}`), 200, nil)
+		scheme := runtime.NewScheme()
+		Expect(profilesv1.AddToScheme(scheme)).To(Succeed())
+		fakeRuntimeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		Expect(fakeRuntimeClient.Create(context.TODO(), &profilesv1.ProfileInstallation{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ProfileInstallation",
+				APIVersion: "weave.works/v1alpha1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx",
+				Namespace: "default",
+			},
+			Spec: profilesv1.ProfileInstallationSpec{
+				Catalog: &profilesv1.Catalog{
+					Catalog: "cat",
+					Profile: "nginx",
+					Version: "v0.2.0",
+				},
+			},
+		})).To(Succeed())
+	})
+
+	It("lists every version of the profile sorted by semver", func() {
+		versions, err := manager.Versions(context.Background(), fakeRuntimeClient, fakeCatalogClient, "cat", "nginx")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]catalog.ProfileVersion{
+			{
+				Version: "v0.1.0",
+				Tag:     "nginx/v0.1.0",
+			},
+			{
+				Version:   "v0.2.0",
+				Tag:       "nginx/v0.2.0",
+				Installed: []string{"default/my-nginx"},
+			},
+			{
+				Version:    "v0.10.0-rc.1",
+				Tag:        "nginx/v0.10.0-rc.1",
+				Prerelease: true,
+			},
+		}))
+		_, _, query := fakeCatalogClient.DoRequestArgsForCall(0)
+		Expect(query).To(Equal(map[string]string{"name": "nginx"}))
+	})
+
+	When("no kubernetes client is provided", func() {
+		It("doesn't list installations", func() {
+			versions, err := manager.Versions(context.Background(), nil, fakeCatalogClient, "cat", "nginx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(HaveLen(3))
+			Expect(versions[1].Installed).To(BeEmpty())
+		})
+	})
+
+	When("the installations can't be listed", func() {
+		It("lists the versions without their installations", func() {
+			// ProfileInstallation isn't registered in the scheme so listing the installations fails.
+			failingRuntimeClient := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+			versions, err := manager.Versions(context.Background(), failingRuntimeClient, fakeCatalogClient, "cat", "nginx")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(HaveLen(3))
+			for _, v := range versions {
+				Expect(v.Installed).To(BeEmpty())
+			}
+		})
+	})
+
+	When("the profile doesn't exist", func() {
+		It("returns an error", func() {
+			_, err := manager.Versions(context.Background(), nil, fakeCatalogClient, "cat", "nope")
+			Expect(err).To(MatchError(`unable to find profile "nope" in catalog "cat"`))
+			Expect(errors.Is(err, catalog.ErrProfileNotFound)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/paths"
+)
+
+// DefaultCacheTTL is the time a cached catalog response is considered fresh.
+const DefaultCacheTTL = 5 * time.Minute
+
+// Requester makes requests to a catalog. It is satisfied by every catalog client.
+type Requester interface {
+	DoRequest(ctx context.Context, path string, query map[string]string) ([]byte, int, error)
+}
+
+// ConditionalRequester is implemented by catalog clients which can revalidate a cached response.
+type ConditionalRequester interface {
+	DoConditionalRequest(ctx context.Context, path string, query map[string]string, validators Validators) (Response, error)
+}
+
+// Validators are used to revalidate a previously fetched response.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Response is the result of a conditional request.
+type Response struct {
+	Data         []byte
+	StatusCode   int
+	ETag         string
+	LastModified string
+	// RetryAfter is the raw Retry-After header of the response, if any.
+	RetryAfter string
+}
+
+// CacheOptions holds options for the caching catalog client
+type CacheOptions struct {
+	// Dir is the directory responses are stored in. Defaults to <user cache dir>/pctl/catalog.
+	Dir string
+	// Namespace separates the cached responses of different catalog endpoints.
+	Namespace string
+	// TTL is the time a cached response is served without contacting the catalog.
+	TTL time.Duration
+	// NoCache bypasses the cache completely.
+	NoCache bool
+	// Offline serves responses only from the cache, regardless of their age.
+	Offline bool
+}
+
+// CachingClient is a catalog client decorator which stores responses on disk.
+type CachingClient struct {
+	CacheOptions
+	client Requester
+	now    func() time.Time
+}
+
+type cacheEntry struct {
+	Path         string            `json:"path"`
+	Query        map[string]string `json:"query,omitempty"`
+	StatusCode   int               `json:"statusCode"`
+	Data         []byte            `json:"data,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	LastModified string            `json:"lastModified,omitempty"`
+	StoredAt     time.Time         `json:"storedAt"`
+}
+
+// NewCachingClient wraps client with an on-disk response cache.
+func NewCachingClient(client Requester, options CacheOptions) (*CachingClient, error) {
+	if options.NoCache && options.Offline {
+		return nil, fmt.Errorf("offline mode requires the cache to be enabled")
+	}
+	if options.Dir == "" {
+		dir, err := paths.CacheDir()
+		if err != nil {
+			return nil, err
+		}
+		options.Dir = filepath.Join(dir, "catalog")
+	}
+	if options.TTL == 0 {
+		options.TTL = DefaultCacheTTL
+	}
+	return &CachingClient{
+		CacheOptions: options,
+		client:       client,
+		now:          time.Now,
+	}, nil
+}
+
+// DoRequest serves the request from the cache if possible, otherwise it is sent to the wrapped client.
+func (c *CachingClient) DoRequest(ctx context.Context, path string, query map[string]string) ([]byte, int, error) {
+	if c.NoCache {
+		return c.client.DoRequest(ctx, path, query)
+	}
+
+	filename := c.entryPath(path, query)
+	entry, err := c.load(filename)
+	if err != nil {
+		log.Warningf("ignoring unreadable cache entry %q: %v", filename, err)
+		entry = nil
+	}
+
+	if c.Offline {
+		if entry == nil {
+			return nil, 0, fmt.Errorf("no cached response for %q available in offline mode", path)
+		}
+		return entry.Data, entry.StatusCode, nil
+	}
+
+	if entry != nil && c.now().Sub(entry.StoredAt) < c.TTL {
+		return entry.Data, entry.StatusCode, nil
+	}
+
+	response, err := c.fetch(ctx, path, query, entry)
+	if err != nil || response.StatusCode >= http.StatusInternalServerError {
+		// an interrupted or timed out command should stop instead of carrying on with stale data.
+		if entry != nil && ctx.Err() == nil {
+			log.Warningf("catalog is unavailable, using cached response from %s", entry.StoredAt.Format(time.RFC3339))
+			return entry.Data, entry.StatusCode, nil
+		}
+		return response.Data, response.StatusCode, err
+	}
+
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		entry.StoredAt = c.now()
+		c.store(filename, entry)
+		return entry.Data, entry.StatusCode, nil
+	}
+
+	if response.StatusCode == http.StatusOK || response.StatusCode == http.StatusNotFound {
+		c.store(filename, &cacheEntry{
+			Path:         path,
+			Query:        query,
+			StatusCode:   response.StatusCode,
+			Data:         response.Data,
+			ETag:         response.ETag,
+			LastModified: response.LastModified,
+			StoredAt:     c.now(),
+		})
+	}
+	return response.Data, response.StatusCode, nil
+}
+
+// fetch revalidates the entry if the wrapped client supports it, otherwise it makes a plain request.
+func (c *CachingClient) fetch(ctx context.Context, path string, query map[string]string, entry *cacheEntry) (Response, error) {
+	if cr, ok := c.client.(ConditionalRequester); ok {
+		var validators Validators
+		if entry != nil {
+			validators = Validators{ETag: entry.ETag, LastModified: entry.LastModified}
+		}
+		return cr.DoConditionalRequest(ctx, path, query, validators)
+	}
+	data, code, err := c.client.DoRequest(ctx, path, query)
+	return Response{Data: data, StatusCode: code}, err
+}
+
+func (c *CachingClient) entryPath(path string, query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", c.Namespace, path)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(h, "%s=%s\n", k, query[k])
+	}
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func (c *CachingClient) load(filename string) (*cacheEntry, error) {
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// store writes the entry into the cache. Failing to do so is not fatal for the request.
+func (c *CachingClient) store(filename string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Warningf("failed to marshal cache entry: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		log.Warningf("failed to create cache directory: %v", err)
+		return
+	}
+	// write to a temporary file first so concurrent readers never see a partial entry.
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".entry")
+	if err != nil {
+		log.Warningf("failed to write cache entry: %v", err)
+		return
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		log.Warningf("failed to write cache entry: %v", err)
+		return
+	}
+	_ = tmp.Close()
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		_ = os.Remove(tmp.Name())
+		log.Warningf("failed to write cache entry: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var _ = Describe("CachingClient", func() {
+	var (
+		tmp               string
+		now               time.Time
+		fakeCatalogClient *fakes.FakeCatalogClient
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "pctl-cache")
+		Expect(err).NotTo(HaveOccurred())
+		now = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+		fakeCatalogClient = new(fakes.FakeCatalogClient)
+		fakeCatalogClient.DoRequestReturns([]byte(`{"items":[]}`), http.StatusOK, nil)
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmp)
+	})
+
+	newClient := func(options client.CacheOptions) *client.CachingClient {
+		options.Dir = tmp
+		c, err := client.NewCachingClient(fakeCatalogClient, options)
+		Expect(err).NotTo(HaveOccurred())
+		c.SetNow(func() time.Time { return now })
+		return c
+	}
+
+	It("serves fresh responses from the cache", func() {
+		c := newClient(client.CacheOptions{TTL: time.Minute})
+
+		data, code, err := c.DoRequest(context.Background(), "/profiles", map[string]string{"name": "nginx"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(string(data)).To(Equal(`{"items":[]}`))
+
+		data, code, err = c.DoRequest(context.Background(), "/profiles", map[string]string{"name": "nginx"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(string(data)).To(Equal(`{"items":[]}`))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+
+		_, _, err = c.DoRequest(context.Background(), "/profiles", map[string]string{"name": "other"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(2))
+	})
+
+	It("refetches expired responses", func() {
+		c := newClient(client.CacheOptions{TTL: time.Minute})
+
+		_, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+		Expect(err).NotTo(HaveOccurred())
+		now = now.Add(2 * time.Minute)
+		_, _, err = c.DoRequest(context.Background(), "/profiles", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(2))
+	})
+
+	It("caches not found responses", func() {
+		fakeCatalogClient.DoRequestReturns(nil, http.StatusNotFound, nil)
+		c := newClient(client.CacheOptions{})
+
+		_, code, err := c.DoRequest(context.Background(), "/profiles/catalog/nginx/v0.1.0/available_updates", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusNotFound))
+		_, code, err = c.DoRequest(context.Background(), "/profiles/catalog/nginx/v0.1.0/available_updates", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusNotFound))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+	})
+
+	When("the catalog is unavailable", func() {
+		It("serves stale responses", func() {
+			c := newClient(client.CacheOptions{TTL: time.Minute})
+			_, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			now = now.Add(time.Hour)
+			fakeCatalogClient.DoRequestReturns(nil, 0, errors.New("connection refused"))
+			data, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(data)).To(Equal(`{"items":[]}`))
+		})
+
+		It("returns the error if nothing is cached", func() {
+			fakeCatalogClient.DoRequestReturns(nil, 0, errors.New("connection refused"))
+			c := newClient(client.CacheOptions{})
+			_, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).To(MatchError("connection refused"))
+		})
+	})
+
+	When("no-cache is set", func() {
+		It("always contacts the catalog", func() {
+			c := newClient(client.CacheOptions{NoCache: true})
+			_, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(2))
+		})
+	})
+
+	When("offline is set", func() {
+		It("serves only from the cache", func() {
+			_, _, err := newClient(client.CacheOptions{TTL: time.Minute}).DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			now = now.Add(time.Hour)
+			c := newClient(client.CacheOptions{Offline: true})
+			data, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"items":[]}`))
+
+			_, _, err = c.DoRequest(context.Background(), "/profiles/catalog/nginx", nil)
+			Expect(err).To(MatchError(ContainSubstring("no cached response")))
+			Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+		})
+
+		It("can't be combined with no-cache", func() {
+			_, err := client.NewCachingClient(fakeCatalogClient, client.CacheOptions{Offline: true, NoCache: true})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the wrapped client supports conditional requests", func() {
+		It("revalidates expired responses", func() {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte(`{"items":[]}`))
+			}))
+			defer server.Close()
+			httpClient, err := client.NewHTTPClient(client.HTTPOptions{URL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			c, err := client.NewCachingClient(httpClient, client.CacheOptions{Dir: tmp, TTL: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+			c.SetNow(func() time.Time { return now })
+
+			_, _, err = c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			now = now.Add(time.Hour)
+			data, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(data)).To(Equal(`{"items":[]}`))
+			Expect(requests).To(Equal(2))
+
+			// the revalidated entry is fresh again
+			_, _, err = c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(Equal(2))
+		})
+	})
+})
@@ -44,17 +44,22 @@ type ServiceOptions struct {
 
 // StatusError represents an HTTP status error
 type StatusError struct {
-	wrapped *errors.StatusError
+	code int
+}
+
+// NewStatusError creates a StatusError for an unsuccessful catalog response.
+func NewStatusError(code int) *StatusError {
+	return &StatusError{code: code}
 }
 
 // Code returns the HTTP status code
 func (e *StatusError) Code() int32 {
-	return e.wrapped.ErrStatus.Code
+	return int32(e.code)
 }
 
 // Error implements error
 func (e *StatusError) Error() string {
-	return e.wrapped.Error()
+	return fmt.Sprintf("catalog returned status code %d (%s)", e.code, http.StatusText(e.code))
 }
 
 // Client is a catalog client
@@ -64,7 +69,7 @@ type Client struct {
 }
 
 // DoRequest sends a request to the catalog service
-func (c *Client) DoRequest(path string, query map[string]string) ([]byte, int, error) {
+func (c *Client) DoRequest(ctx context.Context, path string, query map[string]string) ([]byte, int, error) {
 	o := c.serviceOptions
 	u, err := url.Parse(path)
 	if err != nil {
@@ -72,7 +77,7 @@ func (c *Client) DoRequest(path string, query map[string]string) ([]byte, int, e
 	}
 	u.Path = filepath.Join(v1, u.Path)
 	responseWrapper := c.clientset.CoreV1().Services(o.Namespace).ProxyGet("http", o.ServiceName, o.ServicePort, u.String(), query)
-	data, err := responseWrapper.DoRaw(context.TODO())
+	data, err := responseWrapper.DoRaw(ctx)
 	if err != nil {
 		if se, ok := err.(*errors.StatusError); ok {
 			return nil, int(se.Status().Code), nil
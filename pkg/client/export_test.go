@@ -0,0 +1,14 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+func (c *CachingClient) SetNow(now func() time.Time) {
+	c.now = now
+}
+
+func (c *RetryingClient) SetSleep(sleep func(ctx context.Context, d time.Duration) error) {
+	c.sleep = sleep
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	profilescatalog "github.com/weaveworks/profiles/pkg/catalog"
+	"github.com/weaveworks/profiles/pkg/protos"
+	"sigs.k8s.io/yaml"
+)
+
+const availableUpdatesPath = "available_updates"
+
+// FileClient is a catalog client which answers catalog requests from a local directory
+// or index file of profile catalog entries, without contacting any catalog service.
+type FileClient struct {
+	catalog *profilescatalog.Catalog
+	logger  logr.Logger
+}
+
+// NewFileClient creates a new FileClient from the catalog entries found at path.
+// Path can either be a single index file or a directory which is walked for .yaml, .yml and .json files.
+// Each file contains a single entry, a list of entries or an object with an `items` list of entries.
+func NewFileClient(path string) (*FileClient, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog path %q: %w", path, err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			switch filepath.Ext(p) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk catalog directory %q: %w", path, err)
+		}
+	} else {
+		files = append(files, path)
+	}
+
+	var entries []profilesv1.ProfileCatalogEntry
+	for _, f := range files {
+		fileEntries, err := readCatalogEntries(f)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return NewFileClientFromEntries(entries)
+}
+
+// NewFileClientFromEntries creates a new FileClient serving the given entries.
+func NewFileClientFromEntries(entries []profilesv1.ProfileCatalogEntry) (*FileClient, error) {
+	sources := make(map[string][]profilesv1.ProfileCatalogEntry)
+	var order []string
+	for _, e := range entries {
+		if e.CatalogSource == "" {
+			return nil, fmt.Errorf("catalog entry %q with tag %q has no catalogSource set", e.Name, e.Tag)
+		}
+		if _, ok := sources[e.CatalogSource]; !ok {
+			order = append(order, e.CatalogSource)
+		}
+		sources[e.CatalogSource] = append(sources[e.CatalogSource], e)
+	}
+	c := profilescatalog.New()
+	for _, name := range order {
+		c.AddOrReplace(name, sources[name]...)
+	}
+	return &FileClient{
+		catalog: c,
+		logger:  logr.Discard(),
+	}, nil
+}
+
+func readCatalogEntries(filename string) ([]profilesv1.ProfileCatalogEntry, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog file %q: %w", filename, err)
+	}
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog file %q: %w", filename, err)
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		var entries []profilesv1.ProfileCatalogEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog file %q: %w", filename, err)
+		}
+		return entries, nil
+	}
+
+	var list protos.GRPCProfileCatalogEntryList
+	if err := json.Unmarshal(data, &list); err == nil && list.Items != nil {
+		return list.Items, nil
+	}
+	var entry profilesv1.ProfileCatalogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog file %q: %w", filename, err)
+	}
+	return []profilesv1.ProfileCatalogEntry{entry}, nil
+}
+
+// DoRequest answers a catalog request from the loaded entries. It supports the same paths as the catalog service.
+func (c *FileClient) DoRequest(ctx context.Context, p string, query map[string]string) ([]byte, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if parts[0] != "profiles" {
+		return nil, http.StatusNotFound, nil
+	}
+
+	switch {
+	case len(parts) == 1:
+		var result []profilesv1.ProfileCatalogEntry
+		if name := query["name"]; name != "" {
+			result = c.catalog.Search(name)
+		} else {
+			result = c.catalog.SearchAll()
+		}
+		return marshalResponse(protos.GRPCProfileCatalogEntryList{Items: result})
+	case len(parts) == 3:
+		result := c.catalog.Get(parts[1], parts[2])
+		if result == nil {
+			return nil, http.StatusNotFound, nil
+		}
+		return marshalResponse(protos.GRPCProfileCatalogEntry{Item: *result})
+	case len(parts) == 4:
+		result := c.catalog.GetWithVersion(c.logger, parts[1], parts[2], parts[3])
+		if result == nil {
+			return nil, http.StatusNotFound, nil
+		}
+		return marshalResponse(protos.GRPCProfileCatalogEntry{Item: *result})
+	case len(parts) == 5 && parts[4] == availableUpdatesPath:
+		result := c.catalog.ProfilesGreaterThanVersion(c.logger, parts[1], parts[2], parts[3])
+		if len(result) == 0 {
+			return nil, http.StatusNotFound, nil
+		}
+		return marshalResponse(protos.GRPCProfileCatalogEntryList{Items: result})
+	}
+	return nil, http.StatusNotFound, nil
+}
+
+func marshalResponse(v interface{}) ([]byte, int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return data, http.StatusOK, nil
+}
@@ -0,0 +1,130 @@
+package client_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var _ = Describe("FileClient", func() {
+	var (
+		tmp     string
+		manager catalog.Manager
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "pctl-file-catalog")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(tmp, "nginx.yaml"), []byte(`items:
+- name: weaveworks-nginx
+  catalogSource: nginx-catalog
+  tag: weaveworks-nginx/v0.1.0
+  url: https://github.com/weaveworks/profiles-examples
+  description: nginx
+- name: weaveworks-nginx
+  catalogSource: nginx-catalog
+  tag: weaveworks-nginx/v0.1.1
+  url: https://github.com/weaveworks/profiles-examples
+  description: nginx
+`), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tmp, "other"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tmp, "other", "bitnami.json"), []byte(`{
+  "name": "bitnami-nginx",
+  "catalogSource": "other-catalog",
+  "tag": "v0.0.1",
+  "description": "bitnami"
+}`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tmp, "README.md"), []byte(`ignored`), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmp)
+	})
+
+	It("serves search requests", func() {
+		c, err := client.NewFileClient(tmp)
+		Expect(err).NotTo(HaveOccurred())
+
+		profiles, err := manager.Search(context.Background(), c, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(3))
+
+		profiles, err = manager.Search(context.Background(), c, "bitnami")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(1))
+		Expect(profiles[0].CatalogSource).To(Equal("other-catalog"))
+	})
+
+	It("serves show requests", func() {
+		c, err := client.NewFileClient(tmp)
+		Expect(err).NotTo(HaveOccurred())
+
+		profile, err := manager.Show(context.Background(), c, "nginx-catalog", "weaveworks-nginx", "v0.1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Tag).To(Equal("weaveworks-nginx/v0.1.0"))
+
+		profile, err = manager.Show(context.Background(), c, "nginx-catalog", "weaveworks-nginx", "latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Tag).To(Equal("weaveworks-nginx/v0.1.1"))
+
+		_, err = manager.Show(context.Background(), c, "nginx-catalog", "weaveworks-nginx", "v9.9.9")
+		Expect(err).To(MatchError(ContainSubstring("unable to find profile")))
+	})
+
+	It("serves available updates requests", func() {
+		c, err := client.NewFileClient(tmp)
+		Expect(err).NotTo(HaveOccurred())
+
+		updates, err := catalog.GetAvailableUpdates(context.Background(), c, "nginx-catalog", "weaveworks-nginx", "v0.1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(HaveLen(1))
+		Expect(updates[0].Tag).To(Equal("weaveworks-nginx/v0.1.1"))
+
+		updates, err = catalog.GetAvailableUpdates(context.Background(), c, "nginx-catalog", "weaveworks-nginx", "v0.1.1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+	})
+
+	It("can be created from a single index file", func() {
+		c, err := client.NewFileClient(filepath.Join(tmp, "nginx.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		profiles, err := manager.Search(context.Background(), c, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(2))
+	})
+
+	It("returns not found for unknown paths", func() {
+		c, err := client.NewFileClientFromEntries(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, code, err := c.DoRequest(context.Background(), "/unknown", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	When("an entry has no catalog source", func() {
+		It("returns an error", func() {
+			_, err := client.NewFileClientFromEntries([]profilesv1.ProfileCatalogEntry{{Name: "foo"}})
+			Expect(err).To(MatchError(ContainSubstring("has no catalogSource set")))
+		})
+	})
+
+	When("the path doesn't exist", func() {
+		It("returns an error", func() {
+			_, err := client.NewFileClient(filepath.Join(tmp, "nope"))
+			Expect(err).To(MatchError(ContainSubstring("failed to read catalog path")))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+const defaultHTTPTimeout = 30 * time.Second
+
+// HTTPOptions holds options to connect to the catalog service directly
+type HTTPOptions struct {
+	// URL is the base URL of the catalog API, e.g. https://catalog.example.com
+	URL string
+	// CAFile is an optional path to a PEM encoded CA bundle used to verify the catalog's certificate
+	CAFile string
+	// Token is an optional bearer token sent with every request
+	Token string
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+	// Timeout for a single request, defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// HTTPClient is a catalog client which talks to the catalog API over HTTP(S)
+// without going through the Kubernetes service proxy
+type HTTPClient struct {
+	baseURL *url.URL
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPClient creates a new HTTPClient from the supplied options
+func NewHTTPClient(options HTTPOptions) (*HTTPClient, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("catalog url must be provided")
+	}
+	u, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog url %q: %w", options.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported catalog url scheme %q, must be http or https", u.Scheme)
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if options.CAFile != "" {
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %q: %w", options.CAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in CA file %q", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &HTTPClient{
+		baseURL: u,
+		token:   options.Token,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// DoRequest sends a request to the catalog API
+func (c *HTTPClient) DoRequest(ctx context.Context, p string, query map[string]string) ([]byte, int, error) {
+	resp, err := c.DoConditionalRequest(ctx, p, query, Validators{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Data, resp.StatusCode, nil
+}
+
+// DoConditionalRequest sends a request to the catalog API which is answered with
+// http.StatusNotModified if the validators still match the requested resource.
+func (c *HTTPClient) DoConditionalRequest(ctx context.Context, p string, query map[string]string, validators Validators) (Response, error) {
+	ref, err := url.Parse(p)
+	if err != nil {
+		return Response{}, err
+	}
+	u := *c.baseURL
+	u.Path = path.Join("/", u.Path, v1, ref.Path)
+	q := u.Query()
+	for k, v := range query {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	response := Response{
+		StatusCode:   resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		RetryAfter:   resp.Header.Get("Retry-After"),
+	}
+	if resp.StatusCode == http.StatusOK {
+		response.Data = data
+	}
+	return response, nil
+}
@@ -0,0 +1,142 @@
+package client_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var _ = Describe("HTTPClient", func() {
+	var (
+		server      *httptest.Server
+		lastRequest *http.Request
+		status      int
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastRequest = r
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("NewHTTPClient", func() {
+		When("the url is empty", func() {
+			It("returns an error", func() {
+				_, err := client.NewHTTPClient(client.HTTPOptions{})
+				Expect(err).To(MatchError("catalog url must be provided"))
+			})
+		})
+
+		When("the url scheme is not supported", func() {
+			It("returns an error", func() {
+				_, err := client.NewHTTPClient(client.HTTPOptions{URL: "ftp://catalog"})
+				Expect(err).To(MatchError(ContainSubstring("unsupported catalog url scheme")))
+			})
+		})
+
+		When("the CA file doesn't contain certificates", func() {
+			It("returns an error", func() {
+				tmp, err := ioutil.TempDir("", "pctl-ca")
+				Expect(err).NotTo(HaveOccurred())
+				defer func() { _ = os.RemoveAll(tmp) }()
+				caFile := filepath.Join(tmp, "ca.pem")
+				Expect(ioutil.WriteFile(caFile, []byte("not a cert"), 0644)).To(Succeed())
+
+				_, err = client.NewHTTPClient(client.HTTPOptions{URL: server.URL, CAFile: caFile})
+				Expect(err).To(MatchError(ContainSubstring("no valid certificates found in CA file")))
+			})
+		})
+	})
+
+	Describe("DoRequest", func() {
+		It("sends the request to the versioned path with the query and token", func() {
+			c, err := client.NewHTTPClient(client.HTTPOptions{URL: server.URL, Token: "secret"})
+			Expect(err).NotTo(HaveOccurred())
+
+			data, code, err := c.DoRequest(context.Background(), "/profiles", map[string]string{"name": "nginx"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(string(data)).To(Equal(`{"items":[]}`))
+			Expect(lastRequest.URL.Path).To(Equal("/v1/profiles"))
+			Expect(lastRequest.URL.Query().Get("name")).To(Equal("nginx"))
+			Expect(lastRequest.Header.Get("Authorization")).To(Equal("Bearer secret"))
+		})
+
+		It("keeps any path prefix of the base url", func() {
+			c, err := client.NewHTTPClient(client.HTTPOptions{URL: server.URL + "/catalog"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = c.DoRequest(context.Background(), "/profiles/catalog/nginx/v0.1.0", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastRequest.URL.Path).To(Equal("/catalog/v1/profiles/catalog/nginx/v0.1.0"))
+			Expect(lastRequest.Header.Get("Authorization")).To(BeEmpty())
+		})
+
+		When("the catalog returns a non 200 status code", func() {
+			It("returns the status code without an error", func() {
+				status = http.StatusNotFound
+				c, err := client.NewHTTPClient(client.HTTPOptions{URL: server.URL})
+				Expect(err).NotTo(HaveOccurred())
+
+				data, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusNotFound))
+				Expect(data).To(BeNil())
+			})
+		})
+
+		When("the catalog is served over TLS", func() {
+			It("verifies the certificate unless told otherwise", func() {
+				tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`{}`))
+				}))
+				defer tlsServer.Close()
+
+				c, err := client.NewHTTPClient(client.HTTPOptions{URL: tlsServer.URL})
+				Expect(err).NotTo(HaveOccurred())
+				_, _, err = c.DoRequest(context.Background(), "/profiles", nil)
+				Expect(err).To(HaveOccurred())
+
+				c, err = client.NewHTTPClient(client.HTTPOptions{URL: tlsServer.URL, InsecureSkipVerify: true})
+				Expect(err).NotTo(HaveOccurred())
+				_, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(http.StatusOK))
+			})
+		})
+
+		When("the context is cancelled", func() {
+			It("aborts the request", func() {
+				blocked := make(chan struct{})
+				slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-blocked
+				}))
+				defer slowServer.Close()
+				defer close(blocked)
+
+				c, err := client.NewHTTPClient(client.HTTPOptions{URL: slowServer.URL})
+				Expect(err).NotTo(HaveOccurred())
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				_, _, err = c.DoRequest(ctx, "/profiles", nil)
+				Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+			})
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+const (
+	// DefaultRetryAttempts is the number of times a catalog request is attempted before giving up.
+	DefaultRetryAttempts = 4
+	// DefaultRetryMaxWait is the longest pctl waits between two attempts.
+	DefaultRetryMaxWait = 30 * time.Second
+
+	initialBackoff = 500 * time.Millisecond
+)
+
+// RetryOptions holds options for the retrying catalog client
+type RetryOptions struct {
+	// Attempts is the maximum number of attempts per request, including the first one.
+	Attempts int
+	// MaxWait caps the wait between two attempts, including waits requested by Retry-After.
+	MaxWait time.Duration
+}
+
+// RetryingClient is a catalog client decorator which retries requests failing with a transient error.
+// Server errors, 429 Too Many Requests and refused connections are retried with jittered
+// exponential backoff. Other 4xx responses are returned straight away.
+type RetryingClient struct {
+	RetryOptions
+	client Requester
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+var _ ConditionalRequester = &RetryingClient{}
+
+// NewRetryingClient wraps client with retries.
+func NewRetryingClient(client Requester, options RetryOptions) *RetryingClient {
+	if options.Attempts < 1 {
+		options.Attempts = DefaultRetryAttempts
+	}
+	if options.MaxWait <= 0 {
+		options.MaxWait = DefaultRetryMaxWait
+	}
+	return &RetryingClient{
+		RetryOptions: options,
+		client:       client,
+		sleep:        sleep,
+	}
+}
+
+// DoRequest sends the request to the wrapped client until it succeeds or the attempts are exhausted.
+func (c *RetryingClient) DoRequest(ctx context.Context, path string, query map[string]string) ([]byte, int, error) {
+	response, err := c.DoConditionalRequest(ctx, path, query, Validators{})
+	return response.Data, response.StatusCode, err
+}
+
+// DoConditionalRequest retries a conditional request. If the wrapped client doesn't support conditional
+// requests the validators are ignored and the full response is returned.
+func (c *RetryingClient) DoConditionalRequest(ctx context.Context, path string, query map[string]string, validators Validators) (Response, error) {
+	// conditional requests are also used for plain ones as they expose the Retry-After header.
+	if cr, ok := c.client.(ConditionalRequester); ok {
+		return c.retry(ctx, func() (Response, error) {
+			return cr.DoConditionalRequest(ctx, path, query, validators)
+		})
+	}
+	return c.retry(ctx, func() (Response, error) {
+		data, code, err := c.client.DoRequest(ctx, path, query)
+		return Response{Data: data, StatusCode: code}, err
+	})
+}
+
+func (c *RetryingClient) retry(ctx context.Context, request func() (Response, error)) (Response, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := request()
+		if attempt >= c.Attempts || !retryable(ctx, response, err) {
+			return response, err
+		}
+
+		wait := c.backoff(attempt, response.RetryAfter)
+		if err != nil {
+			log.Warningf("catalog request failed: %v, retrying in %s", err, wait)
+		} else {
+			log.Warningf("catalog returned status code %d, retrying in %s", response.StatusCode, wait)
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return response, err
+		}
+	}
+}
+
+// backoff returns the wait before the next attempt. Retry-After takes precedence over
+// the exponential backoff, both are capped at MaxWait.
+func (c *RetryingClient) backoff(attempt int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		if d > c.MaxWait {
+			return c.MaxWait
+		}
+		return d
+	}
+	d := initialBackoff << (attempt - 1)
+	if d > c.MaxWait || d <= 0 {
+		d = c.MaxWait
+	}
+	// full jitter in the upper half of the interval spreads out clients retrying at the same time.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(ctx context.Context, response Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter parses the delay in seconds or the HTTP date of a Retry-After header.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	"github.com/weaveworks/pctl/pkg/client"
+)
+
+var _ = Describe("RetryingClient", func() {
+	var (
+		fakeCatalogClient *fakes.FakeCatalogClient
+		waits             []time.Duration
+		connectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	)
+
+	BeforeEach(func() {
+		fakeCatalogClient = new(fakes.FakeCatalogClient)
+		waits = nil
+	})
+
+	newClient := func(requester client.Requester, options client.RetryOptions) *client.RetryingClient {
+		c := client.NewRetryingClient(requester, options)
+		c.SetSleep(func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		})
+		return c
+	}
+
+	It("retries server errors and refused connections until the request succeeds", func() {
+		fakeCatalogClient.DoRequestReturnsOnCall(0, nil, http.StatusServiceUnavailable, nil)
+		fakeCatalogClient.DoRequestReturnsOnCall(1, nil, 0, fmt.Errorf("request failed: %w", connectionRefused))
+		fakeCatalogClient.DoRequestReturnsOnCall(2, []byte(`{"items":[]}`), http.StatusOK, nil)
+		c := newClient(fakeCatalogClient, client.RetryOptions{Attempts: 4, MaxWait: time.Minute})
+
+		data, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(string(data)).To(Equal(`{"items":[]}`))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(3))
+		Expect(waits).To(HaveLen(2))
+		Expect(waits[0]).To(BeNumerically(">=", 250*time.Millisecond))
+		Expect(waits[0]).To(BeNumerically("<=", 500*time.Millisecond))
+		Expect(waits[1]).To(BeNumerically(">=", 500*time.Millisecond))
+		Expect(waits[1]).To(BeNumerically("<=", time.Second))
+	})
+
+	It("returns the last response once the attempts are exhausted", func() {
+		fakeCatalogClient.DoRequestReturns(nil, http.StatusTooManyRequests, nil)
+		c := newClient(fakeCatalogClient, client.RetryOptions{Attempts: 3, MaxWait: time.Second})
+
+		_, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusTooManyRequests))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(3))
+	})
+
+	It("doesn't retry client errors", func() {
+		fakeCatalogClient.DoRequestReturns(nil, http.StatusNotFound, nil)
+		c := newClient(fakeCatalogClient, client.RetryOptions{})
+
+		_, code, err := c.DoRequest(context.Background(), "/profiles/catalog/nginx/v0.1.0", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(http.StatusNotFound))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+	})
+
+	It("doesn't retry other errors", func() {
+		fakeCatalogClient.DoRequestReturns(nil, 0, errors.New("invalid request"))
+		c := newClient(fakeCatalogClient, client.RetryOptions{})
+
+		_, _, err := c.DoRequest(context.Background(), "/profiles", nil)
+		Expect(err).To(MatchError("invalid request"))
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+	})
+
+	It("stops when the context is cancelled", func() {
+		fakeCatalogClient.DoRequestReturns(nil, http.StatusBadGateway, nil)
+		c := client.NewRetryingClient(fakeCatalogClient, client.RetryOptions{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := c.DoRequest(ctx, "/profiles", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeCatalogClient.DoRequestCallCount()).To(Equal(1))
+	})
+
+	When("the catalog sends Retry-After", func() {
+		It("waits as long as requested, up to the max wait", func() {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests == 1 {
+					w.Header().Set("Retry-After", "2")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if requests == 2 {
+					w.Header().Set("Retry-After", "120")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte(`{"items":[]}`))
+			}))
+			defer server.Close()
+			httpClient, err := client.NewHTTPClient(client.HTTPOptions{URL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+			c := newClient(httpClient, client.RetryOptions{MaxWait: 10 * time.Second})
+
+			_, code, err := c.DoRequest(context.Background(), "/profiles", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(waits).To(Equal([]time.Duration{2 * time.Second, 10 * time.Second}))
+		})
+	})
+})
@@ -0,0 +1,300 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+)
+
+// DefinitionFetcher fetches the definition of a profile from its repository.
+//go:generate counterfeiter -o fakes/fake_definition_fetcher.go . DefinitionFetcher
+type DefinitionFetcher interface {
+	ProfileDefinition(url, branchOrTag, path string) (profilesv1.ProfileDefinition, error)
+}
+
+// Config holds the fields used to diff two versions of a catalog profile.
+type Config struct {
+	CatalogClient  catalog.CatalogClient
+	CatalogManager catalog.CatalogManager
+	Fetcher        DefinitionFetcher
+	CatalogName    string
+	ProfileName    string
+	From           string
+	To             string
+}
+
+// ChangeType describes how an artifact changed.
+type ChangeType string
+
+const (
+	// Added artifacts only exist in the target version.
+	Added ChangeType = "added"
+	// Removed artifacts only exist in the source version.
+	Removed ChangeType = "removed"
+	// Modified artifacts exist in both versions with a different Field.
+	Modified ChangeType = "modified"
+)
+
+// Change is a single difference of an artifact between two versions of a profile.
+type Change struct {
+	Artifact string     `json:"artifact"`
+	Type     ChangeType `json:"type"`
+	// Field is the changed property of a modified artifact, e.g. chart.version or chart.defaultValues.replicaCount.
+	Field string `json:"field,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// Result is the difference between two versions of a catalog profile.
+type Result struct {
+	Catalog string   `json:"catalog"`
+	Profile string   `json:"profile"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Diff fetches the profile definitions of both versions of a catalog profile and compares their artifacts.
+func Diff(ctx context.Context, cfg Config) (Result, error) {
+	from, err := definition(ctx, cfg, cfg.From)
+	if err != nil {
+		return Result{}, err
+	}
+	to, err := definition(ctx, cfg, cfg.To)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Catalog: cfg.CatalogName,
+		Profile: cfg.ProfileName,
+		From:    cfg.From,
+		To:      cfg.To,
+		Changes: Compare(from, to),
+	}, nil
+}
+
+func definition(ctx context.Context, cfg Config, version string) (profilesv1.ProfileDefinition, error) {
+	p, err := cfg.CatalogManager.Show(ctx, cfg.CatalogClient, cfg.CatalogName, cfg.ProfileName, version)
+	if err != nil {
+		return profilesv1.ProfileDefinition{}, fmt.Errorf("failed to get profile %q in catalog %q version %q: %w", cfg.ProfileName, cfg.CatalogName, version, err)
+	}
+	//tag could be <semver> or <name/semver>
+	path := "."
+	splitTag := strings.Split(p.Tag, "/")
+	if len(splitTag) > 1 {
+		path = splitTag[0]
+	}
+	def, err := cfg.Fetcher.ProfileDefinition(p.URL, p.Tag, path)
+	if err != nil {
+		return profilesv1.ProfileDefinition{}, fmt.Errorf("failed to fetch definition of profile %q version %q: %w", cfg.ProfileName, version, err)
+	}
+	return def, nil
+}
+
+// Compare returns the changes of the artifacts of from in to. Added and removed artifacts come first,
+// in the order of their definition, followed by the modifications of every artifact.
+func Compare(from, to profilesv1.ProfileDefinition) []Change {
+	fromArtifacts := make(map[string]profilesv1.Artifact)
+	for _, a := range from.Spec.Artifacts {
+		fromArtifacts[a.Name] = a
+	}
+	toArtifacts := make(map[string]profilesv1.Artifact)
+	for _, a := range to.Spec.Artifacts {
+		toArtifacts[a.Name] = a
+	}
+
+	changes := []Change{}
+	for _, a := range from.Spec.Artifacts {
+		if _, ok := toArtifacts[a.Name]; !ok {
+			changes = append(changes, Change{Artifact: a.Name, Type: Removed, From: kind(a)})
+		}
+	}
+	for _, a := range to.Spec.Artifacts {
+		if _, ok := fromArtifacts[a.Name]; !ok {
+			changes = append(changes, Change{Artifact: a.Name, Type: Added, To: kind(a)})
+		}
+	}
+	for _, a := range to.Spec.Artifacts {
+		if previous, ok := fromArtifacts[a.Name]; ok {
+			changes = append(changes, compareArtifacts(previous, a)...)
+		}
+	}
+	return changes
+}
+
+func compareArtifacts(from, to profilesv1.Artifact) []Change {
+	var changes []Change
+	modified := func(field, f, t string) {
+		if f != t {
+			changes = append(changes, Change{Artifact: to.Name, Type: Modified, Field: field, From: f, To: t})
+		}
+	}
+
+	fromFields, toFields := fields(from), fields(to)
+	for _, field := range fieldNames(fromFields, toFields) {
+		if field == "chart.defaultValues" {
+			changes = append(changes, compareValues(to.Name, fromFields[field], toFields[field])...)
+			continue
+		}
+		modified(field, fromFields[field], toFields[field])
+	}
+
+	fromDeps, toDeps := dependencies(from), dependencies(to)
+	for _, d := range fromDeps {
+		if !contains(toDeps, d) {
+			modified("dependsOn", d, "")
+		}
+	}
+	for _, d := range toDeps {
+		if !contains(fromDeps, d) {
+			modified("dependsOn", "", d)
+		}
+	}
+	return changes
+}
+
+// fields flattens the source of an artifact. Fields which aren't set are omitted.
+func fields(a profilesv1.Artifact) map[string]string {
+	f := map[string]string{"kind": kind(a)}
+	set := func(field, value string) {
+		if value != "" {
+			f[field] = value
+		}
+	}
+	if a.Chart != nil {
+		set("chart.url", a.Chart.URL)
+		set("chart.name", a.Chart.Name)
+		set("chart.version", a.Chart.Version)
+		set("chart.path", a.Chart.Path)
+		set("chart.defaultValues", a.Chart.DefaultValues)
+	}
+	if a.Kustomize != nil {
+		set("kustomize.path", a.Kustomize.Path)
+	}
+	if a.Profile != nil && a.Profile.Source != nil {
+		set("profile.url", a.Profile.Source.URL)
+		set("profile.branch", a.Profile.Source.Branch)
+		set("profile.tag", a.Profile.Source.Tag)
+		set("profile.path", a.Profile.Source.Path)
+	}
+	return f
+}
+
+// compareValues reports changed default values per key, or as a whole when they aren't a YAML map.
+func compareValues(artifact, from, to string) []Change {
+	fromValues, fromErr := flattenValues(from)
+	toValues, toErr := flattenValues(to)
+	if fromErr != nil || toErr != nil {
+		if from == to {
+			return nil
+		}
+		return []Change{{Artifact: artifact, Type: Modified, Field: "chart.defaultValues", From: from, To: to}}
+	}
+	var changes []Change
+	for _, key := range fieldNames(fromValues, toValues) {
+		if fromValues[key] != toValues[key] {
+			changes = append(changes, Change{
+				Artifact: artifact,
+				Type:     Modified,
+				Field:    "chart.defaultValues." + key,
+				From:     fromValues[key],
+				To:       toValues[key],
+			})
+		}
+	}
+	return changes
+}
+
+// flattenValues maps the dotted path of every leaf in values to its JSON encoding.
+func flattenValues(values string) (map[string]string, error) {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &m); err != nil {
+		return nil, err
+	}
+	flat := make(map[string]string)
+	var walk func(prefix string, v interface{}) error
+	walk = func(prefix string, v interface{}) error {
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			for k, nv := range nested {
+				if err := walk(joinKey(prefix, k), nv); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if s, ok := v.(string); ok {
+			flat[prefix] = s
+			return nil
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		flat[prefix] = string(out)
+		return nil
+	}
+	for k, v := range m {
+		if err := walk(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return flat, nil
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// fieldNames returns the sorted union of the keys of a and b.
+func fieldNames(a, b map[string]string) []string {
+	var names []string
+	for k := range a {
+		names = append(names, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func kind(a profilesv1.Artifact) string {
+	switch {
+	case a.Profile != nil:
+		return "profile"
+	case a.Chart != nil:
+		return "chart"
+	case a.Kustomize != nil:
+		return "kustomize"
+	}
+	return ""
+}
+
+func dependencies(a profilesv1.Artifact) []string {
+	var deps []string
+	for _, d := range a.DependsOn {
+		deps = append(deps, d.Name)
+	}
+	return deps
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
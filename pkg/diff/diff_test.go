@@ -0,0 +1,146 @@
+package diff_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	catalogfakes "github.com/weaveworks/pctl/pkg/catalog/fakes"
+	"github.com/weaveworks/pctl/pkg/diff"
+	"github.com/weaveworks/pctl/pkg/diff/fakes"
+)
+
+var _ = Describe("Diff", func() {
+	var (
+		fakeCatalogManager *catalogfakes.FakeCatalogManager
+		fakeFetcher        *fakes.FakeDefinitionFetcher
+		cfg                diff.Config
+		from, to           profilesv1.ProfileDefinition
+	)
+
+	BeforeEach(func() {
+		fakeCatalogManager = new(catalogfakes.FakeCatalogManager)
+		fakeFetcher = new(fakes.FakeDefinitionFetcher)
+		fakeCatalogManager.ShowStub = func(_ context.Context, _ catalog.CatalogClient, _, _, version string) (profilesv1.ProfileCatalogEntry, error) {
+			return profilesv1.ProfileCatalogEntry{
+				Name: "nginx",
+				Tag:  "nginx/" + version,
+				URL:  "https://github.com/weaveworks/profiles-examples",
+			}, nil
+		}
+		from = profilesv1.ProfileDefinition{
+			Spec: profilesv1.ProfileDefinitionSpec{
+				Artifacts: []profilesv1.Artifact{
+					{
+						Name: "server",
+						Chart: &profilesv1.Chart{
+							URL:           "https://charts.bitnami.com/bitnami",
+							Name:          "nginx",
+							Version:       "8.9.1",
+							DefaultValues: "replicaCount: 1\nservice:\n  type: ClusterIP\n",
+						},
+					},
+					{
+						Name:      "config",
+						Kustomize: &profilesv1.Kustomize{Path: "nginx/config"},
+					},
+					{
+						Name: "nested",
+						Profile: &profilesv1.Profile{
+							Source: &profilesv1.Source{URL: "https://github.com/weaveworks/profiles-examples", Tag: "bitnami-nginx/v0.0.1"},
+						},
+					},
+				},
+			},
+		}
+		to = profilesv1.ProfileDefinition{
+			Spec: profilesv1.ProfileDefinitionSpec{
+				Artifacts: []profilesv1.Artifact{
+					{
+						Name: "server",
+						Chart: &profilesv1.Chart{
+							URL:           "https://charts.bitnami.com/bitnami",
+							Name:          "nginx",
+							Version:       "9.0.0",
+							DefaultValues: "replicaCount: 2\nservice:\n  type: ClusterIP\n  port: 8080\n",
+						},
+						DependsOn: []profilesv1.DependsOn{{Name: "cache"}},
+					},
+					{
+						Name: "cache",
+						Chart: &profilesv1.Chart{
+							URL:  "https://charts.bitnami.com/bitnami",
+							Name: "redis",
+						},
+					},
+					{
+						Name: "nested",
+						Profile: &profilesv1.Profile{
+							Source: &profilesv1.Source{URL: "https://github.com/weaveworks/profiles-examples", Tag: "bitnami-nginx/v0.0.2"},
+						},
+					},
+				},
+			},
+		}
+		fakeFetcher.ProfileDefinitionReturnsOnCall(0, from, nil)
+		fakeFetcher.ProfileDefinitionReturnsOnCall(1, to, nil)
+		cfg = diff.Config{
+			CatalogManager: fakeCatalogManager,
+			Fetcher:        fakeFetcher,
+			CatalogName:    "catalog",
+			ProfileName:    "nginx",
+			From:           "v0.1.0",
+			To:             "v0.2.0",
+		}
+	})
+
+	It("returns the artifact changes between the two versions", func() {
+		result, err := diff.Diff(context.Background(), cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeFetcher.ProfileDefinitionCallCount()).To(Equal(2))
+		url, tag, path := fakeFetcher.ProfileDefinitionArgsForCall(0)
+		Expect(url).To(Equal("https://github.com/weaveworks/profiles-examples"))
+		Expect(tag).To(Equal("nginx/v0.1.0"))
+		Expect(path).To(Equal("nginx"))
+		_, tag, _ = fakeFetcher.ProfileDefinitionArgsForCall(1)
+		Expect(tag).To(Equal("nginx/v0.2.0"))
+
+		Expect(result.From).To(Equal("v0.1.0"))
+		Expect(result.To).To(Equal("v0.2.0"))
+		Expect(result.Changes).To(Equal([]diff.Change{
+			{Artifact: "config", Type: diff.Removed, From: "kustomize"},
+			{Artifact: "cache", Type: diff.Added, To: "chart"},
+			{Artifact: "server", Type: diff.Modified, Field: "chart.defaultValues.replicaCount", From: "1", To: "2"},
+			{Artifact: "server", Type: diff.Modified, Field: "chart.defaultValues.service.port", To: "8080"},
+			{Artifact: "server", Type: diff.Modified, Field: "chart.version", From: "8.9.1", To: "9.0.0"},
+			{Artifact: "server", Type: diff.Modified, Field: "dependsOn", To: "cache"},
+			{Artifact: "nested", Type: diff.Modified, Field: "profile.tag", From: "bitnami-nginx/v0.0.1", To: "bitnami-nginx/v0.0.2"},
+		}))
+	})
+
+	It("returns no changes for identical definitions", func() {
+		Expect(diff.Compare(from, from)).To(BeEmpty())
+	})
+
+	When("a version doesn't exist", func() {
+		It("errors", func() {
+			fakeCatalogManager.ShowStub = nil
+			fakeCatalogManager.ShowReturns(profilesv1.ProfileCatalogEntry{}, errors.New("not found"))
+			_, err := diff.Diff(context.Background(), cfg)
+			Expect(err).To(MatchError(`failed to get profile "nginx" in catalog "catalog" version "v0.1.0": not found`))
+		})
+	})
+
+	When("fetching a definition fails", func() {
+		It("errors", func() {
+			fakeFetcher.ProfileDefinitionReturnsOnCall(1, profilesv1.ProfileDefinition{}, errors.New("clone failed"))
+			_, err := diff.Diff(context.Background(), cfg)
+			Expect(err).To(MatchError(`failed to fetch definition of profile "nginx" version "v0.2.0": clone failed`))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"sync"
+
+	"github.com/weaveworks/pctl/pkg/diff"
+	"github.com/weaveworks/profiles/api/v1alpha1"
+)
+
+type FakeDefinitionFetcher struct {
+	ProfileDefinitionStub        func(string, string, string) (v1alpha1.ProfileDefinition, error)
+	profileDefinitionMutex       sync.RWMutex
+	profileDefinitionArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	profileDefinitionReturns struct {
+		result1 v1alpha1.ProfileDefinition
+		result2 error
+	}
+	profileDefinitionReturnsOnCall map[int]struct {
+		result1 v1alpha1.ProfileDefinition
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinition(arg1 string, arg2 string, arg3 string) (v1alpha1.ProfileDefinition, error) {
+	fake.profileDefinitionMutex.Lock()
+	ret, specificReturn := fake.profileDefinitionReturnsOnCall[len(fake.profileDefinitionArgsForCall)]
+	fake.profileDefinitionArgsForCall = append(fake.profileDefinitionArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ProfileDefinitionStub
+	fakeReturns := fake.profileDefinitionReturns
+	fake.recordInvocation("ProfileDefinition", []interface{}{arg1, arg2, arg3})
+	fake.profileDefinitionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinitionCallCount() int {
+	fake.profileDefinitionMutex.RLock()
+	defer fake.profileDefinitionMutex.RUnlock()
+	return len(fake.profileDefinitionArgsForCall)
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinitionCalls(stub func(string, string, string) (v1alpha1.ProfileDefinition, error)) {
+	fake.profileDefinitionMutex.Lock()
+	defer fake.profileDefinitionMutex.Unlock()
+	fake.ProfileDefinitionStub = stub
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinitionArgsForCall(i int) (string, string, string) {
+	fake.profileDefinitionMutex.RLock()
+	defer fake.profileDefinitionMutex.RUnlock()
+	argsForCall := fake.profileDefinitionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinitionReturns(result1 v1alpha1.ProfileDefinition, result2 error) {
+	fake.profileDefinitionMutex.Lock()
+	defer fake.profileDefinitionMutex.Unlock()
+	fake.ProfileDefinitionStub = nil
+	fake.profileDefinitionReturns = struct {
+		result1 v1alpha1.ProfileDefinition
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDefinitionFetcher) ProfileDefinitionReturnsOnCall(i int, result1 v1alpha1.ProfileDefinition, result2 error) {
+	fake.profileDefinitionMutex.Lock()
+	defer fake.profileDefinitionMutex.Unlock()
+	fake.ProfileDefinitionStub = nil
+	if fake.profileDefinitionReturnsOnCall == nil {
+		fake.profileDefinitionReturnsOnCall = make(map[int]struct {
+			result1 v1alpha1.ProfileDefinition
+			result2 error
+		})
+	}
+	fake.profileDefinitionReturnsOnCall[i] = struct {
+		result1 v1alpha1.ProfileDefinition
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDefinitionFetcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.profileDefinitionMutex.RLock()
+	defer fake.profileDefinitionMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeDefinitionFetcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ diff.DefinitionFetcher = new(FakeDefinitionFetcher)
@@ -0,0 +1,213 @@
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// defaultTokenUsername is the username sent with a token, which git hosts generally ignore.
+const defaultTokenUsername = "git"
+
+// Auth holds the credentials for the git repositories whose URL starts with URLPrefix. HTTPS repositories
+// authenticate with a token or a username and password, which requires git 2.31 or later when the git binary
+// is used, SSH repositories with a private key.
+type Auth struct {
+	URLPrefix string `yaml:"urlPrefix"`
+	// TokenEnv is the environment variable holding a token for HTTPS repositories.
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+	// Username is sent with the token of TokenEnv. Defaults to git.
+	Username string `yaml:"username,omitempty"`
+	// PasswordFile is a file containing <username>:<password> for HTTPS repositories.
+	PasswordFile string `yaml:"passwordFile,omitempty"`
+	// SSHKeyFile is the private key for SSH repositories.
+	SSHKeyFile string `yaml:"sshKeyFile,omitempty"`
+	// KnownHostsFile verifies the host keys of SSH repositories. Without SSHKeyFile, the keys of the ssh agent are used.
+	KnownHostsFile string `yaml:"knownHostsFile,omitempty"`
+}
+
+// credentials are the secrets and files an Auth refers to.
+type credentials struct {
+	username       string
+	password       string
+	sshKeyFile     string
+	knownHostsFile string
+}
+
+// AuthFor returns the credentials with the longest URL prefix matching url, or nil if there are none.
+func AuthFor(auths []Auth, url string) *Auth {
+	var match *Auth
+	for i := range auths {
+		if strings.HasPrefix(url, auths[i].URLPrefix) && (match == nil || len(auths[i].URLPrefix) > len(match.URLPrefix)) {
+			match = &auths[i]
+		}
+	}
+	return match
+}
+
+func (a *Auth) credentials() (credentials, error) {
+	if a.TokenEnv != "" && a.PasswordFile != "" {
+		return credentials{}, fmt.Errorf("credentials for %q can't have both a token and a password file", a.URLPrefix)
+	}
+	creds := credentials{
+		sshKeyFile:     expandHome(a.SSHKeyFile),
+		knownHostsFile: expandHome(a.KnownHostsFile),
+	}
+	if a.TokenEnv != "" {
+		creds.password = os.Getenv(a.TokenEnv)
+		if creds.password == "" {
+			return credentials{}, fmt.Errorf("environment variable %s with the token for %q is not set", a.TokenEnv, a.URLPrefix)
+		}
+		creds.username = a.Username
+		if creds.username == "" {
+			creds.username = defaultTokenUsername
+		}
+	}
+	if a.PasswordFile != "" {
+		content, err := ioutil.ReadFile(expandHome(a.PasswordFile))
+		if err != nil {
+			return credentials{}, fmt.Errorf("failed to read password file for %q: %w", a.URLPrefix, err)
+		}
+		parts := strings.SplitN(strings.TrimSpace(string(content)), ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return credentials{}, fmt.Errorf("password file %s for %q must contain <username>:<password>", a.PasswordFile, a.URLPrefix)
+		}
+		creds.username, creds.password = parts[0], parts[1]
+	}
+	return creds, nil
+}
+
+// cliAuthEnv returns the environment passing the credentials for url to the git binary. The password is passed
+// through the GIT_CONFIG_* variables of git 2.31 and later, so it doesn't show in the arguments of the process.
+// The header is added after the configuration the user may already pass with them.
+func cliAuthEnv(auths []Auth, url string) ([]string, error) {
+	a := AuthFor(auths, url)
+	if a == nil {
+		return nil, nil
+	}
+	creds, err := a.credentials()
+	if err != nil {
+		return nil, err
+	}
+	var env []string
+	if creds.password != "" {
+		count := 0
+		if value := os.Getenv("GIT_CONFIG_COUNT"); value != "" {
+			if count, err = strconv.Atoi(value); err != nil || count < 0 {
+				return nil, fmt.Errorf("invalid GIT_CONFIG_COUNT %q", value)
+			}
+		}
+		header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(creds.username+":"+creds.password))
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.extraHeader", count),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, header))
+	}
+	if creds.sshKeyFile != "" || creds.knownHostsFile != "" {
+		command := "ssh"
+		if creds.sshKeyFile != "" {
+			command += " -i " + shellQuote(creds.sshKeyFile) + " -o IdentitiesOnly=yes"
+		}
+		if creds.knownHostsFile != "" {
+			command += " -o UserKnownHostsFile=" + shellQuote(creds.knownHostsFile) + " -o StrictHostKeyChecking=yes"
+		}
+		env = append(env, "GIT_SSH_COMMAND="+command)
+	}
+	return env, nil
+}
+
+// checkConfigEnvVersion returns an error if the output of git version is older than 2.31. Older versions
+// silently ignore the GIT_CONFIG_* variables, so they would run without the credentials.
+func checkConfigEnvVersion(output string) error {
+	fields := strings.Fields(output)
+	if len(fields) < 3 {
+		return fmt.Errorf("unable to parse git version %q", strings.TrimSpace(output))
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return fmt.Errorf("unable to parse git version %q", fields[2])
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("unable to parse git version %q", fields[2])
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("unable to parse git version %q", fields[2])
+	}
+	if major < 2 || major == 2 && minor < 31 {
+		return fmt.Errorf("git %s doesn't support passing credentials in the environment, git 2.31 or later is required", fields[2])
+	}
+	return nil
+}
+
+// goGitAuth returns the go-git credentials for url, or nil if there are none.
+func goGitAuth(auths []Auth, url string) (transport.AuthMethod, error) {
+	a := AuthFor(auths, url)
+	if a == nil {
+		return nil, nil
+	}
+	creds, err := a.credentials()
+	if err != nil {
+		return nil, err
+	}
+	if creds.password != "" {
+		return &githttp.BasicAuth{Username: creds.username, Password: creds.password}, nil
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol != "ssh" || creds.sshKeyFile == "" && creds.knownHostsFile == "" {
+		return nil, nil
+	}
+	user := ep.User
+	if user == "" {
+		user = "git"
+	}
+	var (
+		auth   transport.AuthMethod
+		helper *gitssh.HostKeyCallbackHelper
+	)
+	if creds.sshKeyFile == "" {
+		// like the git binary, authenticate with the keys of the ssh agent and only verify the host keys.
+		agent, err := gitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the ssh agent for %q: %w", a.URLPrefix, err)
+		}
+		auth, helper = agent, &agent.HostKeyCallbackHelper
+	} else {
+		keys, err := gitssh.NewPublicKeysFromFile(user, creds.sshKeyFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh key for %q: %w", a.URLPrefix, err)
+		}
+		auth, helper = keys, &keys.HostKeyCallbackHelper
+	}
+	if creds.knownHostsFile != "" {
+		if helper.HostKeyCallback, err = gitssh.NewKnownHostsCallback(creds.knownHostsFile); err != nil {
+			return nil, fmt.Errorf("failed to read known hosts for %q: %w", a.URLPrefix, err)
+		}
+	}
+	return auth, nil
+}
+
+// expandHome replaces a leading ~ with the home directory of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -64,6 +64,31 @@ type FakeGit struct {
 	createBranchReturnsOnCall map[int]struct {
 		result1 error
 	}
+	FetchStub        func(string, string) error
+	fetchMutex       sync.RWMutex
+	fetchArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	fetchReturns struct {
+		result1 error
+	}
+	fetchReturnsOnCall map[int]struct {
+		result1 error
+	}
+	FetchRevisionStub        func(string, string, string) error
+	fetchRevisionMutex       sync.RWMutex
+	fetchRevisionArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	fetchRevisionReturns struct {
+		result1 error
+	}
+	fetchRevisionReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetDirectoryStub        func() string
 	getDirectoryMutex       sync.RWMutex
 	getDirectoryArgsForCall []struct {
@@ -139,6 +164,19 @@ type FakeGit struct {
 	removeAllReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RevisionStub        func(string) (string, error)
+	revisionMutex       sync.RWMutex
+	revisionArgsForCall []struct {
+		arg1 string
+	}
+	revisionReturns struct {
+		result1 string
+		result2 error
+	}
+	revisionReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -442,6 +480,131 @@ func (fake *FakeGit) CreateBranchReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeGit) Fetch(arg1 string, arg2 string) error {
+	fake.fetchMutex.Lock()
+	ret, specificReturn := fake.fetchReturnsOnCall[len(fake.fetchArgsForCall)]
+	fake.fetchArgsForCall = append(fake.fetchArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.FetchStub
+	fakeReturns := fake.fetchReturns
+	fake.recordInvocation("Fetch", []interface{}{arg1, arg2})
+	fake.fetchMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeGit) FetchCallCount() int {
+	fake.fetchMutex.RLock()
+	defer fake.fetchMutex.RUnlock()
+	return len(fake.fetchArgsForCall)
+}
+
+func (fake *FakeGit) FetchCalls(stub func(string, string) error) {
+	fake.fetchMutex.Lock()
+	defer fake.fetchMutex.Unlock()
+	fake.FetchStub = stub
+}
+
+func (fake *FakeGit) FetchArgsForCall(i int) (string, string) {
+	fake.fetchMutex.RLock()
+	defer fake.fetchMutex.RUnlock()
+	argsForCall := fake.fetchArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeGit) FetchReturns(result1 error) {
+	fake.fetchMutex.Lock()
+	defer fake.fetchMutex.Unlock()
+	fake.FetchStub = nil
+	fake.fetchReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchReturnsOnCall(i int, result1 error) {
+	fake.fetchMutex.Lock()
+	defer fake.fetchMutex.Unlock()
+	fake.FetchStub = nil
+	if fake.fetchReturnsOnCall == nil {
+		fake.fetchReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.fetchReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchRevision(arg1 string, arg2 string, arg3 string) error {
+	fake.fetchRevisionMutex.Lock()
+	ret, specificReturn := fake.fetchRevisionReturnsOnCall[len(fake.fetchRevisionArgsForCall)]
+	fake.fetchRevisionArgsForCall = append(fake.fetchRevisionArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.FetchRevisionStub
+	fakeReturns := fake.fetchRevisionReturns
+	fake.recordInvocation("FetchRevision", []interface{}{arg1, arg2, arg3})
+	fake.fetchRevisionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeGit) FetchRevisionCallCount() int {
+	fake.fetchRevisionMutex.RLock()
+	defer fake.fetchRevisionMutex.RUnlock()
+	return len(fake.fetchRevisionArgsForCall)
+}
+
+func (fake *FakeGit) FetchRevisionCalls(stub func(string, string, string) error) {
+	fake.fetchRevisionMutex.Lock()
+	defer fake.fetchRevisionMutex.Unlock()
+	fake.FetchRevisionStub = stub
+}
+
+func (fake *FakeGit) FetchRevisionArgsForCall(i int) (string, string, string) {
+	fake.fetchRevisionMutex.RLock()
+	defer fake.fetchRevisionMutex.RUnlock()
+	argsForCall := fake.fetchRevisionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGit) FetchRevisionReturns(result1 error) {
+	fake.fetchRevisionMutex.Lock()
+	defer fake.fetchRevisionMutex.Unlock()
+	fake.FetchRevisionStub = nil
+	fake.fetchRevisionReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchRevisionReturnsOnCall(i int, result1 error) {
+	fake.fetchRevisionMutex.Lock()
+	defer fake.fetchRevisionMutex.Unlock()
+	fake.FetchRevisionStub = nil
+	if fake.fetchRevisionReturnsOnCall == nil {
+		fake.fetchRevisionReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.fetchRevisionReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeGit) GetDirectory() string {
 	fake.getDirectoryMutex.Lock()
 	ret, specificReturn := fake.getDirectoryReturnsOnCall[len(fake.getDirectoryArgsForCall)]
@@ -827,6 +990,70 @@ func (fake *FakeGit) RemoveAllReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeGit) Revision(arg1 string) (string, error) {
+	fake.revisionMutex.Lock()
+	ret, specificReturn := fake.revisionReturnsOnCall[len(fake.revisionArgsForCall)]
+	fake.revisionArgsForCall = append(fake.revisionArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.RevisionStub
+	fakeReturns := fake.revisionReturns
+	fake.recordInvocation("Revision", []interface{}{arg1})
+	fake.revisionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGit) RevisionCallCount() int {
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
+	return len(fake.revisionArgsForCall)
+}
+
+func (fake *FakeGit) RevisionCalls(stub func(string) (string, error)) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = stub
+}
+
+func (fake *FakeGit) RevisionArgsForCall(i int) string {
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
+	argsForCall := fake.revisionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGit) RevisionReturns(result1 string, result2 error) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = nil
+	fake.revisionReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) RevisionReturnsOnCall(i int, result1 string, result2 error) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = nil
+	if fake.revisionReturnsOnCall == nil {
+		fake.revisionReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.revisionReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -840,6 +1067,10 @@ func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	defer fake.commitMutex.RUnlock()
 	fake.createBranchMutex.RLock()
 	defer fake.createBranchMutex.RUnlock()
+	fake.fetchMutex.RLock()
+	defer fake.fetchMutex.RUnlock()
+	fake.fetchRevisionMutex.RLock()
+	defer fake.fetchRevisionMutex.RUnlock()
 	fake.getDirectoryMutex.RLock()
 	defer fake.getDirectoryMutex.RUnlock()
 	fake.hasChangesMutex.RLock()
@@ -854,6 +1085,8 @@ func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	defer fake.pushMutex.RUnlock()
 	fake.removeAllMutex.RLock()
 	defer fake.removeAllMutex.RUnlock()
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/weaveworks/pctl/pkg/log"
 	"github.com/weaveworks/pctl/pkg/runner"
@@ -31,6 +32,12 @@ type Git interface {
 	Push() error
 	// Clone will take a repo location and clone it into a given folder.
 	Clone(repo, branch, location string) error
+	// Fetch updates a clone made by Clone to the latest commit of its branch or tag.
+	Fetch(branch, location string) error
+	// FetchRevision checks out a commit of the branch or tag in a clone made by Clone, fetching it if needed.
+	FetchRevision(branch, revision, location string) error
+	// Revision returns the commit checked out in the repository at location.
+	Revision(location string) (string, error)
 	// Init will create a git repository
 	Init() error
 	// Merge will merge the branch into the currently checked out branch
@@ -52,12 +59,18 @@ type CLIGitConfig struct {
 	Message   string
 	Base      string
 	Quiet     bool
+	// Auth holds the credentials for cloning, fetching and pushing, by repository URL prefix.
+	Auth []Auth
 }
 
 // CLIGit is a new command line based Git.
 type CLIGit struct {
 	CLIGitConfig
 	Runner runner.Runner
+	// versionOnce checks once whether git reads credentials from the environment, the result is in versionErr.
+	// Nested profiles are cloned concurrently with the same CLIGit.
+	versionOnce sync.Once
+	versionErr  error
 }
 
 // NewCLIGit creates a new command line based Git.
@@ -103,12 +116,89 @@ func (g *CLIGit) Clone(repo, branch, location string) error {
 		repo,
 		location,
 	}
-	if err := g.runGitCmd(args...); err != nil {
+	if err := g.runGitCmdFor(repo, args...); err != nil {
 		return fmt.Errorf("failed to run clone: %w\n", err)
 	}
 	return nil
 }
 
+// Fetch updates the clone in location to the latest commit of the branch or tag, discarding local changes.
+func (g *CLIGit) Fetch(branch, location string) error {
+	url, err := g.remoteURL(location, "origin")
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	args := []string{
+		"--git-dir", filepath.Join(location, ".git"),
+		"--work-tree", location,
+		"fetch",
+		"--depth",
+		"1",
+		"origin",
+		branch,
+	}
+	if err := g.runGitCmdFor(url, args...); err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	args = []string{
+		"--git-dir", filepath.Join(location, ".git"),
+		"--work-tree", location,
+		"reset",
+		"--hard",
+		"FETCH_HEAD",
+	}
+	if err := g.runGitCmd(args...); err != nil {
+		return fmt.Errorf("failed to reset to fetched branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// FetchRevision checks out the commit revision of the branch or tag in the clone in location. The commit is
+// fetched by its SHA if the clone doesn't have it, or with the whole history of the branch if the server
+// doesn't allow that.
+func (g *CLIGit) FetchRevision(branch, revision, location string) error {
+	gitDir := filepath.Join(location, ".git")
+	if _, err := g.Runner.Run(gitCmd, "--git-dir", gitDir, "cat-file", "-e", revision+"^{commit}"); err != nil {
+		url, err := g.remoteURL(location, "origin")
+		if err != nil {
+			return fmt.Errorf("failed to fetch revision %s: %w", revision, err)
+		}
+		if _, err := g.gitOutputFor(url, "--git-dir", gitDir, "--work-tree", location, "fetch", "--depth", "1", "origin", revision); err != nil {
+			args := []string{
+				"--git-dir", gitDir,
+				"--work-tree", location,
+				"fetch",
+				"--unshallow",
+				"origin",
+				branch,
+			}
+			if err := g.runGitCmdFor(url, args...); err != nil {
+				return fmt.Errorf("failed to fetch revision %s: %w", revision, err)
+			}
+		}
+	}
+	args := []string{
+		"--git-dir", gitDir,
+		"--work-tree", location,
+		"reset",
+		"--hard",
+		revision,
+	}
+	if err := g.runGitCmd(args...); err != nil {
+		return fmt.Errorf("failed to check out revision %s of %s: %w", revision, branch, err)
+	}
+	return nil
+}
+
+// Revision returns the commit checked out in the repository in location.
+func (g *CLIGit) Revision(location string) (string, error) {
+	out, err := g.Runner.Run(gitCmd, "--git-dir", filepath.Join(location, ".git"), "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get revision of %s: %w", location, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // Commit all changes.
 func (g *CLIGit) Commit() error {
 	hasChanges, err := g.HasChanges()
@@ -179,6 +269,10 @@ func (g *CLIGit) HasChanges() (bool, error) {
 // Push will push to a remote.
 func (g *CLIGit) Push() error {
 	g.printf("pushing to remote\n")
+	url, err := g.remoteURL(g.Directory, g.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
+	}
 	args := []string{
 		"--git-dir", filepath.Join(g.Directory, ".git"),
 		"--work-tree", g.Directory,
@@ -186,7 +280,7 @@ func (g *CLIGit) Push() error {
 		g.Remote,
 		g.Branch,
 	}
-	if err := g.runGitCmd(args...); err != nil {
+	if err := g.runGitCmdFor(url, args...); err != nil {
 		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
 	}
 	return nil
@@ -217,6 +311,73 @@ func (g *CLIGit) runGitCmd(args ...string) error {
 	return err
 }
 
+// authEnv returns the environment passing the credentials for url to git, checking first that git reads them.
+func (g *CLIGit) authEnv(url string) ([]string, error) {
+	env, err := cliAuthEnv(g.Auth, url)
+	if err != nil || len(env) == 0 || !strings.HasPrefix(env[0], "GIT_CONFIG_COUNT=") {
+		return env, err
+	}
+	g.versionOnce.Do(func() {
+		out, err := g.Runner.Run(gitCmd, "version")
+		if err != nil {
+			g.versionErr = fmt.Errorf("failed to run git version: %w", err)
+			return
+		}
+		g.versionErr = checkConfigEnvVersion(string(out))
+	})
+	if g.versionErr != nil {
+		return nil, g.versionErr
+	}
+	return env, nil
+}
+
+// runGitCmdFor runs a git command against the repository at url with the credentials configured for it.
+func (g *CLIGit) runGitCmdFor(url string, args ...string) error {
+	env, err := g.authEnv(url)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		return g.runGitCmd(args...)
+	}
+	out, err := g.Runner.RunWithEnv(env, gitCmd, args...)
+	if err != nil {
+		log.Failuref("failed to run git with output: %s\n", string(out))
+	}
+	return err
+}
+
+// gitOutputFor is runGitCmdFor for commands which are allowed to fail, it returns their output without logging it.
+func (g *CLIGit) gitOutputFor(url string, args ...string) ([]byte, error) {
+	env, err := g.authEnv(url)
+	if err != nil {
+		return nil, err
+	}
+	if env == nil {
+		return g.Runner.Run(gitCmd, args...)
+	}
+	return g.Runner.RunWithEnv(env, gitCmd, args...)
+}
+
+// remoteURL returns the url of the remote of the repository in dir. It's only looked up when credentials are
+// configured, as it's only needed to find those.
+func (g *CLIGit) remoteURL(dir, remote string) (string, error) {
+	if len(g.Auth) == 0 {
+		return "", nil
+	}
+	args := []string{
+		"--git-dir", filepath.Join(dir, ".git"),
+		"remote",
+		"get-url",
+		remote,
+	}
+	out, err := g.Runner.Run(gitCmd, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get url of remote %s: %w", remote, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // Init will initalise the git repository
 func (g *CLIGit) Init() error {
 	args := []string{
@@ -109,6 +109,88 @@ var _ = Describe("git", func() {
 		})
 	})
 
+	Context("Fetch", func() {
+		When("normal flow operations", func() {
+			It("fetches the branch and resets the clone to it", func() {
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				Expect(g.Fetch("main", "location")).To(Succeed())
+				Expect(runner.RunCallCount()).To(Equal(2))
+				arg, args := runner.RunArgsForCall(0)
+				Expect(arg).To(Equal("git"))
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "fetch", "--depth", "1", "origin", "main"}))
+				arg, args = runner.RunArgsForCall(1)
+				Expect(arg).To(Equal("git"))
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "reset", "--hard", "FETCH_HEAD"}))
+			})
+		})
+		When("the flow is disrupted with errors", func() {
+			It("returns a sensible wrapped error", func() {
+				runner.RunReturns([]byte("nope"), errors.New("nope"))
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				err := g.Fetch("main", "location")
+				Expect(err).To(MatchError("failed to run fetch: nope"))
+				Expect(runner.RunCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("FetchRevision", func() {
+		When("the clone has the commit", func() {
+			It("checks it out without fetching", func() {
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				Expect(g.FetchRevision("main", "abc123", "location")).To(Succeed())
+				Expect(runner.RunCallCount()).To(Equal(2))
+				_, args := runner.RunArgsForCall(0)
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "cat-file", "-e", "abc123^{commit}"}))
+				_, args = runner.RunArgsForCall(1)
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "reset", "--hard", "abc123"}))
+			})
+		})
+		When("the clone doesn't have the commit", func() {
+			It("fetches it by its SHA", func() {
+				runner.RunReturnsOnCall(0, nil, errors.New("missing"))
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				Expect(g.FetchRevision("main", "abc123", "location")).To(Succeed())
+				Expect(runner.RunCallCount()).To(Equal(3))
+				_, args := runner.RunArgsForCall(1)
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "fetch", "--depth", "1", "origin", "abc123"}))
+				_, args = runner.RunArgsForCall(2)
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "reset", "--hard", "abc123"}))
+			})
+
+			It("fetches the history of the branch if the server doesn't allow fetching the SHA", func() {
+				runner.RunReturnsOnCall(0, nil, errors.New("missing"))
+				runner.RunReturnsOnCall(1, nil, errors.New("not our ref"))
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				Expect(g.FetchRevision("main", "abc123", "location")).To(Succeed())
+				Expect(runner.RunCallCount()).To(Equal(4))
+				_, args := runner.RunArgsForCall(2)
+				Expect(args).To(Equal([]string{"--git-dir", "location/.git", "--work-tree", "location", "fetch", "--unshallow", "origin", "main"}))
+			})
+		})
+		When("the flow is disrupted with errors", func() {
+			It("returns a sensible wrapped error", func() {
+				runner.RunReturns([]byte("nope"), errors.New("nope"))
+				g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+				err := g.FetchRevision("main", "abc123", "location")
+				Expect(err).To(MatchError("failed to fetch revision abc123: nope"))
+				Expect(runner.RunCallCount()).To(Equal(3))
+			})
+		})
+	})
+
+	Context("Revision", func() {
+		It("returns the commit checked out", func() {
+			runner.RunReturns([]byte("abc123\n"), nil)
+			g := git.NewCLIGit(git.CLIGitConfig{}, runner)
+			revision, err := g.Revision("location")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revision).To(Equal("abc123"))
+			_, args := runner.RunArgsForCall(0)
+			Expect(args).To(Equal([]string{"--git-dir", "location/.git", "rev-parse", "HEAD"}))
+		})
+	})
+
 	Context("Push", func() {
 		When("normal flow operations", func() {
 			It("pushes changes to a remote", func() {
@@ -0,0 +1,598 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+const (
+	defaultAuthorName  = "pctl"
+	defaultAuthorEmail = "pctl@localhost"
+)
+
+// GoGit is an in-process Git which doesn't need a git binary. It uses the same configuration as CLIGit.
+type GoGit struct {
+	CLIGitConfig
+}
+
+// NewGoGit creates a new in-process Git.
+func NewGoGit(cfg CLIGitConfig) *GoGit {
+	return &GoGit{
+		CLIGitConfig: cfg,
+	}
+}
+
+// Make sure GoGit implements all the required methods.
+var _ Git = &GoGit{}
+
+// GetDirectory returns the git directory.
+func (g *GoGit) GetDirectory() string {
+	return g.Directory
+}
+
+// Clone will take a repo location and clone it into a given folder. The branch can also be a tag.
+func (g *GoGit) Clone(repo, branch, location string) error {
+	auth, err := goGitAuth(g.Auth, repo)
+	if err != nil {
+		return fmt.Errorf("failed to run clone: %w", err)
+	}
+	ref, err := remoteReference(repo, branch, auth)
+	if err != nil {
+		return fmt.Errorf("failed to run clone: %w", err)
+	}
+	_, err = gogit.PlainClone(location, false, &gogit.CloneOptions{
+		URL:           repo,
+		Auth:          auth,
+		ReferenceName: ref,
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run clone: %w", err)
+	}
+	return nil
+}
+
+// Fetch updates the clone in location to the latest commit of the branch or tag, discarding local changes.
+func (g *GoGit) Fetch(branch, location string) error {
+	r, err := gogit.PlainOpen(location)
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	auth, err := goGitAuth(g.Auth, remote.Config().URLs[0])
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	ref, err := remoteReference(remote.Config().URLs[0], branch, auth)
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	err = r.Fetch(&gogit.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + ref)},
+		Depth:      1,
+		Tags:       gogit.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: *hash, Mode: gogit.HardReset}); err != nil {
+		return fmt.Errorf("failed to run fetch: %w", err)
+	}
+	return nil
+}
+
+// FetchRevision checks out the commit revision of the branch or tag in the clone in location. The commit is
+// fetched by its SHA if the clone doesn't have it, or with the whole history of the branch if the server
+// doesn't allow that.
+func (g *GoGit) FetchRevision(branch, revision, location string) error {
+	r, err := gogit.PlainOpen(location)
+	if err != nil {
+		return fmt.Errorf("failed to fetch revision %s: %w", revision, err)
+	}
+	hash := plumbing.NewHash(revision)
+	if _, err := r.CommitObject(hash); err != nil {
+		if err := fetchRevision(r, g.Auth, branch, revision); err != nil {
+			return fmt.Errorf("failed to fetch revision %s: %w", revision, err)
+		}
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to check out revision %s of %s: %w", revision, branch, err)
+	}
+	if err := w.Reset(&gogit.ResetOptions{Commit: hash, Mode: gogit.HardReset}); err != nil {
+		return fmt.Errorf("failed to check out revision %s of %s: %w", revision, branch, err)
+	}
+	return nil
+}
+
+// fetchRevision fetches the commit revision into r, by its SHA or with the whole history of the branch.
+func fetchRevision(r *gogit.Repository, auths []Auth, branch, revision string) error {
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return err
+	}
+	url := remote.Config().URLs[0]
+	auth, err := goGitAuth(auths, url)
+	if err != nil {
+		return err
+	}
+	err = r.Fetch(&gogit.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		// go-git needs a destination for the commit, it's checked out rather than used by its reference.
+		RefSpecs: []config.RefSpec{config.RefSpec(revision + ":refs/pctl/revision")},
+		Depth:    1,
+		Tags:     gogit.NoTags,
+	})
+	if err == nil || errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	ref, err := remoteReference(url, branch, auth)
+	if err != nil {
+		return err
+	}
+	err = r.Fetch(&gogit.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + ref)},
+		// deepens the shallow clone to the whole history.
+		Depth: math.MaxInt32,
+		Tags:  gogit.NoTags,
+		Force: true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return err
+	}
+	if _, err := r.CommitObject(plumbing.NewHash(revision)); err != nil {
+		return fmt.Errorf("commit not found on %s", branch)
+	}
+	return nil
+}
+
+// Revision returns the commit checked out in the repository in location.
+func (g *GoGit) Revision(location string) (string, error) {
+	r, err := gogit.PlainOpen(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to get revision of %s: %w", location, err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get revision of %s: %w", location, err)
+	}
+	return head.Hash().String(), nil
+}
+
+// remoteReference returns the reference of the branch or tag in the remote repository.
+func remoteReference(repo, branchOrTag string, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	remote := gogit.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repo},
+	})
+	refs, err := remote.List(&gogit.ListOptions{Auth: auth})
+	if err != nil {
+		return "", fmt.Errorf("failed to list references of %q: %w", repo, err)
+	}
+	branch, tag := plumbing.NewBranchReferenceName(branchOrTag), plumbing.NewTagReferenceName(branchOrTag)
+	var isTag bool
+	for _, ref := range refs {
+		switch ref.Name() {
+		case branch:
+			return branch, nil
+		case tag:
+			isTag = true
+		}
+	}
+	if isTag {
+		return tag, nil
+	}
+	return "", fmt.Errorf("remote branch or tag %q not found in %q", branchOrTag, repo)
+}
+
+// Init will initialise the git repository with a main branch.
+func (g *GoGit) Init() error {
+	r, err := gogit.PlainInit(g.Directory, false)
+	if err != nil {
+		return fmt.Errorf("failed to init: %w", err)
+	}
+	if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))); err != nil {
+		return fmt.Errorf("failed to init: %w", err)
+	}
+	return nil
+}
+
+// IsRepository returns whether a location is a git repository or not.
+func (g *GoGit) IsRepository() error {
+	_, err := gogit.PlainOpen(g.Directory)
+	return err
+}
+
+// HasChanges returns whether a location has uncommitted changes or not.
+func (g *GoGit) HasChanges() (bool, error) {
+	_, w, err := g.open()
+	if err != nil {
+		return false, fmt.Errorf("failed to check if there are changes: %w", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to check if there are changes: %w", err)
+	}
+	return !status.IsClean(), nil
+}
+
+// Add will add any changes in dir, including removed files.
+func (g *GoGit) Add(dir string) error {
+	g.printf("adding unstaged changes\n")
+	_, w, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to run add: %w", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return fmt.Errorf("failed to run add: %w", err)
+	}
+	prefix := filepath.ToSlash(filepath.Clean(dir))
+	for path, s := range status {
+		if s.Worktree == gogit.Unmodified {
+			continue
+		}
+		if prefix != "." && path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if _, err := w.Add(path); err != nil {
+			return fmt.Errorf("failed to run add: %w", err)
+		}
+	}
+	return nil
+}
+
+// Commit all changes.
+func (g *GoGit) Commit() error {
+	hasChanges, err := g.HasChanges()
+	if err != nil {
+		return fmt.Errorf("failed to detect if repository has changes: %w", err)
+	}
+	if !hasChanges {
+		return nil
+	}
+	r, w, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to run commit: %w", err)
+	}
+	if _, err := w.Commit(g.Message, &gogit.CommitOptions{All: true, Author: author(r)}); err != nil {
+		return fmt.Errorf("failed to run commit: %w", err)
+	}
+	return nil
+}
+
+// author returns the user configured for git, or a default one in environments without any configuration.
+func author(r *gogit.Repository) *object.Signature {
+	signature := &object.Signature{
+		Name:  defaultAuthorName,
+		Email: defaultAuthorEmail,
+		When:  time.Now(),
+	}
+	cfg, err := r.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return signature
+	}
+	if cfg.User.Name != "" && cfg.User.Email != "" {
+		signature.Name = cfg.User.Name
+		signature.Email = cfg.User.Email
+	}
+	return signature
+}
+
+// CreateBranch creates a branch if it differs from the base, keeping the changes of the working tree.
+func (g *GoGit) CreateBranch(branch string) error {
+	if branch == g.Base {
+		return nil
+	}
+	g.printf("creating new branch\n")
+	_, w, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to create new branch %s: %w", branch, err)
+	}
+	err = w.Checkout(&gogit.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+		Create: true,
+		Keep:   true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create new branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Checkout the target branch.
+func (g *GoGit) Checkout(branch string) error {
+	_, w, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to checkout branch %s: %w", branch, err)
+	}
+	if err := w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch)}); err != nil {
+		return fmt.Errorf("failed to checkout branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Push will push to a remote.
+func (g *GoGit) Push() error {
+	g.printf("pushing to remote\n")
+	r, _, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
+	}
+	remote, err := r.Remote(g.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
+	}
+	auth, err := goGitAuth(g.Auth, remote.Config().URLs[0])
+	if err != nil {
+		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
+	}
+	ref := plumbing.NewBranchReferenceName(g.Branch)
+	err = r.Push(&gogit.PushOptions{
+		RemoteName: g.Remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(ref + ":" + ref)},
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push changes to remote %s with branch %s: %w", g.Remote, g.Branch, err)
+	}
+	return nil
+}
+
+// RemoveAll files from git repository.
+func (g *GoGit) RemoveAll() error {
+	if err := g.Add("."); err != nil {
+		return err
+	}
+	r, w, err := g.open()
+	if err != nil {
+		return fmt.Errorf("failed to run rm: %w", err)
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to run rm: %w", err)
+	}
+	var paths []string
+	for _, e := range idx.Entries {
+		paths = append(paths, e.Name)
+	}
+	for _, p := range paths {
+		if _, err := w.Remove(p); err != nil {
+			return fmt.Errorf("failed to run rm: %w", err)
+		}
+	}
+	return nil
+}
+
+// Merge will merge the branch into the currently checked out branch.
+// It returns the list of files containing conflicts when a conflict occurs.
+func (g *GoGit) Merge(branch string) ([]string, error) {
+	conflicts, err := g.MergeConflicts(branch)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, c := range conflicts {
+		files = append(files, c.Path)
+	}
+	return files, nil
+}
+
+// MergeConflicts three-way merges the branch into the currently checked out branch and commits the result.
+// When conflicts occur, the conflicting files are left in the working tree with conflict markers, nothing is
+// committed, and the conflicts are returned.
+func (g *GoGit) MergeConflicts(branch string) ([]Conflict, error) {
+	r, w, err := g.open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+	ours, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+	theirsRef, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: branch %s: %w", branch, err)
+	}
+	theirs, err := r.CommitObject(theirsRef.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+
+	if isAncestor, err := theirs.IsAncestor(ours); err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	} else if isAncestor || theirs.Hash == ours.Hash {
+		return nil, nil
+	}
+	if isAncestor, err := ours.IsAncestor(theirs); err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	} else if isAncestor {
+		// fast-forward
+		if err := r.Storer.SetReference(plumbing.NewHashReference(head.Name(), theirs.Hash)); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+		if err := w.Reset(&gogit.ResetOptions{Commit: theirs.Hash, Mode: gogit.HardReset}); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+		return nil, nil
+	}
+
+	var base *object.Commit
+	baseFiles := map[string][]byte{}
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+	if len(bases) > 0 {
+		base = bases[0]
+		if baseFiles, err = commitFiles(base); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+	}
+	ourFiles, err := commitFiles(ours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+	theirFiles, err := commitFiles(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run merge: %w", err)
+	}
+
+	merged, conflicts := mergeFiles(baseFiles, ourFiles, theirFiles, "HEAD", branch)
+	for path, content := range merged {
+		filename := filepath.Join(g.Directory, filepath.FromSlash(path))
+		if content == nil {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to run merge: %w", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+		mode, err := mergedFileMode(filename, base, theirs, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+		if err := ioutil.WriteFile(filename, content, mode); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+		// WriteFile only sets the mode of new files.
+		if err := os.Chmod(filename, mode); err != nil {
+			return nil, fmt.Errorf("failed to run merge: %w", err)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	if err := g.Add("."); err != nil {
+		return nil, err
+	}
+	_, err = w.Commit(fmt.Sprintf("Merge branch '%s'", branch), &gogit.CommitOptions{
+		Author:  author(r),
+		Parents: []plumbing.Hash{ours.Hash, theirs.Hash},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil, nil
+}
+
+// commitFiles returns the content of the files of a commit, keyed by their slash separated path.
+func commitFiles(c *object.Commit) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	iter, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		files[f.Name] = []byte(content)
+		return nil
+	})
+	return files, err
+}
+
+// mergedFileMode returns the mode of a merged file. Their mode is used when the mode of our file is the
+// mode it had in the merge base, our mode otherwise, the same way the content is merged.
+func mergedFileMode(filename string, base, theirs *object.Commit, path string) (os.FileMode, error) {
+	theirMode, err := commitFileMode(theirs, path)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		if theirMode == 0 {
+			return 0644, nil
+		}
+		return theirMode, nil
+	} else if err != nil {
+		return 0, err
+	}
+	ourMode := info.Mode().Perm()
+	baseMode, err := commitFileMode(base, path)
+	if err != nil {
+		return 0, err
+	}
+	if theirMode != 0 && ourMode == baseMode {
+		return theirMode, nil
+	}
+	return ourMode, nil
+}
+
+// commitFileMode returns the mode of the file at path in the commit, or zero if the commit doesn't have it.
+func commitFileMode(c *object.Commit, path string) (os.FileMode, error) {
+	if c == nil {
+		return 0, nil
+	}
+	f, err := c.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	mode, err := f.Mode.ToOSFileMode()
+	if err != nil {
+		return 0, err
+	}
+	return mode.Perm(), nil
+}
+
+func (g *GoGit) open() (*gogit.Repository, *gogit.Worktree, error) {
+	r, err := gogit.PlainOpen(g.Directory)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, w, nil
+}
+
+func (g *GoGit) printf(format string, a ...interface{}) {
+	if !g.Quiet {
+		log.Actionf(format, a...)
+	}
+}
@@ -0,0 +1,242 @@
+package git_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/git"
+)
+
+var _ = Describe("GoGit", func() {
+	var (
+		dir string
+		g   *git.GoGit
+	)
+
+	write := func(name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+	commit := func() {
+		Expect(g.Add(".")).To(Succeed())
+		Expect(g.Commit()).To(Succeed())
+		hasChanges, err := g.HasChanges()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasChanges).To(BeFalse())
+	}
+	// branches creates a main branch and branches from it with the content written by each of the funcs.
+	branches := func(main, ours, theirs func()) {
+		Expect(g.Init()).To(Succeed())
+		main()
+		commit()
+		Expect(g.CreateBranch("theirs")).To(Succeed())
+		theirs()
+		commit()
+		Expect(g.Checkout("main")).To(Succeed())
+		Expect(g.CreateBranch("ours")).To(Succeed())
+		ours()
+		commit()
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "go-git")
+		Expect(err).NotTo(HaveOccurred())
+		g = git.NewGoGit(git.CLIGitConfig{
+			Directory: dir,
+			Message:   "commit",
+			Quiet:     true,
+		})
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("initialises a repository and commits changes", func() {
+		Expect(g.IsRepository()).NotTo(Succeed())
+		Expect(g.Init()).To(Succeed())
+		Expect(g.IsRepository()).To(Succeed())
+		write("a/file.yaml", "a: 1\n")
+		hasChanges, err := g.HasChanges()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasChanges).To(BeTrue())
+		commit()
+
+		By("staging removed files")
+		Expect(os.Remove(filepath.Join(dir, "a/file.yaml"))).To(Succeed())
+		commit()
+	})
+
+	It("removes every file", func() {
+		Expect(g.Init()).To(Succeed())
+		write("a/file.yaml", "a: 1\n")
+		write("b.yaml", "b: 1\n")
+		commit()
+		Expect(g.RemoveAll()).To(Succeed())
+		_, err := os.Stat(filepath.Join(dir, "a/file.yaml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(filepath.Join(dir, "b.yaml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(g.Commit()).To(Succeed())
+	})
+
+	It("checks out an earlier commit of a shallow clone", func() {
+		Expect(g.Init()).To(Succeed())
+		write("values.yaml", "a: 1\n")
+		commit()
+		first, err := g.Revision(dir)
+		Expect(err).NotTo(HaveOccurred())
+		write("values.yaml", "a: 2\n")
+		commit()
+
+		clone, err := ioutil.TempDir("", "go-git-clone")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(clone)
+		Expect(g.Clone("file://"+dir, "main", clone)).To(Succeed())
+		Expect(g.FetchRevision("main", first, clone)).To(Succeed())
+		revision, err := g.Revision(clone)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revision).To(Equal(first))
+		content, err := ioutil.ReadFile(filepath.Join(clone, "values.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("a: 1\n"))
+	})
+
+	Context("Merge", func() {
+		It("merges changes to different lines of the same file", func() {
+			branches(func() {
+				write("values.yaml", "a: 1\nb: 1\nc: 1\n")
+				write("removed.yaml", "removed: true\n")
+			}, func() {
+				write("values.yaml", "a: 2\nb: 1\nc: 1\n")
+				write("ours.yaml", "ours: true\n")
+			}, func() {
+				write("values.yaml", "a: 1\nb: 1\nc: 3\n")
+				Expect(os.Remove(filepath.Join(dir, "removed.yaml"))).To(Succeed())
+			})
+
+			conflicts, err := g.MergeConflicts("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(BeEmpty())
+			Expect(read("values.yaml")).To(Equal("a: 2\nb: 1\nc: 3\n"))
+			Expect(read("ours.yaml")).To(Equal("ours: true\n"))
+			_, err = os.Stat(filepath.Join(dir, "removed.yaml"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			hasChanges, err := g.HasChanges()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hasChanges).To(BeFalse())
+		})
+
+		It("returns the conflicts and marks them in the files", func() {
+			branches(func() {
+				write("values.yaml", "a: 1\nb: 1\nc: 1\n")
+				write("removed.yaml", "removed: true\n")
+			}, func() {
+				write("values.yaml", "a: 2\nb: 1\nc: 1\n")
+				write("removed.yaml", "removed: false\n")
+			}, func() {
+				write("values.yaml", "a: 3\nb: 1\nc: 1\n")
+				Expect(os.Remove(filepath.Join(dir, "removed.yaml"))).To(Succeed())
+			})
+
+			conflicts, err := g.MergeConflicts("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(Equal([]git.Conflict{
+				{Path: "removed.yaml"},
+				{Path: "values.yaml", Hunks: 1},
+			}))
+			Expect(read("values.yaml")).To(Equal("<<<<<<< HEAD\na: 2\n=======\na: 3\n>>>>>>> theirs\nb: 1\nc: 1\n"))
+			Expect(read("removed.yaml")).To(Equal("removed: false\n"))
+		})
+
+		It("keeps the mode of the merged files", func() {
+			mode := func(name string) os.FileMode {
+				info, err := os.Stat(filepath.Join(dir, name))
+				Expect(err).NotTo(HaveOccurred())
+				return info.Mode().Perm()
+			}
+			branches(func() {
+				write("hook.sh", "a\nb\nc\n")
+				Expect(os.Chmod(filepath.Join(dir, "hook.sh"), 0755)).To(Succeed())
+			}, func() {
+				write("hook.sh", "a2\nb\nc\n")
+			}, func() {
+				write("hook.sh", "a\nb\nc2\n")
+				write("added.sh", "added\n")
+				Expect(os.Chmod(filepath.Join(dir, "added.sh"), 0755)).To(Succeed())
+			})
+
+			conflicts, err := g.MergeConflicts("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(BeEmpty())
+			Expect(read("hook.sh")).To(Equal("a2\nb\nc2\n"))
+			Expect(mode("hook.sh")).To(Equal(os.FileMode(0755)))
+			Expect(mode("added.sh")).To(Equal(os.FileMode(0755)))
+		})
+
+		It("reports changed binary files as conflicts and keeps ours", func() {
+			branches(func() {
+				write("chart.tgz", "\x1f\x8b\x00base\n")
+			}, func() {
+				write("chart.tgz", "\x1f\x8b\x00ours\n")
+			}, func() {
+				write("chart.tgz", "\x1f\x8b\x00theirs\n")
+			})
+
+			conflicts, err := g.MergeConflicts("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(Equal([]git.Conflict{{Path: "chart.tgz"}}))
+			Expect(read("chart.tgz")).To(Equal("\x1f\x8b\x00ours\n"))
+		})
+
+		It("merges changes to large files", func() {
+			lines := func(changes map[int]string) string {
+				var b strings.Builder
+				for i := 0; i < 50000; i++ {
+					if line, ok := changes[i]; ok {
+						b.WriteString(line)
+						continue
+					}
+					fmt.Fprintf(&b, "line: %d\n", i)
+				}
+				return b.String()
+			}
+			branches(func() {
+				write("values.yaml", lines(nil))
+			}, func() {
+				write("values.yaml", lines(map[int]string{10: "ours: true\n"}))
+			}, func() {
+				write("values.yaml", lines(map[int]string{49990: "theirs: true\n"}))
+			})
+
+			conflicts, err := g.MergeConflicts("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(BeEmpty())
+			Expect(read("values.yaml")).To(Equal(lines(map[int]string{10: "ours: true\n", 49990: "theirs: true\n"})))
+		})
+
+		It("fast-forwards", func() {
+			branches(func() {
+				write("values.yaml", "a: 1\n")
+			}, func() {}, func() {
+				write("values.yaml", "a: 2\n")
+			})
+			files, err := g.Merge("theirs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(BeEmpty())
+			Expect(read("values.yaml")).To(Equal("a: 2\n"))
+		})
+	})
+})
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// binaryCheckSize is the number of leading bytes checked for a NUL byte to detect binary content, as git does.
+const binaryCheckSize = 8000
+
+// Conflict is a file which couldn't be merged automatically.
+type Conflict struct {
+	// Path of the file, relative to the repository.
+	Path string
+	// Hunks is the number of conflicting hunks marked in the file. It's zero when the file was
+	// removed on one side and changed on the other, in which case the changed file is kept, or when
+	// the file couldn't be merged line by line, in which case our file is kept.
+	Hunks int
+}
+
+// mergeFiles three-way merges the files of ours and theirs using base. It returns the merged content of
+// every file which differs from ours, with a nil content for removed files, and the conflicts.
+func mergeFiles(base, ours, theirs map[string][]byte, oursLabel, theirsLabel string) (map[string][]byte, []Conflict) {
+	paths := make(map[string]struct{})
+	for _, files := range []map[string][]byte{base, ours, theirs} {
+		for p := range files {
+			paths[p] = struct{}{}
+		}
+	}
+	merged := make(map[string][]byte)
+	var conflicts []Conflict
+	for p := range paths {
+		b, inBase := base[p]
+		o, inOurs := ours[p]
+		t, inTheirs := theirs[p]
+		switch {
+		case inOurs == inTheirs && bytes.Equal(o, t):
+			// same on both sides.
+		case inOurs == inBase && bytes.Equal(o, b):
+			// only changed by theirs.
+			merged[p] = t
+		case inTheirs == inBase && bytes.Equal(t, b):
+			// only changed by ours.
+		case !inOurs || !inTheirs:
+			// removed on one side and changed on the other, keep the changes.
+			if !inOurs {
+				merged[p] = t
+			}
+			conflicts = append(conflicts, Conflict{Path: p})
+		case isBinary(b) || isBinary(o) || isBinary(t):
+			// binary content can't be merged line by line, keep ours.
+			conflicts = append(conflicts, Conflict{Path: p})
+		default:
+			content, hunks, ok := mergeLines(b, o, t, oursLabel, theirsLabel)
+			if !ok {
+				conflicts = append(conflicts, Conflict{Path: p})
+				continue
+			}
+			merged[p] = content
+			if hunks > 0 {
+				conflicts = append(conflicts, Conflict{Path: p, Hunks: hunks})
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+	return merged, conflicts
+}
+
+// mergeLines merges the lines changed by ours and theirs in base. Overlapping changes which differ are
+// marked with conflict markers. It returns the merged content and the number of conflicting hunks, or false
+// if the files have too many distinct lines to be diffed.
+func mergeLines(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, int, bool) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchOurs, ok := matchLines(b, o)
+	if !ok {
+		return nil, 0, false
+	}
+	matchTheirs, ok := matchLines(b, t)
+	if !ok {
+		return nil, 0, false
+	}
+
+	var (
+		out                   strings.Builder
+		conflicts             int
+		iBase, iOurs, iTheirs int
+	)
+	writeChunk := func(end, endOurs, endTheirs int) {
+		baseChunk, oursChunk, theirsChunk := b[iBase:end], o[iOurs:endOurs], t[iTheirs:endTheirs]
+		switch {
+		case equalLines(oursChunk, theirsChunk), equalLines(theirsChunk, baseChunk):
+			out.WriteString(strings.Join(oursChunk, ""))
+		case equalLines(oursChunk, baseChunk):
+			out.WriteString(strings.Join(theirsChunk, ""))
+		default:
+			conflicts++
+			fmt.Fprintf(&out, "<<<<<<< %s\n%s=======\n%s>>>>>>> %s\n", oursLabel, terminated(oursChunk), terminated(theirsChunk), theirsLabel)
+		}
+	}
+	for {
+		// find the next base line which is unchanged on both sides.
+		next := iBase
+		for next < len(b) && (matchOurs[next] < 0 || matchTheirs[next] < 0) {
+			next++
+		}
+		if next == len(b) {
+			writeChunk(len(b), len(o), len(t))
+			break
+		}
+		if next > iBase || matchOurs[next] > iOurs || matchTheirs[next] > iTheirs {
+			writeChunk(next, matchOurs[next], matchTheirs[next])
+		}
+		out.WriteString(b[next])
+		iBase, iOurs, iTheirs = next+1, matchOurs[next]+1, matchTheirs[next]+1
+	}
+	return []byte(out.String()), conflicts, true
+}
+
+// matchLines returns for every line of a the index of the matching line of b in their shortest edit script,
+// or -1 if it has none. The lines are diffed with the linear space Myers algorithm, it returns false if they
+// have too many distinct lines to be encoded.
+func matchLines(a, b []string) ([]int, bool) {
+	runesA, runesB, ok := lineRunes(a, b)
+	if !ok {
+		return nil, false
+	}
+	dmp := diffmatchpatch.New()
+	// without a timeout the diff, and so the merge, is the same on every run.
+	dmp.DiffTimeout = 0
+	match := make([]int, len(a))
+	i, j := 0, 0
+	for _, d := range dmp.DiffMainRunes(runesA, runesB, false) {
+		n := utf8.RuneCountInString(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			for k := 0; k < n; k++ {
+				match[i] = j
+				i++
+				j++
+			}
+		case diffmatchpatch.DiffDelete:
+			for k := 0; k < n; k++ {
+				match[i] = -1
+				i++
+			}
+		case diffmatchpatch.DiffInsert:
+			j += n
+		}
+	}
+	return match, true
+}
+
+// lineRunes encodes every distinct line of a and b as a rune, so they can be diffed as a sequence of runes.
+// Surrogate halves are skipped as they aren't valid runes. It returns false if there are more distinct lines
+// than runes.
+func lineRunes(a, b []string) ([]rune, []rune, bool) {
+	codes := make(map[string]rune)
+	next := rune(1)
+	encode := func(lines []string) ([]rune, bool) {
+		runes := make([]rune, len(lines))
+		for i, line := range lines {
+			code, ok := codes[line]
+			if !ok {
+				if next >= 0xd800 && next <= 0xdfff {
+					next = 0xe000
+				}
+				if next > utf8.MaxRune {
+					return nil, false
+				}
+				code = next
+				codes[line] = code
+				next++
+			}
+			runes[i] = code
+		}
+		return runes, true
+	}
+	runesA, ok := encode(a)
+	if !ok {
+		return nil, nil, false
+	}
+	runesB, ok := encode(b)
+	return runesA, runesB, ok
+}
+
+// isBinary reports whether content is binary, that is whether it has a NUL byte near its start.
+func isBinary(content []byte) bool {
+	if len(content) > binaryCheckSize {
+		content = content[:binaryCheckSize]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// splitLines splits content into lines, keeping their line endings.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// terminated joins lines, making sure the result ends with a line ending.
+func terminated(lines []string) string {
+	s := strings.Join(lines, "")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
@@ -1,8 +1,8 @@
 package artifact
 
 import (
+	"bytes"
 	"fmt"
-	"os"
 	"path"
 	"path/filepath"
 	"strings"
@@ -13,8 +13,6 @@ import (
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/dependency"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
-	"github.com/otiai10/copy"
-	"github.com/weaveworks/pctl/pkg/log"
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +26,7 @@ import (
 const (
 	defaultValuesKey           = "default-values.yaml"
 	helmChartLocation          = "helm-chart"
+	valuesLocation             = "values"
 	kustomizeWrapperObjectName = "kustomize-flux.yaml"
 	defaultInterval            = time.Minute * 5
 )
@@ -53,10 +52,17 @@ type Writer struct {
 	GitRepositoryName      string
 	GitRepositoryNamespace string
 	RootDir                string
+	// Values are written to the ConfigMap of the installation, keyed by chart artifact name.
+	Values map[string]string
+	// Sink receives the written files. Defaults to FilesystemSink.
+	Sink Sink
 }
 
 // Build a single artifact from a profile artifact and installation.
 func (c *Writer) Write(installation profilesv1.ProfileInstallation, artifacts []ArtifactWrapper) error {
+	if err := c.validateValues(installation, artifacts); err != nil {
+		return err
+	}
 	for _, a := range artifacts {
 		var deps []ArtifactWrapper
 		for _, dep := range a.DependsOn {
@@ -82,9 +88,56 @@ func (c *Writer) Write(installation profilesv1.ProfileInstallation, artifacts []
 			return fmt.Errorf("no artifact type set")
 		}
 	}
+	if len(c.Values) > 0 {
+		if err := c.writeValuesConfigMap(installation); err != nil {
+			return err
+		}
+	}
 	return c.writeResourceWithName(&installation, filepath.Join(c.RootDir, "profile-installation.yaml"))
 }
 
+// validateValues makes sure values are only given for chart artifacts of the installation.
+func (c *Writer) validateValues(installation profilesv1.ProfileInstallation, artifacts []ArtifactWrapper) error {
+	if len(c.Values) == 0 {
+		return nil
+	}
+	if installation.Spec.ConfigMap == "" {
+		return fmt.Errorf("values can only be written for an installation with a config map")
+	}
+	charts := make(map[string]struct{})
+	for _, a := range artifacts {
+		if a.Chart != nil {
+			charts[ValuesKey(a.Name)] = struct{}{}
+		}
+	}
+	for name := range c.Values {
+		if _, ok := charts[name]; !ok {
+			return fmt.Errorf("values given for %q which isn't a chart artifact of the profile", name)
+		}
+	}
+	return nil
+}
+
+// writeValuesConfigMap writes the ConfigMap referenced by the installation with the values of every chart artifact.
+func (c *Writer) writeValuesConfigMap(installation profilesv1.ProfileInstallation) error {
+	valuesDir := filepath.Join(c.RootDir, valuesLocation)
+	cfgMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      installation.Spec.ConfigMap,
+			Namespace: installation.ObjectMeta.Namespace,
+		},
+		Data: c.Values,
+	}
+	if err := c.writeResource(cfgMap, valuesDir); err != nil {
+		return err
+	}
+	return c.writeOutKustomizeResource([]string{"ConfigMap.yaml"}, valuesDir)
+}
+
 func validateArtifact(a profilesv1.Artifact) error {
 	if a.Chart != nil {
 		if a.Profile != nil {
@@ -119,9 +172,6 @@ func (c *Writer) writeKustomizeArtifact(installation profilesv1.ProfileInstallat
 func (c *Writer) writeChartArtifact(installation profilesv1.ProfileInstallation, a ArtifactWrapper, deps []ArtifactWrapper) error {
 	artifactDir := filepath.Join(c.RootDir, "artifacts", a.NestedProfileSubDirectoryName, a.Name)
 	helmChartDir := filepath.Join(artifactDir, helmChartLocation)
-	if err := os.MkdirAll(helmChartDir, 0755); err != nil && !os.IsExist(err) {
-		return fmt.Errorf("failed to create directory %w", err)
-	}
 	var objs []runtime.Object
 	helmRelease, cfgMap := c.makeHelmReleaseObjects(a.Artifact, installation, a.ProfileName)
 	if cfgMap != nil {
@@ -172,7 +222,7 @@ func (c *Writer) writeOutKustomizeResource(resources []string, dir string) error
 		return fmt.Errorf("failed to marshal kustomize resource: %w", err)
 	}
 	filename := filepath.Join(dir, "kustomization.yaml")
-	if err = os.WriteFile(filename, data, 0644); err != nil {
+	if err = c.sink().WriteFile(filename, data); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", filename, err)
 	}
 	return nil
@@ -180,48 +230,36 @@ func (c *Writer) writeOutKustomizeResource(resources []string, dir string) error
 
 func (c *Writer) writeResourceWithName(obj runtime.Object, filename string) error {
 	e := kjson.NewSerializerWithOptions(kjson.DefaultMetaFactory, nil, nil, kjson.SerializerOptions{Yaml: true, Strict: true})
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
+	var buf bytes.Buffer
+	if err := e.Encode(obj, &buf); err != nil {
 		return err
 	}
-	defer func(f *os.File) {
-		if err := f.Close(); err != nil {
-			log.Failuref("Failed to properly close file %s\n", f.Name())
-		}
-	}(f)
-	if err := e.Encode(obj, f); err != nil {
-		return err
+	if err := c.sink().WriteFile(filename, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filename, err)
 	}
 	return nil
 }
 
 func (c *Writer) writeResource(obj runtime.Object, dir string) error {
 	name := obj.GetObjectKind().GroupVersionKind().Kind
-	filename := filepath.Join(dir, fmt.Sprintf("%s.%s", name, "yaml"))
-	e := kjson.NewSerializerWithOptions(kjson.DefaultMetaFactory, nil, nil, kjson.SerializerOptions{Yaml: true, Strict: true})
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer func(f *os.File) {
-		if err := f.Close(); err != nil {
-			log.Failuref("Failed to properly close file %s\n", f.Name())
-		}
-	}(f)
-	if err := e.Encode(obj, f); err != nil {
-		return err
-	}
-	return nil
+	return c.writeResourceWithName(obj, filepath.Join(dir, fmt.Sprintf("%s.%s", name, "yaml")))
 }
 
 func (c *Writer) copyArtifacts(a ArtifactWrapper, subDir, destDir string) error {
 	srcDir := filepath.Join(a.PathToProfileClone, subDir)
-	if err := copy.Copy(srcDir, destDir); err != nil {
+	if err := c.sink().Copy(srcDir, destDir); err != nil {
 		return fmt.Errorf("failed to copy files: %w", err)
 	}
 	return nil
 }
 
+func (c *Writer) sink() Sink {
+	if c.Sink == nil {
+		return FilesystemSink{}
+	}
+	return c.Sink
+}
+
 func (c *Writer) makeHelmReleaseObjects(artifact profilesv1.Artifact, installation profilesv1.ProfileInstallation, definitionName string) (*helmv2.HelmRelease, *corev1.ConfigMap) {
 	var helmChartSpec helmv2.HelmChartTemplateSpec
 	if artifact.Chart.Path != "" {
@@ -243,11 +281,10 @@ func (c *Writer) makeHelmReleaseObjects(artifact profilesv1.Artifact, installati
 		})
 	}
 	if installation.Spec.ConfigMap != "" {
-		artifactNameParts := strings.Split(artifact.Name, "/")
 		values = append(values, helmv2.ValuesReference{
 			Kind:      "ConfigMap",
 			Name:      installation.Spec.ConfigMap,
-			ValuesKey: artifactNameParts[len(artifactNameParts)-1],
+			ValuesKey: ValuesKey(artifact.Name),
 		})
 	}
 	helmRelease := &helmv2.HelmRelease{
@@ -384,6 +421,13 @@ func (c *Writer) makeCfgMapName(name string, installationName string) string {
 	return c.join(installationName, name, "defaultvalues")
 }
 
+// ValuesKey returns the key of the values of an artifact in the ConfigMap of the installation.
+// Nested artifacts are named <nested profile>/<artifact>, their values are keyed by the artifact alone.
+func ValuesKey(artifactName string) string {
+	artifactNameParts := strings.Split(artifactName, "/")
+	return artifactNameParts[len(artifactNameParts)-1]
+}
+
 // join creates a joined string of name using - as a join character.
 func (c *Writer) join(s ...string) string {
 	return strings.Join(s, "-")
@@ -307,6 +307,53 @@ var _ = Describe("Helm", func() {
 		})
 	})
 
+	When("values are given", func() {
+		BeforeEach(func() {
+			installation.Spec.ConfigMap = configMapName
+			artifactWriter.Values = map[string]string{artifactName: "replicaCount: 2\n"}
+		})
+
+		It("generates the ConfigMap of the installation", func() {
+			err := artifactWriter.Write(installation, artifacts)
+			Expect(err).NotTo(HaveOccurred())
+
+			kustomization := types.Kustomization{}
+			decodeFile(filepath.Join(rootDir, "values/kustomization.yaml"), &kustomization)
+			Expect(kustomization).To(Equal(types.Kustomization{
+				Resources: []string{"ConfigMap.yaml"},
+			}))
+
+			configMap := corev1.ConfigMap{}
+			decodeFile(filepath.Join(rootDir, "values/ConfigMap.yaml"), &configMap)
+			Expect(configMap).To(Equal(corev1.ConfigMap{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "ConfigMap",
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      configMapName,
+					Namespace: namespace,
+				},
+				Data: map[string]string{
+					artifactName: "replicaCount: 2\n",
+				},
+			}))
+		})
+
+		It("returns an error when the values aren't for a chart artifact", func() {
+			artifactWriter.Values = map[string]string{"unknown": "replicaCount: 2\n"}
+			err := artifactWriter.Write(installation, artifacts)
+			Expect(err).To(MatchError(`values given for "unknown" which isn't a chart artifact of the profile`))
+			Expect(filepath.Join(rootDir, "artifacts")).NotTo(BeADirectory())
+		})
+
+		It("returns an error when the installation has no config map", func() {
+			installation.Spec.ConfigMap = ""
+			err := artifactWriter.Write(installation, artifacts)
+			Expect(err).To(MatchError("values can only be written for an installation with a config map"))
+		})
+	})
+
 	When("copying the artifact fails", func() {
 		It("returns an error", func() {
 			artifacts[0].PathToProfileClone = "/tmp/i/dont/exist"
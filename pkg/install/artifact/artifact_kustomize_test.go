@@ -1,6 +1,7 @@
 package artifact_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -11,6 +12,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/weaveworks/pctl/pkg/install/artifact"
+	"github.com/weaveworks/pctl/pkg/install/artifact/fakes"
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/kustomize/api/types"
@@ -98,4 +100,39 @@ var _ = Describe("Kustomize", func() {
 			Expect(err).To(MatchError(ContainSubstring("failed to copy files:")))
 		})
 	})
+
+	When("the writer has a memory sink", func() {
+		It("keeps the files in memory without writing to disk", func() {
+			sink := &artifact.MemorySink{}
+			artifactWriter.Sink = sink
+			Expect(artifactWriter.Write(installation, artifacts)).To(Succeed())
+
+			entries, err := ioutil.ReadDir(rootDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+
+			var paths []string
+			for _, f := range sink.Files {
+				paths = append(paths, strings.TrimPrefix(f.Path, rootDir+"/"))
+				Expect(f.Copied).To(Equal(f.Path == filepath.Join(rootDir, "artifacts/1/files/file1")))
+			}
+			Expect(paths).To(Equal([]string{
+				"artifacts/1/files/file1",
+				"artifacts/1/kustomization.yaml",
+				"artifacts/1/kustomize-flux.yaml",
+				"profile-installation.yaml",
+			}))
+			Expect(string(sink.Files[0].Data)).To(Equal("foo"))
+		})
+	})
+
+	When("the sink fails to write", func() {
+		It("returns an error", func() {
+			sink := &fakes.FakeSink{}
+			sink.WriteFileReturns(errors.New("disk full"))
+			artifactWriter.Sink = sink
+			err := artifactWriter.Write(installation, artifacts)
+			Expect(err).To(MatchError(ContainSubstring("disk full")))
+		})
+	})
 })
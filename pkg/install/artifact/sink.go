@@ -0,0 +1,90 @@
+package artifact
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/otiai10/copy"
+)
+
+// Sink receives the files of an installation generated by the Writer.
+//go:generate counterfeiter -o fakes/fake_sink.go . Sink
+type Sink interface {
+	// WriteFile writes data to filename, creating its parent directories.
+	WriteFile(filename string, data []byte) error
+	// Copy copies the directory or file at src, which is part of a profile repository, to dest.
+	Copy(src, dest string) error
+}
+
+// FilesystemSink writes the files to disk.
+type FilesystemSink struct{}
+
+var _ Sink = FilesystemSink{}
+
+// WriteFile implements Sink.
+func (FilesystemSink) WriteFile(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %w", err)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// Copy implements Sink.
+func (FilesystemSink) Copy(src, dest string) error {
+	return copy.Copy(src, dest)
+}
+
+// File is a file received by a MemorySink.
+type File struct {
+	Path string
+	Data []byte
+	// Copied is set for files copied from the profile repository rather than generated.
+	Copied bool
+}
+
+// MemorySink keeps the files in memory, in the order they were first written, without touching disk.
+type MemorySink struct {
+	Files []File
+}
+
+var _ Sink = &MemorySink{}
+
+// WriteFile implements Sink.
+func (m *MemorySink) WriteFile(filename string, data []byte) error {
+	m.add(File{Path: filename, Data: data})
+	return nil
+}
+
+// Copy implements Sink. The copied files are read, but not written anywhere.
+func (m *MemorySink) Copy(src, dest string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		m.add(File{Path: filepath.Join(dest, rel), Data: data, Copied: true})
+		return nil
+	})
+}
+
+func (m *MemorySink) add(f File) {
+	for i := range m.Files {
+		if m.Files[i].Path == f.Path {
+			m.Files[i] = f
+			return
+		}
+	}
+	m.Files = append(m.Files, f)
+}
@@ -0,0 +1,88 @@
+package install
+
+import "sync"
+
+// DefaultConcurrency is the number of repositories cloned concurrently when resolving nested profiles.
+const DefaultConcurrency = 4
+
+// clones holds the directories repositories are cloned into. It's shared by the installers created with WithConfig
+// and safe for concurrent use: every repository and branch is cloned once, even when several nested profiles need
+// it at the same time.
+type clones struct {
+	mu    sync.Mutex
+	repos map[string]*clone
+	// limit bounds the number of concurrent clones.
+	limit chan struct{}
+}
+
+type clone struct {
+	done chan struct{}
+	dir  string
+	// revision is the commit cloned, empty for repositories on the local filesystem.
+	revision string
+	err      error
+}
+
+func newClones(concurrency int) *clones {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return &clones{
+		repos: make(map[string]*clone),
+		limit: make(chan struct{}, concurrency),
+	}
+}
+
+// get returns the directory the repository of key is cloned into, cloning it with cloneRepo if it isn't yet.
+// cloneRepo returns the directory and the commit cloned. Failed clones are forgotten so they are attempted again
+// by later calls.
+func (c *clones) get(key string, cloneRepo func() (string, string, error)) (string, error) {
+	c.mu.Lock()
+	if cl, ok := c.repos[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.dir, cl.err
+	}
+	cl := &clone{done: make(chan struct{})}
+	c.repos[key] = cl
+	c.mu.Unlock()
+
+	c.limit <- struct{}{}
+	cl.dir, cl.revision, cl.err = cloneRepo()
+	<-c.limit
+	if cl.err != nil {
+		c.mu.Lock()
+		delete(c.repos, key)
+		c.mu.Unlock()
+	}
+	close(cl.done)
+	return cl.dir, cl.err
+}
+
+// dir returns the directory the repository of key was cloned into, or an empty string if it wasn't.
+func (c *clones) dir(key string) string {
+	if cl := c.lookup(key); cl != nil {
+		return cl.dir
+	}
+	return ""
+}
+
+// revision returns the commit the repository of key was cloned at, or an empty string if it wasn't.
+func (c *clones) revision(key string) string {
+	if cl := c.lookup(key); cl != nil {
+		return cl.revision
+	}
+	return ""
+}
+
+// lookup returns the finished clone of key, or nil if there is none.
+func (c *clones) lookup(key string) *clone {
+	c.mu.Lock()
+	cl, ok := c.repos[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	<-cl.done
+	return cl
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/weaveworks/pctl/pkg/git"
@@ -15,6 +17,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
+const localURLPrefix = "file://"
+
 // ProfileInstaller installs the profile
 //go:generate counterfeiter -o fakes/fake_profile_installer.go . ProfileInstaller
 type ProfileInstaller interface {
@@ -24,50 +28,111 @@ type ProfileInstaller interface {
 //Ensure Installer implements ProfileInstaller interface
 var _ ProfileInstaller = &Installer{}
 
+// Cloner clones a repository at a branch or tag into a directory. When revision is set, that commit of the branch
+// or tag is cloned instead of its latest one. It returns the commit cloned.
+//go:generate counterfeiter -o fakes/fake_cloner.go . Cloner
+type Cloner interface {
+	Clone(repo, branch, revision, location string) (string, error)
+}
+
+// gitCloner clones repositories with a git client.
+type gitCloner struct {
+	client git.Git
+}
+
+// Clone implements Cloner.
+func (g gitCloner) Clone(repo, branch, revision, location string) (string, error) {
+	if err := g.client.Clone(repo, branch, location); err != nil {
+		return "", err
+	}
+	if revision != "" {
+		if err := g.client.FetchRevision(branch, revision, location); err != nil {
+			return "", err
+		}
+	}
+	return g.client.Revision(location)
+}
+
 // Config defines configurable options for the installer
 type Config struct {
 	GitClient        git.Git
 	RootDir          string
 	GitRepoNamespace string
 	GitRepoName      string
+	// Values are written to the ConfigMap of the installation, keyed by chart artifact name.
+	Values map[string]string
+	// Sink receives the files of the installation. Defaults to writing them to disk.
+	Sink artifact.Sink
+	// Cache clones the profile repositories instead of GitClient when set, such as a persistent repository cache.
+	Cache Cloner
+	// Concurrency is the number of repositories cloned concurrently. Defaults to DefaultConcurrency.
+	Concurrency int
+	// MaxDepth is the maximum depth of nested profiles. Defaults to DefaultMaxDepth.
+	MaxDepth int
+	// Lock pins the profile repositories to the commits it records, such as the lock file of an existing
+	// installation. Profiles it doesn't record can't be installed.
+	Lock *Lock
 }
 
 //Installer holds the configuration for isntalling a profile
 type Installer struct {
 	Config
-	clonedRepos    map[string]string
+	clones         *clones
 	artifactWriter artifact.ArtifactWriter
 }
 
 // NewInstaller creates a new profiles installer
 func NewInstaller(cfg Config) *Installer {
 	return &Installer{
-		clonedRepos: make(map[string]string),
-		Config:      cfg,
+		clones: newClones(cfg.Concurrency),
+		Config: cfg,
 		artifactWriter: &artifact.Writer{
 			GitRepositoryName:      cfg.GitRepoName,
 			GitRepositoryNamespace: cfg.GitRepoNamespace,
 			RootDir:                cfg.RootDir,
+			Values:                 cfg.Values,
+			Sink:                   cfg.Sink,
 		},
 	}
 }
 
-//Install installs the profile
+// WithConfig returns an installer for cfg which shares the repositories cloned by i.
+func (i *Installer) WithConfig(cfg Config) *Installer {
+	installer := NewInstaller(cfg)
+	installer.clones = i.clones
+	return installer
+}
+
+//Install installs the profile and writes the lock file recording the commits it was generated from
 func (i *Installer) Install(installation profilesv1.ProfileInstallation) error {
-	artifacts, err := i.collectArtifacts(installation, "")
+	res := newResolution(i.MaxDepth)
+	artifacts, err := i.collectArtifacts(installation, "", res, nil)
 	if err != nil {
 		return err
 	}
-	return i.artifactWriter.Write(installation, artifacts)
+	if err := i.artifactWriter.Write(installation, artifacts); err != nil {
+		return err
+	}
+	return i.writeLock(res)
+}
+
+// Artifacts returns the artifacts of the profile of installation, including those of nested profiles.
+func (i *Installer) Artifacts(installation profilesv1.ProfileInstallation) ([]artifact.ArtifactWrapper, error) {
+	return i.collectArtifacts(installation, "", newResolution(i.MaxDepth), nil)
 }
 
 //collectArtifacts goes through the profile definition and any nested profiles to collect all artifacts
-func (i *Installer) collectArtifacts(installation profilesv1.ProfileInstallation, nestedDir string) ([]artifact.ArtifactWrapper, error) {
+//chain holds the profiles including this one, from the profile of the installation down.
+func (i *Installer) collectArtifacts(installation profilesv1.ProfileInstallation, nestedDir string, res *resolution, chain []profileRef) ([]artifact.ArtifactWrapper, error) {
 	path := installation.Spec.Source.Path
 	branchOrTag := installation.Spec.Source.Tag
 	if installation.Spec.Source.Tag == "" {
 		branchOrTag = installation.Spec.Source.Branch
 	}
+	ref := profileRef{url: installation.Spec.Source.URL, ref: branchOrTag, path: path}
+	if err := res.enter(chain, ref); err != nil {
+		return nil, err
+	}
 	profileDef, err := i.cloneRepoAndGetProfileDefinition(installation.Spec.Source.URL, branchOrTag, path)
 	if err != nil {
 		return nil, err
@@ -76,18 +141,27 @@ func (i *Installer) collectArtifacts(installation profilesv1.ProfileInstallation
 	if err := validateDefinition(profileDef); err != nil {
 		return nil, err
 	}
+	profileRepoKey := i.cloneKey(installation.Spec.Source.URL, branchOrTag)
+	ref.name = profileDef.Name
+	ref.commit = i.clones.revision(profileRepoKey)
+	chain = append(chain[:len(chain):len(chain)], ref)
+	if err := res.record(chain); err != nil {
+		return nil, err
+	}
 
-	profileRepoKey := cloneCacheKey(installation.Spec.Source.URL, branchOrTag)
-
-	var artifacts []artifact.ArtifactWrapper
-	for _, a := range profileDef.Spec.Artifacts {
+	// nested profiles are resolved concurrently, their artifacts are kept in the order of the definition.
+	results := make([][]artifact.ArtifactWrapper, len(profileDef.Spec.Artifacts))
+	errs := make([]error, len(profileDef.Spec.Artifacts))
+	var wg sync.WaitGroup
+	for idx, a := range profileDef.Spec.Artifacts {
 		//If its a nested profile lets make a recursive call to scans its artifacts
 		if a.Profile != nil {
 			if err := validateProfileArtifact(a.Profile); err != nil {
-				return nil, err
+				errs[idx] = err
+				break
 			}
 			nestedInstallation := installation.DeepCopyObject().(*profilesv1.ProfileInstallation)
-			nestedInstallation.Spec.Source.URL = a.Profile.Source.URL
+			nestedInstallation.Spec.Source.URL = i.resolveLocalURL(a.Profile.Source.URL, installation.Spec.Source.URL, branchOrTag)
 			nestedInstallation.Spec.Source.Branch = a.Profile.Source.Branch
 			nestedInstallation.Spec.Source.Tag = a.Profile.Source.Tag
 			nestedInstallation.Spec.Source.Path = a.Profile.Source.Path
@@ -100,30 +174,44 @@ func (i *Installer) collectArtifacts(installation profilesv1.ProfileInstallation
 				nestedInstallation.Spec.Source.Path = path
 			}
 			nestedInstallation.Name = a.Name
-			nestedArtifacts, err := i.collectArtifacts(*nestedInstallation, filepath.Join(nestedDir, nestedInstallation.Name))
-			if err != nil {
-				return nil, err
-			}
-			artifacts = append(artifacts, nestedArtifacts...)
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				results[idx], errs[idx] = i.collectArtifacts(*nestedInstallation, filepath.Join(nestedDir, nestedInstallation.Name), res, chain)
+			}(idx)
 		} else {
-			newArtifact := artifact.ArtifactWrapper{
+			results[idx] = []artifact.ArtifactWrapper{{
 				Artifact:                      a,
-				PathToProfileClone:            filepath.Join(i.clonedRepos[profileRepoKey], installation.Spec.Source.Path),
+				PathToProfileClone:            filepath.Join(i.clones.dir(profileRepoKey), installation.Spec.Source.Path),
 				ProfileName:                   profileDef.Name,
 				NestedProfileSubDirectoryName: nestedDir,
-			}
-			artifacts = append(artifacts, newArtifact)
+			}}
+		}
+	}
+	wg.Wait()
+
+	var artifacts []artifact.ArtifactWrapper
+	for idx := range results {
+		if errs[idx] != nil {
+			return nil, errs[idx]
 		}
+		artifacts = append(artifacts, results[idx]...)
 	}
 	return artifacts, nil
 }
 
+// ProfileDefinition clones the repository at url and returns the definition of the profile at path
+// on the given branch or tag.
+func (i *Installer) ProfileDefinition(url, branchOrTag, path string) (profilesv1.ProfileDefinition, error) {
+	return i.cloneRepoAndGetProfileDefinition(url, branchOrTag, path)
+}
+
 func validateProfileArtifact(p *profilesv1.Profile) error {
 	if p.Source.Tag != "" && p.Source.Branch != "" {
 		return fmt.Errorf("cannot configure both %q and %q in profile artifact", "profile.Source.Tag", "Profile.Source.Branch")
 	}
 
-	if p.Source.Tag == "" && p.Source.Branch == "" {
+	if p.Source.Tag == "" && p.Source.Branch == "" && !IsLocalURL(p.Source.URL) {
 		return fmt.Errorf("one of %q or %q must be configured", "profile.Source.Tag", "Profile.Source.Branch")
 	}
 	return nil
@@ -140,21 +228,36 @@ func (i *Installer) cloneRepoAndGetProfileDefinition(repoURL, branch, path strin
 		return profilesv1.ProfileDefinition{}, errors.New("the generated uuid is not long enough")
 	}
 
-	var (
-		tmp string
-	)
-	if v, ok := i.clonedRepos[cloneCacheKey(repoURL, branch)]; ok {
-		tmp = v
-	} else {
+	revision := i.Lock.commit(repoURL, branch)
+	if i.Lock != nil && revision == "" && !IsLocalURL(repoURL) {
+		return profilesv1.ProfileDefinition{}, fmt.Errorf("repository %q branch or tag %q is not in the lock file", repoURL, branch)
+	}
+	tmp, err := i.clones.get(i.cloneKey(repoURL, branch), func() (string, string, error) {
+		if IsLocalURL(repoURL) {
+			// local repositories are read in place, whatever the branch.
+			dir := localPath(repoURL)
+			if _, err := os.Stat(dir); err != nil {
+				return "", "", fmt.Errorf("failed to read local repository %q: %w", repoURL, err)
+			}
+			return dir, "", nil
+		}
 		px := u.String()[:6]
-		tmp, err = ioutil.TempDir("", "cloned_profile"+px)
+		dir, err := ioutil.TempDir("", "cloned_profile"+px)
 		if err != nil {
-			return profilesv1.ProfileDefinition{}, fmt.Errorf("failed to create temp folder for cloning repository: %w", err)
+			return "", "", fmt.Errorf("failed to create temp folder for cloning repository: %w", err)
+		}
+		var cloner Cloner = gitCloner{client: i.GitClient}
+		if i.Cache != nil {
+			cloner = i.Cache
 		}
-		if err := i.GitClient.Clone(repoURL, branch, tmp); err != nil {
-			return profilesv1.ProfileDefinition{}, fmt.Errorf("failed to clone repo %q: %w", repoURL, err)
+		cloned, err := cloner.Clone(repoURL, branch, revision, dir)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to clone repo %q: %w", repoURL, err)
 		}
-		i.clonedRepos[cloneCacheKey(repoURL, branch)] = tmp
+		return dir, cloned, nil
+	})
+	if err != nil {
+		return profilesv1.ProfileDefinition{}, err
 	}
 
 	content, err := ioutil.ReadFile(filepath.Join(tmp, path, "profile.yaml"))
@@ -186,6 +289,53 @@ func validateDefinition(def profilesv1.ProfileDefinition) error {
 	return nil
 }
 
-func cloneCacheKey(url, branch string) string {
-	return fmt.Sprintf("%s:%s", url, branch)
+// IsLocalURL returns whether url refers to a profile repository on the local filesystem, such as file:///path/to/repo.
+func IsLocalURL(url string) bool {
+	return strings.HasPrefix(url, localURLPrefix)
+}
+
+// LocalURL returns the url of the profile repository in dir on the local filesystem.
+func LocalURL(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path of %q: %w", dir, err)
+	}
+	return localURLPrefix + filepath.ToSlash(abs), nil
+}
+
+func localPath(url string) string {
+	return filepath.FromSlash(strings.TrimPrefix(url, localURLPrefix))
+}
+
+// resolveLocalURL makes the relative local url of a nested profile absolute. It's relative to the repository
+// of the parent profile if that's local too, otherwise to the working directory.
+func (i *Installer) resolveLocalURL(url, parentURL, parentBranch string) string {
+	if !IsLocalURL(url) || filepath.IsAbs(localPath(url)) {
+		return url
+	}
+	path := localPath(url)
+	if IsLocalURL(parentURL) {
+		path = filepath.Join(i.clones.dir(i.cloneKey(parentURL, parentBranch)), path)
+	}
+	resolved, err := LocalURL(path)
+	if err != nil {
+		return url
+	}
+	return resolved
+}
+
+// cloneKey identifies the clone of the repository at the branch or tag, and the commit it's locked to if any.
+func (i *Installer) cloneKey(url, branch string) string {
+	key := fmt.Sprintf("%s:%s", url, branch)
+	if revision := i.Lock.commit(url, branch); revision != "" {
+		key += "@" + revision
+	}
+	return key
+}
+
+func (i *Installer) sink() artifact.Sink {
+	if i.Sink == nil {
+		return artifact.FilesystemSink{}
+	}
+	return i.Sink
 }
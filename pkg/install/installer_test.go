@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,6 +14,7 @@ import (
 	"github.com/weaveworks/pctl/pkg/install"
 	"github.com/weaveworks/pctl/pkg/install/artifact"
 	"github.com/weaveworks/pctl/pkg/install/artifact/fakes"
+	installfakes "github.com/weaveworks/pctl/pkg/install/fakes"
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -22,6 +25,7 @@ var _ = Describe("Installer", func() {
 	var (
 		fakeGitClient    *fakegit.FakeGit
 		fakeWriter       *fakes.FakeArtifactWriter
+		sink             *artifact.MemorySink
 		installer        *install.Installer
 		installation     profilesv1.ProfileInstallation
 		gitRepoName      = "git-repo-name"
@@ -104,11 +108,13 @@ var _ = Describe("Installer", func() {
 
 		fakeGitClient = &fakegit.FakeGit{}
 		fakeWriter = &fakes.FakeArtifactWriter{}
+		sink = &artifact.MemorySink{}
 		installer = install.NewInstaller(install.Config{
 			GitClient:        fakeGitClient,
 			RootDir:          rootDir,
 			GitRepoNamespace: gitRepoNamespace,
 			GitRepoName:      gitRepoName,
+			Sink:             sink,
 		})
 		installer.SetWriter(fakeWriter)
 
@@ -185,6 +191,301 @@ var _ = Describe("Installer", func() {
 		))
 	})
 
+	When("another installer is created with WithConfig", func() {
+		It("shares the cloned repositories", func() {
+			Expect(installer.Install(installation)).To(Succeed())
+			other := installer.WithConfig(install.Config{
+				GitClient: fakeGitClient,
+				RootDir:   "/tmp/other/root/dir",
+				Sink:      sink,
+			})
+			other.SetWriter(fakeWriter)
+			Expect(other.Install(installation)).To(Succeed())
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(2))
+			Expect(fakeWriter.WriteCallCount()).To(Equal(2))
+		})
+	})
+
+	When("a profile has many nested profiles", func() {
+		It("clones them concurrently, keeping the artifacts in the order of the definition", func() {
+			root := profilesv1.ProfileDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "root"},
+				Spec:       profilesv1.ProfileDefinitionSpec{},
+			}
+			for n := 0; n < 4; n++ {
+				root.Spec.Artifacts = append(root.Spec.Artifacts, profilesv1.Artifact{
+					Name: fmt.Sprintf("nested-%d", n),
+					Profile: &profilesv1.Profile{
+						Source: &profilesv1.Source{
+							URL:    fmt.Sprintf("github.com/weaveworks/nested-%d", n),
+							Branch: "main",
+							Path:   ".",
+						},
+					},
+				})
+			}
+			installation.Spec.Source.URL = "github.com/weaveworks/root"
+			installation.Spec.Source.Path = "."
+
+			var (
+				mu                sync.Mutex
+				active, maxActive int
+			)
+			fakeGitClient.CloneStub = func(url, branch, dir string) error {
+				if url == "github.com/weaveworks/root" {
+					return writeKubernetesResource(&root, dir, "profile.yaml")
+				}
+				mu.Lock()
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				mu.Unlock()
+				// the first nested profiles take the longest to clone.
+				var n int
+				_, _ = fmt.Sscanf(url, "github.com/weaveworks/nested-%d", &n)
+				time.Sleep(time.Duration(4-n) * 20 * time.Millisecond)
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return writeKubernetesResource(&profilesv1.ProfileDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("profile-%d", n)},
+					Spec: profilesv1.ProfileDefinitionSpec{
+						Artifacts: []profilesv1.Artifact{{Name: "artifact"}},
+					},
+				}, dir, "profile.yaml")
+			}
+			concurrent := install.NewInstaller(install.Config{
+				GitClient:   fakeGitClient,
+				RootDir:     rootDir,
+				Concurrency: 2,
+				Sink:        sink,
+			})
+			concurrent.SetWriter(fakeWriter)
+
+			Expect(concurrent.Install(installation)).To(Succeed())
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(5))
+			Expect(maxActive).To(Equal(2))
+			_, artifacts := fakeWriter.WriteArgsForCall(0)
+			var names []string
+			for _, a := range artifacts {
+				names = append(names, a.ProfileName+"/"+a.NestedProfileSubDirectoryName)
+			}
+			Expect(names).To(Equal([]string{"profile-0/nested-0", "profile-1/nested-1", "profile-2/nested-2", "profile-3/nested-3"}))
+		})
+
+		It("returns the error of the first failing nested profile", func() {
+			fakeGitClient.CloneStub = func(url, branch, dir string) error {
+				if url == profileURL1 {
+					return writeKubernetesResource(&profileDefinition1, filepath.Join(dir, profilePath1), "profile.yaml")
+				}
+				return fmt.Errorf("nope")
+			}
+			err := installer.Install(installation)
+			Expect(err).To(MatchError(fmt.Sprintf("failed to clone repo %q: nope", profileURL2)))
+		})
+	})
+
+	When("nested profiles include each other", func() {
+		It("fails with the cycle", func() {
+			cyclic := profileDefinition2.DeepCopy()
+			cyclic.Spec.Artifacts[1].Profile.Source = &profilesv1.Source{
+				URL:    profileURL1,
+				Branch: profileBranch1,
+				Path:   profilePath1,
+			}
+			fakeGitClient.CloneStub = func(url, branch, dir string) error {
+				if url == profileURL1 {
+					return writeKubernetesResource(&profileDefinition1, filepath.Join(dir, profilePath1), "profile.yaml")
+				}
+				return writeKubernetesResource(cyclic, filepath.Join(dir, profilePath2), "profile.yaml")
+			}
+			err := installer.Install(installation)
+			Expect(err).To(MatchError("nested profiles contain a cycle: profile-1@main -> profile-2@main -> profile-1@main"))
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(2))
+		})
+
+		It("fails when a profile includes itself at another version", func() {
+			profileDefinition1.Spec.Artifacts[1].Profile.Source = &profilesv1.Source{
+				URL: profileURL1,
+				Tag: profilePath1 + "/v0.2.0",
+			}
+			err := installer.Install(installation)
+			Expect(err).To(MatchError("nested profiles contain a cycle: profile-1@main -> github.com/weaveworks/profiles-examples/weaveworks-nginx@weaveworks-nginx/v0.2.0"))
+		})
+	})
+
+	When("nested profiles are deeper than the maximum depth", func() {
+		It("fails with the nested profiles", func() {
+			shallow := install.NewInstaller(install.Config{
+				GitClient: fakeGitClient,
+				RootDir:   rootDir,
+				MaxDepth:  1,
+			})
+			shallow.SetWriter(fakeWriter)
+			err := shallow.Install(installation)
+			Expect(err).To(MatchError("nested profiles exceed the maximum depth of 1: profile-1@main -> profile-2@main -> github.com/weaveworks/profiles-examples@main"))
+		})
+	})
+
+	When("a profile is included at conflicting versions", func() {
+		It("fails with both includes", func() {
+			profileDefinition1.Spec.Artifacts = append(profileDefinition1.Spec.Artifacts, profilesv1.Artifact{
+				Name: "nested-artifact-3",
+				Profile: &profilesv1.Profile{
+					Source: &profilesv1.Source{
+						URL:    profileURL3,
+						Branch: "other",
+						Path:   profilePath3,
+					},
+				},
+			})
+			err := installer.Install(installation)
+			Expect(err).To(MatchError("profile profile-3 is included at conflicting versions: " +
+				"profile-1@main -> profile-2@main -> profile-3@main and profile-1@main -> profile-3@other"))
+		})
+	})
+
+	Context("lock file", func() {
+		It("records the commit of every profile", func() {
+			fakeGitClient.RevisionReturnsOnCall(0, "1111111", nil)
+			fakeGitClient.RevisionReturnsOnCall(1, "2222222", nil)
+			Expect(installer.Install(installation)).To(Succeed())
+			Expect(fakeGitClient.FetchRevisionCallCount()).To(Equal(0))
+			Expect(sink.Files).To(HaveLen(1))
+			Expect(sink.Files[0].Path).To(Equal(filepath.Join(rootDir, install.LockFile)))
+			Expect(string(sink.Files[0].Data)).To(Equal(`profiles:
+- commit: "2222222"
+  name: profile-2
+  path: nginx-profile
+  ref: main
+  url: github.com/weaveworks/nginx-profile
+- commit: "1111111"
+  name: profile-3
+  path: .
+  ref: main
+  url: github.com/weaveworks/profiles-examples
+- commit: "1111111"
+  name: profile-1
+  path: weaveworks-nginx
+  ref: main
+  url: github.com/weaveworks/profiles-examples
+`))
+		})
+
+		It("installs the commits recorded by the lock", func() {
+			locked := installer.WithConfig(install.Config{
+				GitClient: fakeGitClient,
+				RootDir:   rootDir,
+				Sink:      sink,
+				Lock: &install.Lock{Profiles: []install.LockedProfile{
+					{URL: profileURL1, Ref: profileBranch1, Path: profilePath1, Commit: "1111111"},
+					{URL: profileURL2, Ref: profileBranch2, Path: profilePath2, Commit: "2222222"},
+				}},
+			})
+			locked.SetWriter(fakeWriter)
+			Expect(locked.Install(installation)).To(Succeed())
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(2))
+			Expect(fakeGitClient.FetchRevisionCallCount()).To(Equal(2))
+			branch, revision, dir := fakeGitClient.FetchRevisionArgsForCall(0)
+			Expect(branch).To(Equal(profileBranch1))
+			Expect(revision).To(Equal("1111111"))
+			_, _, cloneDir := fakeGitClient.CloneArgsForCall(0)
+			Expect(dir).To(Equal(cloneDir))
+			_, revision, _ = fakeGitClient.FetchRevisionArgsForCall(1)
+			Expect(revision).To(Equal("2222222"))
+		})
+
+		It("fails for profiles which aren't in the lock", func() {
+			locked := installer.WithConfig(install.Config{
+				GitClient: fakeGitClient,
+				RootDir:   rootDir,
+				Lock: &install.Lock{Profiles: []install.LockedProfile{
+					{URL: profileURL1, Ref: profileBranch1, Path: profilePath1, Commit: "1111111"},
+				}},
+			})
+			locked.SetWriter(fakeWriter)
+			err := locked.Install(installation)
+			Expect(err).To(MatchError(`repository "github.com/weaveworks/nginx-profile" branch or tag "main" is not in the lock file`))
+			Expect(fakeWriter.WriteCallCount()).To(Equal(0))
+		})
+	})
+
+	When("a cache is set", func() {
+		It("clones the repositories through the cache", func() {
+			fakeCache := &installfakes.FakeCloner{}
+			fakeCache.CloneStub = func(url, branch, revision, dir string) (string, error) {
+				return "", fakeGitClient.CloneStub(url, branch, dir)
+			}
+			cached := install.NewInstaller(install.Config{
+				GitClient: fakeGitClient,
+				RootDir:   rootDir,
+				Cache:     fakeCache,
+				Sink:      sink,
+			})
+			cached.SetWriter(fakeWriter)
+			Expect(cached.Install(installation)).To(Succeed())
+			Expect(fakeCache.CloneCallCount()).To(Equal(2))
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(0))
+			Expect(fakeWriter.WriteCallCount()).To(Equal(1))
+		})
+	})
+
+	When("the profile is on the local filesystem", func() {
+		var localDir string
+
+		BeforeEach(func() {
+			var err error
+			localDir, err = ioutil.TempDir("", "local-profiles")
+			Expect(err).NotTo(HaveOccurred())
+			profileDefinition1.Spec.Artifacts[1].Profile.Source = &profilesv1.Source{
+				URL:  "file://../nginx-profile",
+				Path: profilePath2,
+			}
+			Expect(writeKubernetesResource(&profileDefinition1, filepath.Join(localDir, "profiles-examples", profilePath1), "profile.yaml")).To(Succeed())
+			localDefinition2 := profileDefinition2.DeepCopy()
+			localDefinition2.Spec.Artifacts = localDefinition2.Spec.Artifacts[:1]
+			Expect(writeKubernetesResource(localDefinition2, filepath.Join(localDir, "nginx-profile", profilePath2), "profile.yaml")).To(Succeed())
+			url, err := install.LocalURL(filepath.Join(localDir, "profiles-examples"))
+			Expect(err).NotTo(HaveOccurred())
+			installation.Spec.Source.URL = url
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(localDir)
+		})
+
+		It("reads the profiles in place, without cloning them", func() {
+			Expect(installer.Install(installation)).To(Succeed())
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(0))
+
+			_, artifacts := fakeWriter.WriteArgsForCall(0)
+			Expect(artifacts).To(ConsistOf(
+				artifact.ArtifactWrapper{
+					Artifact: profilesv1.Artifact{
+						Name: "artifact-1",
+					},
+					PathToProfileClone: filepath.Join(localDir, "profiles-examples", profilePath1),
+					ProfileName:        profileDefinition1.Name,
+				},
+				artifact.ArtifactWrapper{
+					Artifact: profilesv1.Artifact{
+						Name: "artifact-2",
+					},
+					NestedProfileSubDirectoryName: "nested-artifact-1",
+					PathToProfileClone:            filepath.Join(localDir, "nginx-profile", profilePath2),
+					ProfileName:                   profileDefinition2.Name,
+				},
+			))
+		})
+
+		It("errors when the directory doesn't exist", func() {
+			installation.Spec.Source.URL = "file:///tmp/i/dont/exist"
+			err := installer.Install(installation)
+			Expect(err).To(MatchError(ContainSubstring(`failed to read local repository "file:///tmp/i/dont/exist"`)))
+		})
+	})
+
 	When("cloning fails", func() {
 		It("returns an erorr", func() {
 			fakeGitClient.CloneReturns(fmt.Errorf("foo"))
@@ -0,0 +1,72 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LockFile is the file in the directory of an installation recording the commits it was generated from.
+const LockFile = "profile-lock.yaml"
+
+// Lock records the commits the profile of an installation and its nested profiles were generated from, so the
+// installation can be generated again from exactly the same files.
+type Lock struct {
+	Profiles []LockedProfile `json:"profiles"`
+}
+
+// LockedProfile is a profile of an installation resolved to a commit.
+type LockedProfile struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Ref is the branch or tag the profile was added from.
+	Ref  string `json:"ref,omitempty"`
+	Path string `json:"path"`
+	// Commit is the SHA the branch or tag was resolved to. It's empty for profiles on the local filesystem,
+	// which are read in place.
+	Commit string `json:"commit,omitempty"`
+}
+
+// ReadLock reads the lock file of the installation in dir. It returns nil if there is none.
+func ReadLock(dir string) (*Lock, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, LockFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	lock := &Lock{}
+	if err := yaml.UnmarshalStrict(content, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", filepath.Join(dir, LockFile), err)
+	}
+	return lock, nil
+}
+
+// commit returns the commit the repository at url is locked to for the branch or tag, or an empty string if
+// it isn't locked.
+func (l *Lock) commit(url, ref string) string {
+	if l == nil {
+		return ""
+	}
+	for _, p := range l.Profiles {
+		if p.URL == url && p.Ref == ref {
+			return p.Commit
+		}
+	}
+	return ""
+}
+
+// writeLock writes the lock of the profiles resolved for the installation to its directory.
+func (i *Installer) writeLock(res *resolution) error {
+	data, err := yaml.Marshal(res.lock())
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+	if err := i.sink().WriteFile(filepath.Join(i.RootDir, LockFile), data); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
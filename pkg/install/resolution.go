@@ -0,0 +1,130 @@
+package install
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxDepth is the maximum depth of nested profiles resolved for an installation.
+const DefaultMaxDepth = 10
+
+// profileRef identifies a profile by its repository, branch or tag and path in the repository.
+type profileRef struct {
+	name string
+	url  string
+	ref  string
+	path string
+	// commit is the commit ref was resolved to.
+	commit string
+}
+
+// key identifies the profile regardless of its version.
+func (p profileRef) key() string {
+	return p.url + "//" + path.Clean(p.path)
+}
+
+func (p profileRef) String() string {
+	name := p.name
+	if name == "" {
+		name = strings.TrimSuffix(p.url+"/"+path.Clean(p.path), "/.")
+	}
+	if p.ref == "" {
+		return name
+	}
+	return name + "@" + p.ref
+}
+
+// trace formats a resolution path, such as "A@v1 -> B@v2 -> A@v1".
+func trace(chain []profileRef) string {
+	refs := make([]string, 0, len(chain))
+	for _, p := range chain {
+		refs = append(refs, p.String())
+	}
+	return strings.Join(refs, " -> ")
+}
+
+// resolution tracks the profiles resolved for an installation. It's safe for concurrent use by the nested
+// profiles being resolved at the same time.
+type resolution struct {
+	maxDepth int
+	mu       sync.Mutex
+	// resolved holds the resolution path of every profile, keyed by the profile regardless of its version.
+	resolved map[string][]profileRef
+}
+
+func newResolution(maxDepth int) *resolution {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	return &resolution{
+		maxDepth: maxDepth,
+		resolved: make(map[string][]profileRef),
+	}
+}
+
+// enter checks that the profile can be resolved from the chain of profiles including it, before it's cloned.
+// It errors if the profile is already part of the chain, even at another version, or the chain is too deep.
+func (r *resolution) enter(chain []profileRef, p profileRef) error {
+	for _, parent := range chain {
+		if parent.key() == p.key() {
+			if parent.ref == p.ref {
+				p.name = parent.name
+			}
+			return fmt.Errorf("nested profiles contain a cycle: %s", trace(append(chain[:len(chain):len(chain)], p)))
+		}
+	}
+	if len(chain) > r.maxDepth {
+		return fmt.Errorf("nested profiles exceed the maximum depth of %d: %s", r.maxDepth, trace(append(chain[:len(chain):len(chain)], p)))
+	}
+	return nil
+}
+
+// record records the resolution path of the last profile of chain. It errors if the same profile was resolved at
+// another version elsewhere in the installation.
+func (r *resolution) record(chain []profileRef) error {
+	p := chain[len(chain)-1]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	other, ok := r.resolved[p.key()]
+	if !ok {
+		r.resolved[p.key()] = chain
+		return nil
+	}
+	if other[len(other)-1].ref == p.ref {
+		return nil
+	}
+	traces := []string{trace(other), trace(chain)}
+	sort.Strings(traces)
+	return fmt.Errorf("profile %s is included at conflicting versions: %s and %s", p.name, traces[0], traces[1])
+}
+
+// lock returns the profiles resolved for the installation, sorted by repository, path and ref.
+func (r *resolution) lock() Lock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lock := Lock{Profiles: make([]LockedProfile, 0, len(r.resolved))}
+	for _, chain := range r.resolved {
+		p := chain[len(chain)-1]
+		lock.Profiles = append(lock.Profiles, LockedProfile{
+			Name:   p.name,
+			URL:    p.url,
+			Ref:    p.ref,
+			Path:   path.Clean(p.path),
+			Commit: p.commit,
+		})
+	}
+	sort.Slice(lock.Profiles, func(i, j int) bool {
+		a, b := lock.Profiles[i], lock.Profiles[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Ref < b.Ref
+	})
+	return lock
+}
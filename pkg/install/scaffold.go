@@ -0,0 +1,84 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/install/artifact"
+)
+
+const chartValuesFile = "values.yaml"
+
+// ScaffoldValues returns a values ConfigMap for installation named configMap, with a key for every chart artifact
+// holding its default values commented out. Nested profiles are included. The values of local charts are read from
+// the values.yaml of the chart in the profile repository, followed by the default values of the profile artifact.
+func ScaffoldValues(installation profilesv1.ProfileInstallation, artifacts []artifact.ArtifactWrapper, configMap string) ([]byte, error) {
+	data := make(map[string]string)
+	for _, a := range artifacts {
+		if a.Chart == nil {
+			continue
+		}
+		var sections []string
+		if a.Chart.Path != "" {
+			content, err := ioutil.ReadFile(filepath.Join(a.PathToProfileClone, a.Chart.Path, chartValuesFile))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read values of chart %q: %w", a.Name, err)
+			}
+			if len(bytes.TrimSpace(content)) > 0 {
+				sections = append(sections, fmt.Sprintf("# values.yaml of chart %s in profile %s:\n%s", a.Chart.Path, a.ProfileName, comment(string(content))))
+			}
+		}
+		if strings.TrimSpace(a.Chart.DefaultValues) != "" {
+			sections = append(sections, fmt.Sprintf("# default values of artifact %s in profile %s:\n%s", a.Name, a.ProfileName, comment(a.Chart.DefaultValues)))
+		}
+		if len(sections) == 0 {
+			sections = append(sections, fmt.Sprintf("# artifact %s in profile %s has no default values\n", a.Name, a.ProfileName))
+		}
+		key := artifact.ValuesKey(a.Name)
+		if existing, ok := data[key]; ok {
+			// artifacts of nested profiles with the same name share their values.
+			sections = append([]string{existing}, sections...)
+		}
+		data[key] = strings.Join(sections, "\n")
+	}
+
+	cfgMap := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMap,
+			Namespace: installation.Namespace,
+		},
+		Data: data,
+	}
+	out, err := yaml.Marshal(cfgMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values ConfigMap: %w", err)
+	}
+	header := fmt.Sprintf("# Values of profile installation %s. Uncomment and change the values to override in each chart artifact.\n", installation.Name)
+	return append([]byte(header), out...), nil
+}
+
+// comment prefixes every line of values with a #.
+func comment(values string) string {
+	lines := strings.Split(strings.TrimRight(values, "\n"), "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = "#"
+			continue
+		}
+		lines[i] = "# " + l
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,90 @@
+package install_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/install/artifact"
+)
+
+var _ = Describe("ScaffoldValues", func() {
+	var (
+		cloneDir     string
+		installation profilesv1.ProfileInstallation
+	)
+
+	BeforeEach(func() {
+		var err error
+		cloneDir, err = ioutil.TempDir("", "scaffold")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(cloneDir, "charts/nginx"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(cloneDir, "charts/nginx/values.yaml"), []byte("replicaCount: 1\n\nimage: nginx\n"), 0644)).To(Succeed())
+		installation = profilesv1.ProfileInstallation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-installation",
+				Namespace: "my-namespace",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(cloneDir)
+	})
+
+	It("comments out the default values of every chart artifact", func() {
+		artifacts := []artifact.ArtifactWrapper{
+			{
+				Artifact: profilesv1.Artifact{
+					Name:  "local",
+					Chart: &profilesv1.Chart{Path: "charts/nginx", DefaultValues: "service:\n  port: 8080\n"},
+				},
+				PathToProfileClone: cloneDir,
+				ProfileName:        "nginx",
+			},
+			{
+				Artifact: profilesv1.Artifact{
+					Name:  "redis",
+					Chart: &profilesv1.Chart{URL: "https://charts.bitnami.com/bitnami", Name: "redis"},
+				},
+				ProfileName:                   "cache",
+				NestedProfileSubDirectoryName: "cache",
+			},
+			{
+				Artifact: profilesv1.Artifact{
+					Name:      "config",
+					Kustomize: &profilesv1.Kustomize{Path: "config"},
+				},
+				ProfileName: "nginx",
+			},
+		}
+		out, err := install.ScaffoldValues(installation, artifacts, "my-values")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(`# Values of profile installation my-installation. Uncomment and change the values to override in each chart artifact.
+apiVersion: v1
+data:
+  local: |
+    # values.yaml of chart charts/nginx in profile nginx:
+    # replicaCount: 1
+    #
+    # image: nginx
+
+    # default values of artifact local in profile nginx:
+    # service:
+    #   port: 8080
+  redis: |
+    # artifact redis in profile cache has no default values
+kind: ConfigMap
+metadata:
+  creationTimestamp: null
+  name: my-values
+  namespace: my-namespace
+`))
+	})
+})
@@ -0,0 +1,108 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// BuildValues returns the values of the chart artifacts of an installation keyed by the name
+// makeHelmReleaseObjects looks them up with. valuesFiles are given as <artifact>=<path> and
+// overrides as <artifact>.<key>[.<key>...]=<value>, which take precedence over the values files.
+func BuildValues(valuesFiles, overrides []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, v := range valuesFiles {
+		artifact, file, ok := split(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("values %q must be in the format <artifact>=<path>", v)
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file for artifact %q: %w", artifact, err)
+		}
+		key := path.Base(artifact)
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("values for artifact %q given more than once", artifact)
+		}
+		values[key] = string(content)
+	}
+
+	parsed := make(map[string]map[string]interface{})
+	for _, o := range overrides {
+		keyPath, value, ok := split(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("value %q must be in the format <artifact>.<key>=<value>", o)
+		}
+		artifact, keys, ok := split(keyPath, ".")
+		if !ok || keys == "" {
+			return nil, fmt.Errorf("value %q must be in the format <artifact>.<key>=<value>", o)
+		}
+		key := path.Base(artifact)
+		m, ok := parsed[key]
+		if !ok {
+			m = make(map[string]interface{})
+			if err := yaml.Unmarshal([]byte(values[key]), &m); err != nil {
+				return nil, fmt.Errorf("failed to parse values of artifact %q: %w", artifact, err)
+			}
+			if m == nil {
+				m = make(map[string]interface{})
+			}
+			parsed[key] = m
+		}
+		if err := setValue(m, strings.Split(keys, "."), parseValue(value)); err != nil {
+			return nil, fmt.Errorf("failed to set value %q: %w", o, err)
+		}
+	}
+	for key, m := range parsed {
+		out, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal values of artifact %q: %w", key, err)
+		}
+		values[key] = string(out)
+	}
+	return values, nil
+}
+
+// setValue sets value at the path of keys in m, creating the maps in between.
+func setValue(m map[string]interface{}, keys []string, value interface{}) error {
+	for i, k := range keys[:len(keys)-1] {
+		next, ok := m[k]
+		if !ok {
+			nested := make(map[string]interface{})
+			m[k] = nested
+			m = nested
+			continue
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%q is not a map", strings.Join(keys[:i+1], "."))
+		}
+		m = nested
+	}
+	m[keys[len(keys)-1]] = value
+	return nil
+}
+
+// parseValue parses value as a YAML scalar so numbers and booleans keep their type, like helm's --set.
+func parseValue(value string) interface{} {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return value
+	}
+	return v
+}
+
+func split(s, sep string) (string, string, bool) {
+	i := strings.Index(s, sep)
+	if i <= 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,56 @@
+package install_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/install"
+)
+
+var _ = Describe("BuildValues", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "values")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "nginx.yaml"), []byte("# nginx values\nreplicaCount: 1\nservice:\n  type: ClusterIP\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("keys the values files by artifact name", func() {
+		values, err := install.BuildValues([]string{"nested-profile/nginx-server=" + filepath.Join(dir, "nginx.yaml")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]string{
+			"nginx-server": "# nginx values\nreplicaCount: 1\nservice:\n  type: ClusterIP\n",
+		}))
+	})
+
+	It("applies the overrides to the values files", func() {
+		values, err := install.BuildValues(
+			[]string{"nginx-server=" + filepath.Join(dir, "nginx.yaml")},
+			[]string{"nginx-server.replicaCount=2", "nginx-server.service.port=8080", "redis.auth.enabled=false", "redis.image.tag=6.2"},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]string{
+			"nginx-server": "replicaCount: 2\nservice:\n  port: 8080\n  type: ClusterIP\n",
+			"redis":        "auth:\n  enabled: false\nimage:\n  tag: 6.2\n",
+		}))
+	})
+
+	It("errors for malformed values", func() {
+		_, err := install.BuildValues([]string{"values.yaml"}, nil)
+		Expect(err).To(MatchError(`values "values.yaml" must be in the format <artifact>=<path>`))
+		_, err = install.BuildValues(nil, []string{"replicaCount=2"})
+		Expect(err).To(MatchError(`value "replicaCount=2" must be in the format <artifact>.<key>=<value>`))
+		_, err = install.BuildValues([]string{"nginx=" + filepath.Join(dir, "nginx.yaml")}, []string{"nginx.replicaCount.max=2"})
+		Expect(err).To(MatchError(`failed to set value "nginx.replicaCount.max=2": "replicaCount" is not a map`))
+	})
+})
@@ -1,6 +1,7 @@
 package installation
 
 import (
+	"context"
 	"fmt"
 
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
@@ -19,9 +20,9 @@ type Summary struct {
 }
 
 // List returns a list of installations
-func (sm *Manager) List() ([]Summary, error) {
+func (sm *Manager) List(ctx context.Context) ([]Summary, error) {
 	var installations profilesv1.ProfileInstallationList
-	err := sm.kClient.List(sm.ctx, &installations)
+	err := sm.kClient.List(ctx, &installations)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list profile installations: %w", err)
 	}
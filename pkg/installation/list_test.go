@@ -89,7 +89,7 @@ var _ = Describe("List", func() {
 	})
 
 	It("returns a list of profiles deployed in the cluster", func() {
-		subs, err := sm.List()
+		subs, err := sm.List(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 		Expect(subs).To(ConsistOf(
 			installation.Summary{
@@ -134,7 +134,7 @@ var _ = Describe("List", func() {
 		})
 
 		It("returns an error", func() {
-			_, err := sm.List()
+			_, err := sm.List(context.Background())
 			Expect(err).To(MatchError(ContainSubstring("failed to list profile installations:")))
 		})
 	})
@@ -2,8 +2,17 @@ package log
 
 import (
 	"fmt"
+	"io"
+	"os"
 )
 
+var out io.Writer
+
+// SetOutput sets where messages are written to. Defaults to, and is reset by nil to, stdout.
+func SetOutput(w io.Writer) {
+	out = w
+}
+
 func Actionf(m string, a ...interface{}) {
 	format(`►`, m, a...)
 }
@@ -25,5 +34,9 @@ func Failuref(m string, a ...interface{}) {
 }
 
 func format(tickmark, m string, a ...interface{}) {
-	fmt.Println(tickmark, fmt.Sprintf(m, a...))
+	w := out
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintln(w, tickmark, fmt.Sprintf(m, a...))
 }
@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 
@@ -45,3 +46,16 @@ var _ = Describe("PrintLogger", func() {
 `))
 	})
 })
+
+var _ = Describe("SetOutput", func() {
+	AfterEach(func() {
+		log.SetOutput(nil)
+	})
+
+	It("logs messages to the given writer", func() {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		log.Actionf("test action")
+		Expect(buf.String()).To(Equal("► test action\n"))
+	})
+})
@@ -0,0 +1,270 @@
+package regenerate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/upgrade"
+	"github.com/weaveworks/pctl/pkg/upgrade/repo"
+)
+
+const installationFile = "profile-installation.yaml"
+
+// Mode is how an installation is generated into a directory which already contains one.
+type Mode string
+
+const (
+	// Refuse errors when the directory already contains an installation.
+	Refuse Mode = "refuse"
+	// Force removes the existing installation before generating the new one, so no stale files are left behind.
+	Force Mode = "force"
+	// Merge three-way merges the new installation with the local changes made to the existing one.
+	Merge Mode = "merge"
+)
+
+// Config holds the fields used to generate an installation into a directory which may already contain one.
+type Config struct {
+	Mode           Mode
+	CatalogManager catalog.CatalogManager
+	// Installer generates the installation into its root directory.
+	Installer *install.Installer
+	// NewRepoManager creates the repository manager used to merge in the working directory dir.
+	NewRepoManager func(dir string) repo.RepoManager
+	// Locked generates the installation from the commits recorded in the lock file of its directory.
+	Locked bool
+}
+
+// Summary lists the files of an existing installation changed by generating it again, relative to its directory.
+type Summary struct {
+	Added     []string
+	Replaced  []string
+	Removed   []string
+	Conflicts []string
+}
+
+// Exists returns whether dir contains a profile installation.
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, installationFile))
+	return err == nil
+}
+
+// Install generates the installation of installCfg with the installer of cfg. If its root directory already
+// contains an installation, it's handled according to the mode of cfg and the changed files are summarised and logged.
+// The summary is empty when there was no existing installation.
+func Install(ctx context.Context, cfg Config, installCfg catalog.InstallConfig) (Summary, error) {
+	dir := cfg.Installer.RootDir
+	if cfg.Locked {
+		// the lock file is read before a forced install removes it.
+		lock, err := install.ReadLock(dir)
+		if err != nil {
+			return Summary{}, err
+		}
+		if lock == nil {
+			return Summary{}, fmt.Errorf("directory %s has no lock file to generate the installation from", dir)
+		}
+		lockedConfig := cfg.Installer.Config
+		lockedConfig.Lock = lock
+		cfg.Installer = cfg.Installer.WithConfig(lockedConfig)
+	}
+	installCfg.Clients.Installer = cfg.Installer
+	if !Exists(dir) {
+		return Summary{}, cfg.CatalogManager.Install(ctx, installCfg)
+	}
+	if cfg.Installer.Sink != nil {
+		// nothing is written to disk, so the existing installation is left as is.
+		return Summary{}, cfg.CatalogManager.Install(ctx, installCfg)
+	}
+
+	before, err := readFiles(dir)
+	if err != nil {
+		return Summary{}, err
+	}
+	var conflicts []string
+	switch cfg.Mode {
+	case Force:
+		if err := replace(dir, func() error {
+			return cfg.CatalogManager.Install(ctx, installCfg)
+		}); err != nil {
+			return Summary{}, err
+		}
+	case Merge:
+		conflicts, err = merge(ctx, cfg, installCfg)
+		if err != nil {
+			return Summary{}, err
+		}
+	default:
+		return Summary{}, fmt.Errorf("directory %s already contains a profile installation; use --force to generate it again or --merge to keep the local changes made to it", dir)
+	}
+
+	after, err := readFiles(dir)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary := Summary{Conflicts: conflicts}
+	for name, content := range after {
+		previous, ok := before[name]
+		if !ok {
+			summary.Added = append(summary.Added, name)
+		} else if !bytes.Equal(previous, content) {
+			summary.Replaced = append(summary.Replaced, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			summary.Removed = append(summary.Removed, name)
+		}
+	}
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Replaced)
+	sort.Strings(summary.Removed)
+	summary.log(dir)
+	if len(conflicts) > 0 {
+		return summary, upgrade.MergeConflictsError("installation generated", dir, conflicts)
+	}
+	return summary, nil
+}
+
+// replace generates a new installation into dir with install, so no stale files are left behind. The existing
+// installation is moved next to dir first and only removed once install succeeds, it's restored otherwise. The
+// installation is generated into dir itself as its path is part of the generated files.
+func replace(dir string, install func() error) error {
+	backup, err := ioutil.TempDir(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create backup of existing profile installation: %w", err)
+	}
+	if err := os.Remove(backup); err != nil {
+		return fmt.Errorf("failed to create backup of existing profile installation: %w", err)
+	}
+	if err := os.Rename(dir, backup); err != nil {
+		return fmt.Errorf("failed to move existing profile installation: %w", err)
+	}
+	if err := install(); err != nil {
+		if rerr := os.RemoveAll(dir); rerr != nil {
+			return fmt.Errorf("%w; failed to remove the partial installation, the existing one is kept in %s: %v", err, backup, rerr)
+		}
+		if rerr := os.Rename(backup, dir); rerr != nil {
+			return fmt.Errorf("%w; failed to restore the existing installation from %s: %v", err, backup, rerr)
+		}
+		return err
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		log.Warningf("failed to remove the previous profile installation %q: %v", backup, err)
+	}
+	return nil
+}
+
+// merge generates the installation the existing one was generated from and the new installation in a working
+// directory, and merges them with the local changes.
+func merge(ctx context.Context, cfg Config, installCfg catalog.InstallConfig) ([]string, error) {
+	dir := cfg.Installer.RootDir
+	content, err := ioutil.ReadFile(filepath.Join(dir, installationFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile installation: %w", err)
+	}
+	var existing profilesv1.ProfileInstallation
+	if err := yaml.Unmarshal(content, &existing); err != nil {
+		return nil, fmt.Errorf("failed to parse profile installation: %w", err)
+	}
+	if existing.Spec.Source == nil {
+		return nil, fmt.Errorf("unable to merge with profile installation %q which has no source", existing.Name)
+	}
+
+	workingDir, err := ioutil.TempDir("", "profile-merge")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(workingDir); err != nil {
+			log.Warningf("failed to cleanup temp directory %q: %v", workingDir, err)
+		}
+	}()
+
+	lock, err := install.ReadLock(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	baseConfig := cfg.Installer.Config
+	baseConfig.RootDir = workingDir
+	// the values given now aren't part of what the existing installation was generated from.
+	baseConfig.Values = nil
+	// installations generated before lock files existed are generated from the latest commits.
+	baseConfig.Lock = lock
+	if existing.Spec.GitRepository != nil {
+		baseConfig.GitRepoNamespace = existing.Spec.GitRepository.Namespace
+		baseConfig.GitRepoName = existing.Spec.GitRepository.Name
+	}
+	updateConfig := cfg.Installer.Config
+	updateConfig.RootDir = workingDir
+	installCfg.Clients.Installer = cfg.Installer.WithConfig(updateConfig)
+
+	return upgrade.Merge(upgrade.MergeConfig{
+		ProfileDir:  dir,
+		WorkingDir:  workingDir,
+		RepoManager: cfg.NewRepoManager(workingDir),
+		Base: func() error {
+			if err := cfg.Installer.WithConfig(baseConfig).Install(existing); err != nil {
+				return fmt.Errorf("failed to generate existing profile installation: %w", err)
+			}
+			return nil
+		},
+		Update: func() error {
+			return cfg.CatalogManager.Install(ctx, installCfg)
+		},
+	})
+}
+
+// log prints the summary of the installation generated again in dir.
+func (s Summary) log(dir string) {
+	log.Actionf("generated existing profile installation in %s again: %d added, %d replaced, %d removed files", dir, len(s.Added), len(s.Replaced), len(s.Removed))
+	for _, f := range s.Added {
+		log.Actionf("added %s", filepath.Join(dir, f))
+	}
+	for _, f := range s.Replaced {
+		log.Actionf("replaced %s", filepath.Join(dir, f))
+	}
+	for _, f := range s.Removed {
+		log.Actionf("removed %s", filepath.Join(dir, f))
+	}
+}
+
+// readFiles returns the content of every file in dir, keyed by their path relative to dir.
+func readFiles(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation directory %s: %w", dir, err)
+	}
+	return files, nil
+}
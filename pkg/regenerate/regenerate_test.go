@@ -0,0 +1,230 @@
+package regenerate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	gitfakes "github.com/weaveworks/pctl/pkg/git/fakes"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/install/artifact"
+	"github.com/weaveworks/pctl/pkg/regenerate"
+	"github.com/weaveworks/pctl/pkg/upgrade/repo"
+	repofakes "github.com/weaveworks/pctl/pkg/upgrade/repo/fakes"
+)
+
+var _ = Describe("Install", func() {
+	var (
+		fakeCatalogManager *fakes.FakeCatalogManager
+		fakeRepoManager    *repofakes.FakeRepoManager
+		cfg                regenerate.Config
+		dir                string
+		mergeDir           string
+	)
+
+	writeFiles := func(dir string, files map[string]string) {
+		for name, content := range files {
+			Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "regenerate")
+		Expect(err).NotTo(HaveOccurred())
+		dir = filepath.Join(dir, "nginx")
+		fakeCatalogManager = new(fakes.FakeCatalogManager)
+		fakeCatalogManager.InstallStub = func(_ context.Context, installCfg catalog.InstallConfig) error {
+			installer := installCfg.Clients.Installer.(*install.Installer)
+			writeFiles(installer.RootDir, map[string]string{
+				"profile-installation.yaml":                 "version: v0.1.1",
+				"artifacts/nginx-server/kustomization.yaml": "resources: []",
+			})
+			return nil
+		}
+		fakeRepoManager = new(repofakes.FakeRepoManager)
+		fakeRepoManager.CreateBranchWithContentFromMainStub = func(_ string, writeContent func() error) error {
+			return writeContent()
+		}
+		cfg = regenerate.Config{
+			Mode:           regenerate.Refuse,
+			CatalogManager: fakeCatalogManager,
+			Installer:      install.NewInstaller(install.Config{GitClient: new(gitfakes.FakeGit), RootDir: dir}),
+			NewRepoManager: func(dir string) repo.RepoManager {
+				mergeDir = dir
+				return fakeRepoManager
+			},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(filepath.Dir(dir))
+	})
+
+	When("the directory doesn't contain an installation", func() {
+		It("generates the installation", func() {
+			summary, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary).To(Equal(regenerate.Summary{}))
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(1))
+			_, installCfg := fakeCatalogManager.InstallArgsForCall(0)
+			Expect(installCfg.Clients.Installer).To(Equal(cfg.Installer))
+			Expect(regenerate.Exists(dir)).To(BeTrue())
+		})
+	})
+
+	When("the directory already contains an installation", func() {
+		BeforeEach(func() {
+			writeFiles(dir, map[string]string{
+				"profile-installation.yaml":                 "version: v0.1.0",
+				"artifacts/nginx-chart/HelmRelease.yaml":    "kind: HelmRelease",
+				"artifacts/nginx-server/kustomization.yaml": "resources: []",
+			})
+		})
+
+		It("refuses to generate the installation by default", func() {
+			_, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+			Expect(err).To(MatchError(fmt.Sprintf("directory %s already contains a profile installation; use --force to generate it again or --merge to keep the local changes made to it", dir)))
+			Expect(fakeCatalogManager.InstallCallCount()).To(Equal(0))
+		})
+
+		When("forced", func() {
+			It("generates the installation again without the stale files", func() {
+				cfg.Mode = regenerate.Force
+				summary, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(summary).To(Equal(regenerate.Summary{
+					Replaced: []string{"profile-installation.yaml"},
+					Removed:  []string{"artifacts/nginx-chart/HelmRelease.yaml"},
+				}))
+				_, err = os.Stat(filepath.Join(dir, "artifacts/nginx-chart"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				siblings, err := ioutil.ReadDir(filepath.Dir(dir))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(siblings).To(HaveLen(1))
+			})
+
+			It("keeps the existing installation when generating it fails", func() {
+				cfg.Mode = regenerate.Force
+				fakeCatalogManager.InstallStub = func(_ context.Context, installCfg catalog.InstallConfig) error {
+					installer := installCfg.Clients.Installer.(*install.Installer)
+					writeFiles(installer.RootDir, map[string]string{"profile-installation.yaml": "version: v0.1.1"})
+					return errors.New("catalog unavailable")
+				}
+				_, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).To(MatchError("catalog unavailable"))
+
+				content, err := ioutil.ReadFile(filepath.Join(dir, "profile-installation.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("version: v0.1.0"))
+				_, err = os.Stat(filepath.Join(dir, "artifacts/nginx-chart/HelmRelease.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+				siblings, err := ioutil.ReadDir(filepath.Dir(dir))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(siblings).To(HaveLen(1))
+			})
+
+			It("generates the installation from the commits of the lock file when locked", func() {
+				writeFiles(dir, map[string]string{
+					install.LockFile: `profiles:
+- commit: 3b5e4bc
+  name: nginx
+  path: nginx
+  ref: main
+  url: https://github.com/weaveworks/profiles-examples
+`,
+				})
+				cfg.Mode = regenerate.Force
+				cfg.Locked = true
+				_, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).NotTo(HaveOccurred())
+				_, installCfg := fakeCatalogManager.InstallArgsForCall(0)
+				Expect(installCfg.Clients.Installer.(*install.Installer).Lock).To(Equal(&install.Lock{
+					Profiles: []install.LockedProfile{{
+						Name:   "nginx",
+						URL:    "https://github.com/weaveworks/profiles-examples",
+						Ref:    "main",
+						Path:   "nginx",
+						Commit: "3b5e4bc",
+					}},
+				}))
+			})
+
+			It("fails without a lock file when locked", func() {
+				cfg.Mode = regenerate.Force
+				cfg.Locked = true
+				_, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).To(MatchError(fmt.Sprintf("directory %s has no lock file to generate the installation from", dir)))
+				Expect(fakeCatalogManager.InstallCallCount()).To(Equal(0))
+			})
+		})
+
+		When("merged", func() {
+			BeforeEach(func() {
+				cfg.Mode = regenerate.Merge
+				writeFiles(dir, map[string]string{
+					"profile-installation.yaml": `apiVersion: weave.works/v1alpha1
+kind: ProfileInstallation
+metadata:
+  name: nginx
+spec:
+  source:
+    url: https://github.com/weaveworks/profiles-examples
+    branch: main
+    path: nginx
+`,
+				})
+			})
+
+			It("merges the generated installation with the local changes", func() {
+				summary, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(summary.Replaced).To(Equal([]string{"profile-installation.yaml"}))
+				Expect(summary.Removed).To(BeEmpty())
+
+				By("generating the new installation in the working directory")
+				_, installCfg := fakeCatalogManager.InstallArgsForCall(0)
+				Expect(installCfg.Clients.Installer.(*install.Installer).RootDir).To(Equal(mergeDir))
+				Expect(fakeRepoManager.CreateRepoWithContentCallCount()).To(Equal(1))
+				branch1, branch2 := fakeRepoManager.MergeBranchesArgsForCall(0)
+				Expect([]string{branch1, branch2}).To(Equal([]string{"update-changes", "user-changes"}))
+
+				content, err := ioutil.ReadFile(filepath.Join(dir, "profile-installation.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("version: v0.1.1"))
+				_, err = os.Stat(mergeDir)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			It("reports the merge conflicts", func() {
+				fakeRepoManager.MergeBranchesReturns([]string{"profile-installation.yaml"}, nil)
+				summary, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).To(MatchError(fmt.Sprintf("installation generated but merge conflicts have occurred, please resolve manually. Files containing conflicts:\n- %s", filepath.Join(dir, "profile-installation.yaml"))))
+				Expect(summary.Conflicts).To(Equal([]string{"profile-installation.yaml"}))
+			})
+		})
+
+		When("the installer doesn't write to disk", func() {
+			It("leaves the existing installation as is", func() {
+				sink := &artifact.MemorySink{}
+				cfg.Installer = install.NewInstaller(install.Config{GitClient: new(gitfakes.FakeGit), RootDir: dir, Sink: sink})
+				fakeCatalogManager.InstallReturns(nil)
+				fakeCatalogManager.InstallStub = nil
+				_, err := regenerate.Install(context.Background(), cfg, catalog.InstallConfig{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCatalogManager.InstallCallCount()).To(Equal(1))
+				_, err = os.Stat(filepath.Join(dir, "artifacts/nginx-chart/HelmRelease.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,252 @@
+package repocache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gofrs/flock"
+	copypkg "github.com/otiai10/copy"
+
+	"github.com/weaveworks/pctl/pkg/git"
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/paths"
+)
+
+const (
+	entryFile = "entry.json"
+	cloneDir  = "repo"
+	lockExt   = ".lock"
+)
+
+// Options holds options for the repository cache.
+type Options struct {
+	// Dir is the directory clones are stored in. Defaults to <user cache dir>/pctl/repos.
+	Dir string
+	// Offline uses the cached clones as they are, without fetching them.
+	Offline bool
+}
+
+// Cache is a persistent cache of profile repository clones shared by pctl runs. Every repository is cloned
+// once for each branch or tag and fetched incrementally afterwards. Concurrent runs are serialised per clone
+// with file locks.
+type Cache struct {
+	Options
+	gitClient git.Git
+	now       func() time.Time
+}
+
+// Entry is a cached clone of a repository.
+type Entry struct {
+	URL string `json:"url"`
+	// Ref is the branch or tag of the clone.
+	Ref       string    `json:"ref"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	UsedAt    time.Time `json:"usedAt"`
+	// Dir is the directory of the entry in the cache.
+	Dir string `json:"-"`
+	// Size is the disk usage of the entry in bytes.
+	Size int64 `json:"-"`
+}
+
+// New creates a repository cache which clones and fetches with gitClient.
+func New(gitClient git.Git, options Options) (*Cache, error) {
+	if options.Dir == "" {
+		dir, err := paths.CacheDir()
+		if err != nil {
+			return nil, err
+		}
+		options.Dir = filepath.Join(dir, "repos")
+	}
+	return &Cache{
+		Options:   options,
+		gitClient: gitClient,
+		now:       time.Now,
+	}, nil
+}
+
+// Clone copies the files of the repository at the branch or tag into location, cloning it into the cache
+// first or fetching the cached clone. The cached clone is only used as it is in offline mode. When revision is set,
+// that commit of the branch is copied instead of its latest one, fetching it only if the cached clone doesn't
+// have it. It returns the commit copied.
+func (c *Cache) Clone(repo, branch, revision, location string) (string, error) {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create repository cache directory: %w", err)
+	}
+	dir := c.entryDir(repo, branch)
+	lock := flock.New(dir + lockExt)
+	if err := lock.Lock(); err != nil {
+		return "", fmt.Errorf("failed to lock cached repository %q: %w", repo, err)
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	entry, err := load(dir)
+	if err != nil {
+		log.Warningf("ignoring unreadable cached repository %q: %v", dir, err)
+		entry = nil
+	}
+	clone := filepath.Join(dir, cloneDir)
+	switch {
+	case entry == nil && c.Offline:
+		return "", fmt.Errorf("no cached clone of %q branch %q available in offline mode", repo, branch)
+	case entry == nil:
+		// remove whatever an interrupted clone left behind.
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("failed to remove cached repository %q: %w", dir, err)
+		}
+		if err := c.gitClient.Clone(repo, branch, clone); err != nil {
+			return "", err
+		}
+		entry = &Entry{URL: repo, Ref: branch, FetchedAt: c.now()}
+	case !c.Offline && revision == "":
+		// a stale clone is only used when asked for, the profile may have changed since.
+		if err := c.gitClient.Fetch(branch, clone); err != nil {
+			return "", fmt.Errorf("failed to fetch %q branch %q, use --offline to use the cached clone from %s: %w", repo, branch, entry.FetchedAt.Format(time.RFC3339), err)
+		}
+		entry.FetchedAt = c.now()
+	}
+	if revision != "" {
+		if err := c.gitClient.FetchRevision(branch, revision, clone); err != nil {
+			return "", err
+		}
+	}
+	checkedOut, err := c.gitClient.Revision(clone)
+	if err != nil {
+		return "", err
+	}
+	entry.UsedAt = c.now()
+	if err := store(dir, entry); err != nil {
+		return "", err
+	}
+
+	err = copypkg.Copy(clone, location, copypkg.Options{
+		Skip: func(src string) (bool, error) {
+			return filepath.Base(src) == ".git", nil
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy cached repository %q: %w", repo, err)
+	}
+	return checkedOut, nil
+}
+
+// List returns the cached clones sorted by url and ref.
+func (c *Cache) List() ([]Entry, error) {
+	infos, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read repository cache: %w", err)
+	}
+	var entries []Entry
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		dir := filepath.Join(c.Dir, info.Name())
+		entry, err := load(dir)
+		if err != nil || entry == nil {
+			// clones which are incomplete or being made right now.
+			continue
+		}
+		if entry.Size, err = size(dir); err != nil {
+			return nil, fmt.Errorf("failed to read cached repository %q: %w", dir, err)
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Ref < entries[j].Ref
+	})
+	return entries, nil
+}
+
+// Prune removes the cached clones which weren't used within unusedFor, or all of them if it's 0.
+// Clones being used by another run are skipped. It returns the removed entries.
+func (c *Cache) Prune(unusedFor time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	for _, entry := range entries {
+		if unusedFor > 0 && c.now().Sub(entry.UsedAt) < unusedFor {
+			continue
+		}
+		// the lock file is kept, removing it would let a concurrent run lock a file nobody else sees.
+		lock := flock.New(entry.Dir + lockExt)
+		locked, err := lock.TryLock()
+		if err != nil {
+			return removed, fmt.Errorf("failed to lock cached repository %q: %w", entry.Dir, err)
+		}
+		if !locked {
+			log.Warningf("skipping cached repository %q branch %q which is in use", entry.URL, entry.Ref)
+			continue
+		}
+		err = os.RemoveAll(entry.Dir)
+		_ = lock.Unlock()
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove cached repository %q: %w", entry.Dir, err)
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+func (c *Cache) entryDir(repo, branch string) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", repo, branch)
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// load returns the entry in dir, or nil if there is none.
+func load(dir string) (*Entry, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, entryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, err
+	}
+	entry.Dir = dir
+	return entry, nil
+}
+
+func store(dir string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached repository entry: %w", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, entryFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cached repository entry: %w", err)
+	}
+	return nil
+}
+
+// size returns the disk usage of the files in dir.
+func size(dir string) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,192 @@
+package repocache_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/weaveworks/pctl/pkg/git/fakes"
+	"github.com/weaveworks/pctl/pkg/repocache"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		tmp           string
+		now           time.Time
+		fakeGitClient *fakes.FakeGit
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "pctl-repocache")
+		Expect(err).NotTo(HaveOccurred())
+		now = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+		fakeGitClient = new(fakes.FakeGit)
+		fakeGitClient.RevisionReturns("abc123", nil)
+		fakeGitClient.CloneStub = func(repo, branch, location string) error {
+			Expect(os.MkdirAll(filepath.Join(location, ".git"), 0755)).To(Succeed())
+			return ioutil.WriteFile(filepath.Join(location, "profile.yaml"), []byte(repo+" "+branch), 0644)
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmp)
+	})
+
+	newCache := func(options repocache.Options) *repocache.Cache {
+		options.Dir = filepath.Join(tmp, "cache")
+		c, err := repocache.New(fakeGitClient, options)
+		Expect(err).NotTo(HaveOccurred())
+		c.SetNow(func() time.Time { return now })
+		return c
+	}
+	cloneInto := func(c *repocache.Cache, repo, branch string) string {
+		location, err := ioutil.TempDir(tmp, "clone")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.Clone(repo, branch, "", location)
+		Expect(err).NotTo(HaveOccurred())
+		return location
+	}
+
+	It("clones a repository once and fetches it afterwards", func() {
+		c := newCache(repocache.Options{})
+
+		location := cloneInto(c, "https://github.com/org/repo", "main")
+		content, err := ioutil.ReadFile(filepath.Join(location, "profile.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("https://github.com/org/repo main"))
+		_, err = os.Stat(filepath.Join(location, ".git"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(fakeGitClient.CloneCallCount()).To(Equal(1))
+		Expect(fakeGitClient.FetchCallCount()).To(Equal(0))
+
+		now = now.Add(time.Hour)
+		location = cloneInto(c, "https://github.com/org/repo", "main")
+		_, err = os.Stat(filepath.Join(location, "profile.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeGitClient.CloneCallCount()).To(Equal(1))
+		Expect(fakeGitClient.FetchCallCount()).To(Equal(1))
+		branch, cloneDir := fakeGitClient.FetchArgsForCall(0)
+		Expect(branch).To(Equal("main"))
+		_, _, cloned := fakeGitClient.CloneArgsForCall(0)
+		Expect(cloneDir).To(Equal(cloned))
+
+		By("keeping separate clones for every ref")
+		cloneInto(c, "https://github.com/org/repo", "v0.1.0")
+		Expect(fakeGitClient.CloneCallCount()).To(Equal(2))
+
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].URL).To(Equal("https://github.com/org/repo"))
+		Expect(entries[0].Ref).To(Equal("main"))
+		Expect(entries[0].FetchedAt).To(BeTemporally("==", now))
+		Expect(entries[0].Size).To(BeNumerically(">", 0))
+		Expect(entries[1].Ref).To(Equal("v0.1.0"))
+	})
+
+	It("returns an error when fetching fails", func() {
+		c := newCache(repocache.Options{})
+		cloneInto(c, "https://github.com/org/repo", "main")
+		fakeGitClient.FetchReturns(errors.New("nope"))
+		now = now.Add(time.Hour)
+
+		location, err := ioutil.TempDir(tmp, "clone")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.Clone("https://github.com/org/repo", "main", "", location)
+		Expect(err).To(MatchError(ContainSubstring(`failed to fetch "https://github.com/org/repo" branch "main", use --offline to use the cached clone from`)))
+		Expect(errors.Unwrap(err)).To(MatchError("nope"))
+		_, err = os.Stat(filepath.Join(location, "profile.yaml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("checks out the revision of the branch instead of fetching it", func() {
+		c := newCache(repocache.Options{})
+		cloneInto(c, "https://github.com/org/repo", "main")
+		fakeGitClient.RevisionReturns("def456", nil)
+
+		location, err := ioutil.TempDir(tmp, "clone")
+		Expect(err).NotTo(HaveOccurred())
+		revision, err := c.Clone("https://github.com/org/repo", "main", "def456", location)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revision).To(Equal("def456"))
+		Expect(fakeGitClient.FetchCallCount()).To(Equal(0))
+		Expect(fakeGitClient.FetchRevisionCallCount()).To(Equal(1))
+		branch, rev, cloneDir := fakeGitClient.FetchRevisionArgsForCall(0)
+		Expect(branch).To(Equal("main"))
+		Expect(rev).To(Equal("def456"))
+		_, _, cloned := fakeGitClient.CloneArgsForCall(0)
+		Expect(cloneDir).To(Equal(cloned))
+	})
+
+	It("returns the error when cloning fails", func() {
+		fakeGitClient.CloneReturns(errors.New("nope"))
+		c := newCache(repocache.Options{})
+		_, err := c.Clone("https://github.com/org/repo", "main", "", tmp)
+		Expect(err).To(MatchError("nope"))
+		entries, err := c.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	When("offline", func() {
+		It("uses the cached clone without fetching", func() {
+			cloneInto(newCache(repocache.Options{}), "https://github.com/org/repo", "main")
+			c := newCache(repocache.Options{Offline: true})
+			cloneInto(c, "https://github.com/org/repo", "main")
+			Expect(fakeGitClient.CloneCallCount()).To(Equal(1))
+			Expect(fakeGitClient.FetchCallCount()).To(Equal(0))
+		})
+
+		It("fails if the repository isn't cached", func() {
+			c := newCache(repocache.Options{Offline: true})
+			_, err := c.Clone("https://github.com/org/repo", "main", "", tmp)
+			Expect(err).To(MatchError(`no cached clone of "https://github.com/org/repo" branch "main" available in offline mode`))
+		})
+	})
+
+	Context("Prune", func() {
+		It("removes the clones which weren't used recently", func() {
+			c := newCache(repocache.Options{})
+			cloneInto(c, "https://github.com/org/old", "main")
+			now = now.Add(48 * time.Hour)
+			cloneInto(c, "https://github.com/org/new", "main")
+
+			removed, err := c.Prune(24 * time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(1))
+			Expect(removed[0].URL).To(Equal("https://github.com/org/old"))
+			_, err = os.Stat(removed[0].Dir)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			entries, err := c.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].URL).To(Equal("https://github.com/org/new"))
+		})
+
+		It("removes every clone which isn't in use", func() {
+			c := newCache(repocache.Options{})
+			cloneInto(c, "https://github.com/org/used", "main")
+			cloneInto(c, "https://github.com/org/unused", "main")
+			entries, err := c.List()
+			Expect(err).NotTo(HaveOccurred())
+			lock := flock.New(entries[1].Dir + ".lock")
+			Expect(lock.Lock()).To(Succeed())
+			defer func() {
+				_ = lock.Unlock()
+			}()
+
+			removed, err := c.Prune(0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(HaveLen(1))
+			Expect(removed[0].URL).To(Equal("https://github.com/org/unused"))
+		})
+	})
+})
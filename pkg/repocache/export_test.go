@@ -0,0 +1,7 @@
+package repocache
+
+import "time"
+
+func (c *Cache) SetNow(now func() time.Time) {
+	c.now = now
+}
@@ -0,0 +1,13 @@
+package repocache_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRepocache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Repocache Suite")
+}
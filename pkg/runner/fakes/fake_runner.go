@@ -22,6 +22,21 @@ type FakeRunner struct {
 		result1 []byte
 		result2 error
 	}
+	RunWithEnvStub        func([]string, string, ...string) ([]byte, error)
+	runWithEnvMutex       sync.RWMutex
+	runWithEnvArgsForCall []struct {
+		arg1 []string
+		arg2 string
+		arg3 []string
+	}
+	runWithEnvReturns struct {
+		result1 []byte
+		result2 error
+	}
+	runWithEnvReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -91,11 +106,84 @@ func (fake *FakeRunner) RunReturnsOnCall(i int, result1 []byte, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeRunner) RunWithEnv(arg1 []string, arg2 string, arg3 ...string) ([]byte, error) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.runWithEnvMutex.Lock()
+	ret, specificReturn := fake.runWithEnvReturnsOnCall[len(fake.runWithEnvArgsForCall)]
+	fake.runWithEnvArgsForCall = append(fake.runWithEnvArgsForCall, struct {
+		arg1 []string
+		arg2 string
+		arg3 []string
+	}{arg1Copy, arg2, arg3})
+	stub := fake.RunWithEnvStub
+	fakeReturns := fake.runWithEnvReturns
+	fake.recordInvocation("RunWithEnv", []interface{}{arg1Copy, arg2, arg3})
+	fake.runWithEnvMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRunner) RunWithEnvCallCount() int {
+	fake.runWithEnvMutex.RLock()
+	defer fake.runWithEnvMutex.RUnlock()
+	return len(fake.runWithEnvArgsForCall)
+}
+
+func (fake *FakeRunner) RunWithEnvCalls(stub func([]string, string, ...string) ([]byte, error)) {
+	fake.runWithEnvMutex.Lock()
+	defer fake.runWithEnvMutex.Unlock()
+	fake.RunWithEnvStub = stub
+}
+
+func (fake *FakeRunner) RunWithEnvArgsForCall(i int) ([]string, string, []string) {
+	fake.runWithEnvMutex.RLock()
+	defer fake.runWithEnvMutex.RUnlock()
+	argsForCall := fake.runWithEnvArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRunner) RunWithEnvReturns(result1 []byte, result2 error) {
+	fake.runWithEnvMutex.Lock()
+	defer fake.runWithEnvMutex.Unlock()
+	fake.RunWithEnvStub = nil
+	fake.runWithEnvReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRunner) RunWithEnvReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.runWithEnvMutex.Lock()
+	defer fake.runWithEnvMutex.Unlock()
+	fake.RunWithEnvStub = nil
+	if fake.runWithEnvReturnsOnCall == nil {
+		fake.runWithEnvReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.runWithEnvReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRunner) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
+	fake.runWithEnvMutex.RLock()
+	defer fake.runWithEnvMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -1,12 +1,17 @@
 package runner
 
-import "os/exec"
+import (
+	"os"
+	"os/exec"
+)
 
 // Runner is an entity which can execute commands with whatever backing medium behind it.
 //go:generate counterfeiter -o fakes/fake_runner.go . Runner
 type Runner interface {
 	// Run takes a command name and some arguments and will apply it to the current environment.
 	Run(command string, args ...string) ([]byte, error)
+	// RunWithEnv runs the command like Run, adding env to the current environment.
+	RunWithEnv(env []string, command string, args ...string) ([]byte, error)
 }
 
 // CLIRunner will use exec.Command as runtime medium.
@@ -17,3 +22,10 @@ func (*CLIRunner) Run(c string, args ...string) ([]byte, error) {
 	cmd := exec.Command(c, args...)
 	return cmd.CombinedOutput()
 }
+
+// RunWithEnv will use exec.Command as a backing medium and run CombinedOutput with env added to the current environment.
+func (*CLIRunner) RunWithEnv(env []string, c string, args ...string) ([]byte, error) {
+	cmd := exec.Command(c, args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.CombinedOutput()
+}
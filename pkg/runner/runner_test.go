@@ -2,6 +2,7 @@ package runner_test
 
 import (
 	"errors"
+	"os"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -24,6 +25,15 @@ var _ = Describe("runner", func() {
 			Expect(string(out)).To(Equal("\n"))
 		})
 	})
+	When("there is an environment to run the commands in", func() {
+		It("adds it to the current environment", func() {
+			r := runner.CLIRunner{}
+			out, err := r.RunWithEnv([]string{"PCTL_RUNNER_TEST=value"}, "sh", "-c", "echo $PCTL_RUNNER_TEST $HOME")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out)).To(Equal("value " + os.Getenv("HOME") + "\n"))
+		})
+	})
+
 	When("the command does not exist", func() {
 		It("returns a sensible error", func() {
 			r := runner.CLIRunner{}
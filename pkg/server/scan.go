@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/log"
+	"github.com/weaveworks/pctl/pkg/runner"
+)
+
+const (
+	profileFile = "profile.yaml"
+	// DirectoryVersion is the version profiles found in directories which aren't git repositories are served with.
+	DirectoryVersion = "v0.0.0"
+)
+
+// Scanner finds profiles in local git repositories and directories.
+type Scanner struct {
+	// CatalogName is the catalog source set on every entry.
+	CatalogName string
+	Runner      runner.Runner
+}
+
+// Scan returns a catalog entry for every profile found at paths. In git repositories every tag
+// in the format <profile directory>/<semver> or <semver> for a profile at the root is an entry.
+// Other directories are walked for profile.yaml files, served with DirectoryVersion and the file:// URL of the
+// directory, which the installer reads in place.
+func (s *Scanner) Scan(paths []string) ([]profilesv1.ProfileCatalogEntry, error) {
+	var entries []profilesv1.ProfileCatalogEntry
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path %q: %w", p, err)
+		}
+		var found []profilesv1.ProfileCatalogEntry
+		if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+			found, err = s.scanRepository(abs)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			found, err = s.scanDirectory(abs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Actionf("found %d profile versions in %q", len(found), p)
+		entries = append(entries, found...)
+	}
+	return entries, nil
+}
+
+func (s *Scanner) scanRepository(dir string) ([]profilesv1.ProfileCatalogEntry, error) {
+	out, err := s.Runner.Run("git", "-C", dir, "tag", "--list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of repository %q: %s: %w", dir, strings.TrimSpace(string(out)), err)
+	}
+	url := dir
+	if remote, err := s.Runner.Run("git", "-C", dir, "config", "--get", "remote.origin.url"); err == nil && len(strings.TrimSpace(string(remote))) > 0 {
+		url = strings.TrimSpace(string(remote))
+	}
+
+	var entries []profilesv1.ProfileCatalogEntry
+	for _, tag := range strings.Fields(string(out)) {
+		version := profilesv1.GetVersionFromTag(tag)
+		if _, err := semver.NewVersion(version); err != nil {
+			continue
+		}
+		profilePath := profileFile
+		if version != tag {
+			profilePath = strings.TrimSuffix(tag, "/"+version) + "/" + profileFile
+		}
+		content, err := s.Runner.Run("git", "-C", dir, "show", fmt.Sprintf("%s:%s", tag, profilePath))
+		if err != nil {
+			// tags of other release artifacts don't point to a profile.
+			continue
+		}
+		entry, err := s.entry(content, tag, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s at tag %q in repository %q: %w", profilePath, tag, dir, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *Scanner) scanDirectory(dir string) ([]profilesv1.ProfileCatalogEntry, error) {
+	url, err := install.LocalURL(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []profilesv1.ProfileCatalogEntry
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() != profileFile {
+			return nil
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", p, err)
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		tag := DirectoryVersion
+		if rel != "." {
+			tag = filepath.ToSlash(rel) + "/" + DirectoryVersion
+		}
+		entry, err := s.entry(content, tag, url)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %w", p, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory %q: %w", dir, err)
+	}
+	return entries, nil
+}
+
+func (s *Scanner) entry(content []byte, tag, url string) (profilesv1.ProfileCatalogEntry, error) {
+	var def profilesv1.ProfileDefinition
+	if err := yaml.Unmarshal(content, &def); err != nil {
+		return profilesv1.ProfileCatalogEntry{}, err
+	}
+	if def.Name == "" {
+		return profilesv1.ProfileCatalogEntry{}, fmt.Errorf("profile has no name")
+	}
+	return profilesv1.ProfileCatalogEntry{
+		Name:               def.Name,
+		Tag:                tag,
+		URL:                url,
+		CatalogSource:      s.CatalogName,
+		ProfileDescription: def.Spec.ProfileDescription,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package server_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/runner/fakes"
+	"github.com/weaveworks/pctl/pkg/server"
+)
+
+const nginxProfile = `apiVersion: weave.works/v1alpha1
+kind: ProfileDefinition
+metadata:
+  name: nginx
+spec:
+  description: nginx profile
+  maintainer: weaveworks
+`
+
+var _ = Describe("Scanner", func() {
+	var (
+		tmp        string
+		fakeRunner *fakes.FakeRunner
+		scanner    *server.Scanner
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "pctl-serve")
+		Expect(err).NotTo(HaveOccurred())
+		fakeRunner = new(fakes.FakeRunner)
+		scanner = &server.Scanner{CatalogName: "local", Runner: fakeRunner}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmp)
+	})
+
+	When("the path is a directory", func() {
+		It("returns the profiles found in it", func() {
+			Expect(os.MkdirAll(filepath.Join(tmp, "nginx"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(tmp, "nginx", "profile.yaml"), []byte(nginxProfile), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(tmp, "README.md"), []byte("readme"), 0644)).To(Succeed())
+
+			entries, err := scanner.Scan([]string{tmp})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(ConsistOf(profilesv1.ProfileCatalogEntry{
+				Name:          "nginx",
+				Tag:           "nginx/" + server.DirectoryVersion,
+				URL:           "file://" + filepath.ToSlash(tmp),
+				CatalogSource: "local",
+				ProfileDescription: profilesv1.ProfileDescription{
+					Description: "nginx profile",
+					Maintainer:  "weaveworks",
+				},
+			}))
+			Expect(fakeRunner.RunCallCount()).To(Equal(0))
+		})
+
+		It("returns an error for invalid profiles", func() {
+			Expect(ioutil.WriteFile(filepath.Join(tmp, "profile.yaml"), []byte("kind: ProfileDefinition"), 0644)).To(Succeed())
+
+			_, err := scanner.Scan([]string{tmp})
+			Expect(err).To(MatchError(ContainSubstring("profile has no name")))
+		})
+	})
+
+	When("the path is a git repository", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(tmp, ".git"), 0755)).To(Succeed())
+		})
+
+		It("returns a profile for every version tag", func() {
+			fakeRunner.RunStub = func(_ string, args ...string) ([]byte, error) {
+				switch args[2] {
+				case "tag":
+					return []byte("nginx/v0.1.0\nnginx/v0.2.0\nv1.0.0\nnot-a-version\ndocs/v0.1.0\n"), nil
+				case "config":
+					return []byte("https://github.com/org/profiles\n"), nil
+				case "show":
+					switch args[3] {
+					case "nginx/v0.1.0:nginx/profile.yaml", "nginx/v0.2.0:nginx/profile.yaml", "v1.0.0:profile.yaml":
+						return []byte(nginxProfile), nil
+					}
+				}
+				return []byte("fatal: path does not exist"), errors.New("exit status 128")
+			}
+
+			entries, err := scanner.Scan([]string{tmp})
+			Expect(err).NotTo(HaveOccurred())
+			var tags []string
+			for _, e := range entries {
+				Expect(e.URL).To(Equal("https://github.com/org/profiles"))
+				Expect(e.CatalogSource).To(Equal("local"))
+				tags = append(tags, e.Tag)
+			}
+			Expect(tags).To(Equal([]string{"nginx/v0.1.0", "nginx/v0.2.0", "v1.0.0"}))
+			name, args := fakeRunner.RunArgsForCall(0)
+			Expect(name).To(Equal("git"))
+			Expect(args).To(Equal([]string{"-C", tmp, "tag", "--list"}))
+		})
+
+		It("uses the repository path as url if there is no remote", func() {
+			fakeRunner.RunStub = func(_ string, args ...string) ([]byte, error) {
+				switch args[2] {
+				case "tag":
+					return []byte("v0.1.0\n"), nil
+				case "show":
+					return []byte(nginxProfile), nil
+				}
+				return nil, errors.New("exit status 1")
+			}
+
+			entries, err := scanner.Scan([]string{tmp})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].URL).To(Equal(tmp))
+		})
+
+		It("returns an error if the tags can't be listed", func() {
+			fakeRunner.RunReturns([]byte("fatal: not a git repository"), errors.New("exit status 128"))
+
+			_, err := scanner.Scan([]string{tmp})
+			Expect(err).To(MatchError(ContainSubstring("failed to list tags of repository")))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/pctl/pkg/client"
+	"github.com/weaveworks/pctl/pkg/log"
+)
+
+const apiPrefix = "/v1"
+
+// Handler serves the catalog API consumed by the catalog clients from a catalog client,
+// usually a client.FileClient holding the scanned profiles.
+type Handler struct {
+	catalog client.Requester
+}
+
+var _ http.Handler = &Handler{}
+
+// NewHandler creates a Handler answering requests from catalog.
+func NewHandler(catalog client.Requester) *Handler {
+	return &Handler{catalog: catalog}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		query[k] = v[0]
+	}
+	data, code, err := h.catalog.DoRequest(r.Context(), strings.TrimPrefix(r.URL.Path, apiPrefix), query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if code != http.StatusOK {
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// Serve listens on address until ctx is cancelled.
+func Serve(ctx context.Context, address string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q: %w", address, err)
+	}
+	srv := &http.Server{Handler: handler}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+	log.Successf("serving catalog on http://%s", listener.Addr())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
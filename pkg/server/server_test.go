@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
+
+	"github.com/weaveworks/pctl/pkg/catalog"
+	"github.com/weaveworks/pctl/pkg/client"
+	gitfakes "github.com/weaveworks/pctl/pkg/git/fakes"
+	"github.com/weaveworks/pctl/pkg/install"
+	"github.com/weaveworks/pctl/pkg/install/artifact"
+	runnerfakes "github.com/weaveworks/pctl/pkg/runner/fakes"
+	"github.com/weaveworks/pctl/pkg/server"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		httpServer    *httptest.Server
+		catalogClient *client.HTTPClient
+		manager       catalog.Manager
+	)
+
+	BeforeEach(func() {
+		fileClient, err := client.NewFileClientFromEntries([]profilesv1.ProfileCatalogEntry{
+			{Name: "nginx", Tag: "nginx/v0.1.0", CatalogSource: "local", URL: "https://github.com/org/profiles"},
+			{Name: "nginx", Tag: "nginx/v0.2.0", CatalogSource: "local", URL: "https://github.com/org/profiles"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		httpServer = httptest.NewServer(server.NewHandler(fileClient))
+		catalogClient, err = client.NewHTTPClient(client.HTTPOptions{URL: httpServer.URL})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		httpServer.Close()
+	})
+
+	It("serves the catalog API", func() {
+		profiles, err := manager.Search(context.Background(), catalogClient, "nginx")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(2))
+
+		profile, err := manager.Show(context.Background(), catalogClient, "local", "nginx", "v0.2.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Tag).To(Equal("nginx/v0.2.0"))
+
+		updates, err := catalog.GetAvailableUpdates(context.Background(), catalogClient, "local", "nginx", "v0.1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(HaveLen(1))
+	})
+
+	It("returns not found for unknown profiles", func() {
+		_, err := manager.Show(context.Background(), catalogClient, "local", "other", "v0.1.0")
+		Expect(err).To(MatchError(ContainSubstring("unable to find profile")))
+
+		resp, err := http.Get(httpServer.URL + "/profiles")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("only accepts GET requests", func() {
+		resp, err := http.Post(httpServer.URL+"/v1/profiles", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	When("the profiles are in a directory", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "pctl-serve")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(dir, "nginx", "deployment"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "nginx", "profile.yaml"), []byte(nginxProfile+`  artifacts:
+  - name: deployment
+    kustomize:
+      path: deployment
+`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "nginx", "deployment", "deployment.yaml"), []byte("kind: Deployment\n"), 0644)).To(Succeed())
+
+			scanner := &server.Scanner{CatalogName: "local", Runner: new(runnerfakes.FakeRunner)}
+			entries, err := scanner.Scan([]string{dir})
+			Expect(err).NotTo(HaveOccurred())
+			fileClient, err := client.NewFileClientFromEntries(entries)
+			Expect(err).NotTo(HaveOccurred())
+			httpServer.Close()
+			httpServer = httptest.NewServer(server.NewHandler(fileClient))
+			catalogClient, err = client.NewHTTPClient(client.HTTPOptions{URL: httpServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		It("installs the served profiles from the directory, without cloning it", func() {
+			gitClient := new(gitfakes.FakeGit)
+			sink := &artifact.MemorySink{}
+			err := manager.Install(context.Background(), catalog.InstallConfig{
+				Clients: catalog.Clients{
+					CatalogClient: catalogClient,
+					Installer: install.NewInstaller(install.Config{
+						GitClient:        gitClient,
+						RootDir:          "nginx",
+						GitRepoNamespace: "flux-system",
+						GitRepoName:      "flux-system",
+						Sink:             sink,
+					}),
+				},
+				Profile: catalog.Profile{
+					ProfileConfig: catalog.ProfileConfig{
+						ProfileName:           "nginx",
+						CatalogName:           "local",
+						Version:               server.DirectoryVersion,
+						InstallationName:      "nginx",
+						InstallationNamespace: "default",
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gitClient.CloneCallCount()).To(Equal(0))
+			var paths []string
+			for _, f := range sink.Files {
+				paths = append(paths, f.Path)
+			}
+			Expect(paths).To(ContainElements(
+				filepath.Join("nginx", "profile-installation.yaml"),
+				filepath.Join("nginx", "artifacts", "deployment", "deployment", "deployment.yaml"),
+			))
+		})
+	})
+})
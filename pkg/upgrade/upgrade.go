@@ -1,6 +1,8 @@
 package upgrade
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -8,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	copypkg "github.com/otiai10/copy"
 	profilesv1 "github.com/weaveworks/profiles/api/v1alpha1"
 	"sigs.k8s.io/yaml"
@@ -30,6 +33,12 @@ type Config struct {
 	WorkingDir     string
 	Message        string
 	Latest         bool
+	// Installer configures the installers generating the profile versions. Their root directory and git
+	// repository are set by the upgrade. The git binary clones the profile repositories if it has no git client.
+	Installer install.Config
+	// IgnoreVersionConstraint allows upgrading outside of the version constraint the installation was added with.
+	// The constraint is still recorded on the upgraded installation, unless Version is a new constraint.
+	IgnoreVersionConstraint bool
 }
 
 var copy func(src, dest string) error = func(src, dest string) error {
@@ -37,7 +46,7 @@ var copy func(src, dest string) error = func(src, dest string) error {
 }
 
 // Upgrade the profile installation to a new version
-func Upgrade(cfg Config) error {
+func Upgrade(ctx context.Context, cfg Config) error {
 	out, err := ioutil.ReadFile(path.Join(cfg.ProfileDir, "profile-installation.yaml"))
 	if err != nil {
 		return fmt.Errorf("failed to read profile installation: %w", err)
@@ -61,9 +70,26 @@ func Upgrade(cfg Config) error {
 		gitRepoNamespace = profileInstallation.Spec.GitRepository.Namespace
 	}
 
+	var constraint *semver.Constraints
+	versionConstraint := profileInstallation.Annotations[catalog.VersionConstraintAnnotation]
+	switch {
+	case catalog.IsVersionConstraint(cfg.Version):
+		// a new constraint replaces the recorded one.
+		versionConstraint = cfg.Version
+		cfg.Version, err = cfg.CatalogManager.ResolveVersionConstraint(ctx, cfg.CatalogClient, catalogName, profileName, versionConstraint)
+		if err != nil {
+			return fmt.Errorf("failed to resolve version constraint %q: %w", versionConstraint, err)
+		}
+	case versionConstraint != "" && !cfg.IgnoreVersionConstraint:
+		constraint, err = semver.NewConstraint(versionConstraint)
+		if err != nil {
+			return fmt.Errorf("failed to parse recorded version constraint %q: %w", versionConstraint, err)
+		}
+	}
+
 	// Find the latest version and set it to cfg.Version... From there it should all just work.
 	if cfg.Latest {
-		availableUpdates, err := catalog.GetAvailableUpdates(cfg.CatalogClient, catalogName, profileName, currentVersion)
+		availableUpdates, err := catalog.GetAvailableUpdates(ctx, cfg.CatalogClient, catalogName, profileName, currentVersion)
 		if err != nil {
 			return fmt.Errorf("failed to get available updates for profile: %w", err)
 		}
@@ -71,33 +97,59 @@ func Upgrade(cfg Config) error {
 			return fmt.Errorf("no new versions available")
 		}
 		cfg.Version = profilesv1.GetVersionFromTag(availableUpdates[0].Tag)
+		if constraint != nil {
+			version, ok := catalog.LatestMatchingVersion(availableUpdates, constraint)
+			if !ok {
+				return fmt.Errorf("no new versions available which satisfy the version constraint %q", versionConstraint)
+			}
+			cfg.Version = version
+		}
+	} else if constraint != nil {
+		v, err := semver.NewVersion(cfg.Version)
+		if err != nil {
+			return fmt.Errorf("failed to parse version %q: %w", cfg.Version, err)
+		}
+		if !constraint.Check(v) {
+			return fmt.Errorf("version %q does not satisfy the version constraint %q the installation was added with", cfg.Version, versionConstraint)
+		}
 	}
 
 	log.Actionf("upgrading profile %q from version %q to %q", profileInstallation.Name, profileInstallation.Spec.Catalog.Version, cfg.Version)
 	//check new version exists
-	_, err = cfg.CatalogManager.Show(cfg.CatalogClient, catalogName, profileName, cfg.Version)
+	_, err = cfg.CatalogManager.Show(ctx, cfg.CatalogClient, catalogName, profileName, cfg.Version)
 	if err != nil {
 		return fmt.Errorf("failed to get profile %q in catalog %q version %q: %w", profileName, catalogName, cfg.Version, err)
 	}
 
-	err = cfg.RepoManager.CreateRepoWithContent(func() error {
-		installConfig := catalog.InstallConfig{
+	installerConfig := cfg.Installer
+	if installerConfig.GitClient == nil {
+		installerConfig.GitClient = git.NewCLIGit(git.CLIGitConfig{
+			Message: cfg.Message,
+		}, &runner.CLIRunner{})
+	}
+	installerConfig.RootDir = cfg.WorkingDir
+	installerConfig.GitRepoNamespace = gitRepoNamespace
+	installerConfig.GitRepoName = gitRepoName
+	// the current version is generated from the commits it was generated from, so only the local changes
+	// and the changes of the new version are merged.
+	lock, err := install.ReadLock(cfg.ProfileDir)
+	if err != nil {
+		return err
+	}
+	newInstallConfig := func(version string, lock *install.Lock) catalog.InstallConfig {
+		lockedConfig := installerConfig
+		lockedConfig.Lock = lock
+		return catalog.InstallConfig{
 			Clients: catalog.Clients{
 				CatalogClient: cfg.CatalogClient,
-				Installer: install.NewInstaller(install.Config{
-					GitClient: git.NewCLIGit(git.CLIGitConfig{
-						Message: cfg.Message,
-					}, &runner.CLIRunner{}),
-					RootDir:          cfg.WorkingDir,
-					GitRepoNamespace: gitRepoNamespace,
-					GitRepoName:      gitRepoName,
-				}),
+				Installer:     install.NewInstaller(lockedConfig),
 			},
 			Profile: catalog.Profile{
 				ProfileConfig: catalog.ProfileConfig{
 					ProfileName:           profileName,
 					CatalogName:           catalogName,
-					Version:               currentVersion,
+					Version:               version,
+					VersionConstraint:     versionConstraint,
 					ConfigMap:             profileInstallation.Spec.ConfigMap,
 					InstallationNamespace: profileInstallation.Namespace,
 					InstallationName:      profileInstallation.Name,
@@ -108,91 +160,97 @@ func Upgrade(cfg Config) error {
 				},
 			},
 		}
-		if err := cfg.CatalogManager.Install(installConfig); err != nil {
-			return fmt.Errorf("failed to install base profile: %w", err)
-		}
-		return nil
+	}
+
+	mergeConflictFiles, err := Merge(MergeConfig{
+		ProfileDir:  cfg.ProfileDir,
+		WorkingDir:  cfg.WorkingDir,
+		RepoManager: cfg.RepoManager,
+		Base: func() error {
+			if err := cfg.CatalogManager.Install(ctx, newInstallConfig(currentVersion, lock)); err != nil {
+				return fmt.Errorf("failed to install base profile: %w", err)
+			}
+			return nil
+		},
+		Update: func() error {
+			if err := cfg.CatalogManager.Install(ctx, newInstallConfig(cfg.Version, nil)); err != nil {
+				return fmt.Errorf("failed to install update profile: %w", err)
+			}
+			return nil
+		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create repository for upgrade: %w", err)
+		return err
+	}
+
+	if len(mergeConflictFiles) > 0 {
+		return MergeConflictsError("upgrade succeeded", cfg.ProfileDir, mergeConflictFiles)
+	}
+
+	log.Successf("upgrade completed successfully")
+	return nil
+}
+
+// MergeConfig holds the fields used to merge a newly generated installation with the local changes
+// made to an existing one.
+type MergeConfig struct {
+	// ProfileDir is the directory of the existing installation.
+	ProfileDir  string
+	WorkingDir  string
+	RepoManager repo.RepoManager
+	// Base writes the installation the local changes were made to into the working directory.
+	Base func() error
+	// Update writes the new installation into the working directory.
+	Update func() error
+}
+
+// Merge three-way merges the update with the local changes in the profile directory, using the base
+// installation they share, and replaces the profile directory with the result. It returns the files
+// containing merge conflicts, relative to the profile directory.
+func Merge(cfg MergeConfig) ([]string, error) {
+	if err := cfg.RepoManager.CreateRepoWithContent(cfg.Base); err != nil {
+		return nil, fmt.Errorf("failed to create repository for upgrade: %w", err)
 	}
 
-	err = cfg.RepoManager.CreateBranchWithContentFromMain("user-changes", func() error {
+	err := cfg.RepoManager.CreateBranchWithContentFromMain("user-changes", func() error {
 		if err := copy(cfg.ProfileDir, cfg.WorkingDir); err != nil {
 			return fmt.Errorf("failed to copy profile during upgrade: %w", err)
 		}
 		return nil
 	})
-
 	if err != nil {
-		return fmt.Errorf("failed to create branch with user changes: %w", err)
+		return nil, fmt.Errorf("failed to create branch with user changes: %w", err)
 	}
 
-	err = cfg.RepoManager.CreateBranchWithContentFromMain("update-changes", func() error {
-		installConfig := catalog.InstallConfig{
-			Clients: catalog.Clients{
-				CatalogClient: cfg.CatalogClient,
-				Installer: install.NewInstaller(install.Config{
-					GitClient: git.NewCLIGit(git.CLIGitConfig{
-						Message: cfg.Message,
-					}, &runner.CLIRunner{}),
-					RootDir:          cfg.WorkingDir,
-					GitRepoNamespace: gitRepoNamespace,
-					GitRepoName:      gitRepoName,
-				}),
-			},
-			Profile: catalog.Profile{
-				ProfileConfig: catalog.ProfileConfig{
-					ProfileName:           profileName,
-					CatalogName:           catalogName,
-					Version:               cfg.Version,
-					ConfigMap:             profileInstallation.Spec.ConfigMap,
-					InstallationNamespace: profileInstallation.Namespace,
-					InstallationName:      profileInstallation.Name,
-				},
-				GitRepoConfig: catalog.GitRepoConfig{
-					Namespace: gitRepoNamespace,
-					Name:      gitRepoName,
-				},
-			},
-		}
-
-		if err := cfg.CatalogManager.Install(installConfig); err != nil {
-			return fmt.Errorf("failed to install update profile: %w", err)
-		}
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to create branch with update changes: %w", err)
+	if err := cfg.RepoManager.CreateBranchWithContentFromMain("update-changes", cfg.Update); err != nil {
+		return nil, fmt.Errorf("failed to create branch with update changes: %w", err)
 	}
 
 	mergeConflictFiles, err := cfg.RepoManager.MergeBranches("update-changes", "user-changes")
 	if err != nil {
-		return fmt.Errorf("failed to merge updates with user changes: %w", err)
+		return nil, fmt.Errorf("failed to merge updates with user changes: %w", err)
 	}
 
 	if err := os.RemoveAll(cfg.ProfileDir); err != nil {
-		return fmt.Errorf("failed to remove existing profile installation: %w", err)
+		return nil, fmt.Errorf("failed to remove existing profile installation: %w", err)
 	}
 
 	if err := os.RemoveAll(filepath.Join(cfg.WorkingDir, ".git/")); err != nil {
-		return fmt.Errorf("failed to remove git directory from upgrade directory: %w", err)
+		return nil, fmt.Errorf("failed to remove git directory from upgrade directory: %w", err)
 	}
 
 	if err := copy(cfg.WorkingDir, cfg.ProfileDir); err != nil {
-		return fmt.Errorf("failed to copy upgraded installation into installation directory: %w", err)
+		return nil, fmt.Errorf("failed to copy upgraded installation into installation directory: %w", err)
 	}
+	return mergeConflictFiles, nil
+}
 
-	if len(mergeConflictFiles) > 0 {
-		msg := "upgrade succeeded but merge conflicts have occurred, please resolve manually. Files containing conflicts:\n"
-		for _, mergeConflictFile := range mergeConflictFiles {
-			msg = fmt.Sprintf("%s- %s\n", msg, filepath.Join(cfg.ProfileDir, mergeConflictFile))
-		}
-		msg = strings.TrimSuffix(msg, "\n")
-		return fmt.Errorf(msg)
+// MergeConflictsError returns the error reporting the files of the profile directory containing merge conflicts.
+func MergeConflictsError(prefix, profileDir string, mergeConflictFiles []string) error {
+	msg := prefix + " but merge conflicts have occurred, please resolve manually. Files containing conflicts:\n"
+	for _, mergeConflictFile := range mergeConflictFiles {
+		msg = fmt.Sprintf("%s- %s\n", msg, filepath.Join(profileDir, mergeConflictFile))
 	}
-
-	log.Successf("upgrade completed successfully")
-	return nil
+	msg = strings.TrimSuffix(msg, "\n")
+	return errors.New(msg)
 }
@@ -1,6 +1,7 @@
 package upgrade_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/weaveworks/pctl/pkg/catalog"
 	"github.com/weaveworks/pctl/pkg/catalog/fakes"
+	"github.com/weaveworks/pctl/pkg/install"
 	"github.com/weaveworks/pctl/pkg/upgrade"
 	repofakes "github.com/weaveworks/pctl/pkg/upgrade/repo/fakes"
 )
@@ -95,11 +97,11 @@ var _ = Describe("Upgrade", func() {
 	})
 
 	It("Upgrades the profile installation", func() {
-		err := upgrade.Upgrade(cfg)
+		err := upgrade.Upgrade(context.Background(), cfg)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeCatalogManager.ShowCallCount()).To(Equal(1))
-		_, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
+		_, _, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
 		Expect(catalogName).To(Equal("my-catalog"))
 		Expect(profileName).To(Equal("my-profile"))
 		Expect(desiredVersion).To(Equal("v0.1.1"))
@@ -111,7 +113,8 @@ var _ = Describe("Upgrade", func() {
 		createRepoWriteContentsFunc := fakeRepoManager.CreateRepoWithContentArgsForCall(0)
 		Expect(createRepoWriteContentsFunc()).To(Succeed())
 		Expect(fakeCatalogManager.InstallCallCount()).To(Equal(1))
-		Expect(fakeCatalogManager.InstallArgsForCall(0).Profile).To(Equal(catalog.Profile{
+		_, installConfig := fakeCatalogManager.InstallArgsForCall(0)
+		Expect(installConfig.Profile).To(Equal(catalog.Profile{
 			ProfileConfig: catalog.ProfileConfig{
 				CatalogName:           "my-catalog",
 				ProfileName:           "my-profile",
@@ -140,7 +143,8 @@ var _ = Describe("Upgrade", func() {
 		Expect(branch).To(Equal("update-changes"))
 		Expect(writeContentFunc()).To(Succeed())
 		Expect(fakeCatalogManager.InstallCallCount()).To(Equal(2))
-		Expect(fakeCatalogManager.InstallArgsForCall(1).Profile).To(Equal(catalog.Profile{
+		_, installConfig = fakeCatalogManager.InstallArgsForCall(1)
+		Expect(installConfig.Profile).To(Equal(catalog.Profile{
 			ProfileConfig: catalog.ProfileConfig{
 				CatalogName:           "my-catalog",
 				ProfileName:           "my-profile",
@@ -163,6 +167,32 @@ var _ = Describe("Upgrade", func() {
 		Expect(copierArgs[0]).To(ConsistOf(workingDir, profileDir))
 	})
 
+	When("the installation has a lock file", func() {
+		It("generates the current version from the locked commits and the new version from the latest ones", func() {
+			lock := install.Lock{Profiles: []install.LockedProfile{{
+				Name:   "my-profile",
+				URL:    "https://github.com/weaveworks/profiles-examples",
+				Ref:    "my-profile/v0.1.0",
+				Path:   "my-profile",
+				Commit: "3b5e4bc",
+			}}}
+			bytes, err := yaml.Marshal(lock)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(profileDir, install.LockFile), bytes, 0644)).To(Succeed())
+
+			Expect(upgrade.Upgrade(context.Background(), cfg)).To(Succeed())
+			createRepoWriteContentsFunc := fakeRepoManager.CreateRepoWithContentArgsForCall(0)
+			Expect(createRepoWriteContentsFunc()).To(Succeed())
+			_, writeContentFunc := fakeRepoManager.CreateBranchWithContentFromMainArgsForCall(1)
+			Expect(writeContentFunc()).To(Succeed())
+
+			_, installConfig := fakeCatalogManager.InstallArgsForCall(0)
+			Expect(installConfig.Clients.Installer.(*install.Installer).Lock).To(Equal(&lock))
+			_, installConfig = fakeCatalogManager.InstallArgsForCall(1)
+			Expect(installConfig.Clients.Installer.(*install.Installer).Lock).To(BeNil())
+		})
+	})
+
 	When("latest version is set", func() {
 		It("will choose a later version", func() {
 			httpBody := []byte(`{"items":
@@ -187,9 +217,9 @@ var _ = Describe("Upgrade", func() {
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 			cfg.Latest = true
 			cfg.Version = ""
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(err).NotTo(HaveOccurred())
-			_, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
+			_, _, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
 			Expect(catalogName).To(Equal("my-catalog"))
 			Expect(profileName).To(Equal("my-profile"))
 			Expect(desiredVersion).To(Equal("v0.2.0"))
@@ -202,18 +232,87 @@ var _ = Describe("Upgrade", func() {
 			fakeCatalogClient.DoRequestReturns(httpBody, 200, nil)
 			cfg.Latest = true
 			cfg.Version = ""
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(err).To(MatchError("no new versions available"))
 		})
 	})
 
+	When("the installation was added with a version constraint", func() {
+		BeforeEach(func() {
+			installation := profilesv1.ProfileInstallation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pctl-installation",
+					Namespace: "default",
+					Annotations: map[string]string{
+						catalog.VersionConstraintAnnotation: "~0.1",
+					},
+				},
+				Spec: profilesv1.ProfileInstallationSpec{
+					Catalog: &profilesv1.Catalog{
+						Version: "v0.1.0",
+						Profile: "my-profile",
+						Catalog: "my-catalog",
+					},
+				},
+			}
+			bytes, err := yaml.Marshal(installation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(profileDir, "profile-installation.yaml"), bytes, 0755)).To(Succeed())
+		})
+
+		It("chooses the latest version within the constraint", func() {
+			fakeCatalogClient.DoRequestReturns([]byte(`{"items":[{"name": "my-profile", "tag": "v0.2.0"}, {"name": "my-profile", "tag": "v0.1.1"}]}`), 200, nil)
+			cfg.Latest = true
+			cfg.Version = ""
+			Expect(upgrade.Upgrade(context.Background(), cfg)).To(Succeed())
+			_, _, _, _, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
+			Expect(desiredVersion).To(Equal("v0.1.1"))
+
+			By("keeping the constraint on the upgraded installation")
+			_, contentFunc := fakeRepoManager.CreateBranchWithContentFromMainArgsForCall(1)
+			Expect(contentFunc()).To(Succeed())
+			_, installConfig := fakeCatalogManager.InstallArgsForCall(0)
+			Expect(installConfig.VersionConstraint).To(Equal("~0.1"))
+		})
+
+		It("refuses to upgrade to a version outside of the constraint", func() {
+			cfg.Version = "v0.2.0"
+			err := upgrade.Upgrade(context.Background(), cfg)
+			Expect(err).To(MatchError(`version "v0.2.0" does not satisfy the version constraint "~0.1" the installation was added with`))
+		})
+
+		It("upgrades outside of the constraint when asked to and keeps the constraint", func() {
+			cfg.Version = "v0.2.0"
+			cfg.IgnoreVersionConstraint = true
+			Expect(upgrade.Upgrade(context.Background(), cfg)).To(Succeed())
+			_, contentFunc := fakeRepoManager.CreateBranchWithContentFromMainArgsForCall(1)
+			Expect(contentFunc()).To(Succeed())
+			_, installConfig := fakeCatalogManager.InstallArgsForCall(0)
+			Expect(installConfig.Version).To(Equal("v0.2.0"))
+			Expect(installConfig.VersionConstraint).To(Equal("~0.1"))
+		})
+
+		It("replaces the constraint with a new one", func() {
+			fakeCatalogManager.ResolveVersionConstraintReturns("v0.2.3", nil)
+			cfg.Version = "~0.2"
+			Expect(upgrade.Upgrade(context.Background(), cfg)).To(Succeed())
+			_, _, catalogName, profileName, constraint := fakeCatalogManager.ResolveVersionConstraintArgsForCall(0)
+			Expect([]string{catalogName, profileName, constraint}).To(Equal([]string{"my-catalog", "my-profile", "~0.2"}))
+			_, contentFunc := fakeRepoManager.CreateBranchWithContentFromMainArgsForCall(1)
+			Expect(contentFunc()).To(Succeed())
+			_, installConfig := fakeCatalogManager.InstallArgsForCall(0)
+			Expect(installConfig.Version).To(Equal("v0.2.3"))
+			Expect(installConfig.VersionConstraint).To(Equal("~0.2"))
+		})
+	})
+
 	When("merge conflicts occur", func() {
 		BeforeEach(func() {
 			fakeRepoManager.MergeBranchesReturns([]string{"foo/bar"}, nil)
 		})
 
 		It("returns a list of files that contain conflicts", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			expectedErrMsg := fmt.Sprintf("upgrade succeeded but merge conflicts have occurred, please resolve manually. Files containing conflicts:\n- %s", filepath.Join(profileDir, "foo/bar"))
 			Expect(err).To(MatchError(expectedErrMsg))
 		})
@@ -225,7 +324,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(err).To(MatchError(ContainSubstring("failed to read profile installation:")))
 		})
 	})
@@ -252,7 +351,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(err).To(MatchError(ContainSubstring("unable to upgrade an installation that was not created from a catalog")))
 		})
 	})
@@ -264,7 +363,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(err).To(MatchError(ContainSubstring("failed to parse profile installation:")))
 		})
 	})
@@ -275,10 +374,10 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(fakeCatalogManager.ShowCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring("failed to get profile \"my-profile\" in catalog \"my-catalog\" version \"v0.1.1\":")))
-			_, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
+			_, _, catalogName, profileName, desiredVersion := fakeCatalogManager.ShowArgsForCall(0)
 			Expect(catalogName).To(Equal("my-catalog"))
 			Expect(profileName).To(Equal("my-profile"))
 			Expect(desiredVersion).To(Equal("v0.1.1"))
@@ -291,7 +390,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(fakeRepoManager.CreateRepoWithContentCallCount()).To(Equal(1))
 			Expect(err).To(MatchError("failed to create repository for upgrade: foo"))
 		})
@@ -303,7 +402,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(fakeRepoManager.CreateBranchWithContentFromMainCallCount()).To(Equal(1))
 			Expect(err).To(MatchError("failed to create branch with user changes: bar"))
 		})
@@ -315,7 +414,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(fakeRepoManager.CreateBranchWithContentFromMainCallCount()).To(Equal(2))
 			Expect(err).To(MatchError("failed to create branch with update changes: baz"))
 		})
@@ -327,7 +426,7 @@ var _ = Describe("Upgrade", func() {
 		})
 
 		It("returns an error", func() {
-			err := upgrade.Upgrade(cfg)
+			err := upgrade.Upgrade(context.Background(), cfg)
 			Expect(fakeRepoManager.MergeBranchesCallCount()).To(Equal(1))
 			Expect(err).To(MatchError("failed to merge updates with user changes: bab"))
 		})
@@ -100,6 +100,7 @@ var _ = Describe("pctl add", func() {
 		By("creating the artifacts")
 		Expect(filesInDir(profilesDir)).To(ContainElements(
 			"profile-installation.yaml",
+			"profile-lock.yaml",
 			"artifacts/nginx-deployment/kustomization.yaml",
 			"artifacts/nginx-deployment/kustomize-flux.yaml",
 			"artifacts/nginx-deployment/nginx/deployment/deployment.yaml",